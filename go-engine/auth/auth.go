@@ -12,37 +12,59 @@ import (
 	"linkedin-automation/database"
 	"linkedin-automation/logger"
 	"linkedin-automation/stealth"
+	"linkedin-automation/utils"
 )
 
 // Authenticator handles LinkedIn authentication
 type Authenticator struct {
 	config       config.CredentialsConfig
-	db           *database.DB
+	site         config.LinkedInConfig
+	db           database.Store
 	logger       *logger.Logger
 	timing       *stealth.TimingController
 	typing       *stealth.TypingSimulator
 	bezier       *stealth.BezierMouse
+	cursor       *stealth.Cursor
 	fingerprint  *stealth.FingerprintMasker
+	network      *stealth.NetworkThrottler
+	proxySession string
 }
 
 // NewAuthenticator creates a new Authenticator
 func NewAuthenticator(
 	cfg config.CredentialsConfig,
-	db *database.DB,
+	db database.Store,
 	log *logger.Logger,
 	stealthCfg config.StealthConfig,
+	cursor *stealth.Cursor,
+	site config.LinkedInConfig,
 ) *Authenticator {
 	return &Authenticator{
 		config:      cfg,
+		site:        site,
 		db:          db,
 		logger:      log.WithComponent("auth"),
 		timing:      stealth.NewTimingController(stealthCfg.Timing),
 		typing:      stealth.NewTypingSimulator(stealthCfg.Timing),
 		bezier:      stealth.NewBezierMouse(stealthCfg.Bezier),
+		cursor:      cursor,
 		fingerprint: stealth.NewFingerprintMasker(stealthCfg.Fingerprint),
+		network:     stealth.NewNetworkThrottler(stealthCfg.Network),
 	}
 }
 
+// SetUserAgent makes the injected navigator overrides match the user agent
+// the browser was launched with
+func (a *Authenticator) SetUserAgent(ua string) {
+	a.fingerprint.SetUserAgent(ua)
+}
+
+// SetProxySession tags cookies saved from now on with the given proxy
+// session ID
+func (a *Authenticator) SetProxySession(id string) {
+	a.proxySession = id
+}
+
 // LoginResult represents the result of a login attempt
 type LoginResult struct {
 	Success          bool
@@ -51,8 +73,20 @@ type LoginResult struct {
 	ErrorMessage     string
 }
 
+// ChallengeError is returned when a login made on the way, such as by a
+// session refresh, runs into a security challenge
+type ChallengeError struct {
+	Type    string // as LoginResult.ChallengeType
+	Message string
+}
+
+func (e *ChallengeError) Error() string {
+	return "security challenge: " + e.Type
+}
+
 // Login performs LinkedIn login with realistic behavior
-func (a *Authenticator) Login(browser *rod.Browser) (*rod.Page, *LoginResult, error) {
+func (a *Authenticator) Login(browser *rod.Browser) (_ *rod.Page, _ *LoginResult, err error) {
+	defer utils.RecoverAsError(&err)
 	a.logger.Info("starting login process")
 
 	// Validate credentials
@@ -74,14 +108,14 @@ func (a *Authenticator) Login(browser *rod.Browser) (*rod.Page, *LoginResult, er
 
 	// Navigate to LinkedIn login
 	a.logger.Info("navigating to login page")
-	err = page.Navigate("https://www.linkedin.com/login")
+	err = page.Navigate(a.site.URL("/login"))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to navigate: %w", err)
 	}
 
 	// Wait for page load
 	time.Sleep(a.timing.GetPageLoadDelay())
-	err = page.WaitLoad()
+	err = utils.WaitLoadBounded(page, a.site.PageLoadTimeout, a.logger)
 	if err != nil {
 		return nil, nil, fmt.Errorf("page load timeout: %w", err)
 	}
@@ -135,7 +169,7 @@ func (a *Authenticator) Login(browser *rod.Browser) (*rod.Page, *LoginResult, er
 
 	// Wait for navigation
 	time.Sleep(3 * time.Second)
-	err = page.WaitLoad()
+	err = utils.WaitLoadBounded(page, a.site.PageLoadTimeout, a.logger)
 	if err != nil {
 		a.logger.LogError("wait after login", err, nil)
 	}
@@ -154,6 +188,9 @@ func (a *Authenticator) Login(browser *rod.Browser) (*rod.Page, *LoginResult, er
 		if err != nil {
 			a.logger.LogError("save cookies", err, nil)
 		}
+		if err := a.saveLocalStorage(page); err != nil {
+			a.logger.LogError("save local storage", err, nil)
+		}
 
 		return page, &LoginResult{Success: true}, nil
 	}
@@ -180,30 +217,29 @@ func (a *Authenticator) TrySessionRestore(browser *rod.Browser) (*rod.Page, bool
 	}
 
 	// Apply stealth
-	a.applyStealthScripts(page)
+	if err := a.applyStealthScripts(page); err != nil {
+		a.logger.LogError("apply stealth scripts", err, nil)
+	}
 
 	// Navigate to LinkedIn first (cookies require same domain)
-	err = page.Navigate("https://www.linkedin.com")
+	err = page.Navigate(a.site.URL("/"))
 	if err != nil {
 		return nil, false, err
 	}
 	time.Sleep(2 * time.Second)
 
-	// Inject cookies
+	// Inject cookies with the same domain scope they were captured with
 	for _, cookie := range cookies {
-		err = page.SetCookies([]*proto.NetworkCookieParam{{
-			Name:     cookie.Name,
-			Value:    cookie.Value,
-			Domain:   cookie.Domain,
-			Path:     cookie.Path,
-			Expires:  proto.TimeSinceEpoch(cookie.ExpiresAt.Unix()),
-		}})
+		err = page.SetCookies([]*proto.NetworkCookieParam{cookieParam(cookie)})
 		if err != nil {
 			a.logger.LogError("inject cookie", err, map[string]interface{}{"cookie": cookie.Name})
 		}
 	}
+	if err := a.restoreLocalStorage(page); err != nil {
+		a.logger.LogError("restore local storage", err, nil)
+	}
 
-	// Reload page with cookies
+	// Reload page with cookies and local storage
 	err = page.Reload()
 	if err != nil {
 		return nil, false, err
@@ -218,23 +254,61 @@ func (a *Authenticator) TrySessionRestore(browser *rod.Browser) (*rod.Page, bool
 
 	a.logger.Info("session restore failed, cookies expired")
 	a.db.ClearCookies()
+	a.db.ClearLocalStorage()
 	return nil, false, nil
 }
 
-// applyStealthScripts injects anti-detection JavaScript
+// TryProfileSession checks whether the browser profile is still logged in
+// on its own, as a persistent or attached profile can be, without
+// injecting any saved cookies
+func (a *Authenticator) TryProfileSession(browser *rod.Browser) (*rod.Page, bool, error) {
+	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return nil, false, err
+	}
+	if err := a.applyStealthScripts(page); err != nil {
+		a.logger.LogError("apply stealth scripts", err, nil)
+	}
+
+	if err := page.Navigate(a.site.URL("/feed/")); err != nil {
+		page.Close()
+		return nil, false, err
+	}
+	time.Sleep(a.timing.GetPageLoadDelay())
+	if err := utils.WaitLoadBounded(page, a.site.PageLoadTimeout, a.logger); err != nil {
+		page.Close()
+		return nil, false, err
+	}
+
+	if a.isLoggedIn(page) {
+		a.logger.Info("browser profile is logged in")
+		return page, true, nil
+	}
+	a.logger.Info("browser profile is not logged in")
+	page.Close()
+	return nil, false, nil
+}
+
+// applyStealthScripts injects anti-detection JavaScript into every
+// document the page loads and throttles its network like a real
+// connection's
 func (a *Authenticator) applyStealthScripts(page *rod.Page) error {
-	script := a.fingerprint.GetAllMaskingScripts()
-	if script != "" {
-		_, err := page.Eval(script)
+	if err := a.fingerprint.InstallOnPage(page); err != nil {
 		return err
 	}
-	return nil
+	return a.network.Follow(page)
 }
 
 // typeWithRealism types text with human-like patterns
 func (a *Authenticator) typeWithRealism(element *rod.Element, text string) error {
 	sequence := a.typing.GenerateTypingSequence(text)
 
+	// Focus the field and pause before the first keystroke
+	if err := element.Focus(); err != nil {
+		return fmt.Errorf("failed to focus field: %w", err)
+	}
+	time.Sleep(a.timing.GetFocusDelay())
+
 	for _, char := range sequence {
 		if char.IsBurstPause {
 			time.Sleep(char.Delay)
@@ -242,12 +316,16 @@ func (a *Authenticator) typeWithRealism(element *rod.Element, text string) error
 		}
 
 		if char.IsBackspace {
-			element.MustInput("\b")
+			if err := stealth.PressBackspace(element.Page()); err != nil {
+				return fmt.Errorf("failed to type backspace: %w", err)
+			}
 			time.Sleep(char.Delay)
 			continue
 		}
 
-		element.MustInput(string(char.Char))
+		if err := element.Input(string(char.Char)); err != nil {
+			return fmt.Errorf("failed to type character: %w", err)
+		}
 		time.Sleep(char.Delay)
 	}
 
@@ -263,16 +341,17 @@ func (a *Authenticator) clickWithRealism(page *rod.Page, element *rod.Element) e
 	}
 
 	quads := box.Quads
-	if len(quads) == 0 || len(quads[0]) < 4 {
+	if len(quads) == 0 || len(quads[0]) < 8 {
 		return element.Click(proto.InputMouseButtonLeft, 1)
 	}
 
-	// Calculate center of element
-	centerX := (quads[0][0] + quads[0][2]) / 2
-	centerY := (quads[0][1] + quads[0][5]) / 2
+	// Pick a point inside the element, not its exact center
+	centerX, centerY := a.bezier.RandomPointInQuad(quads[0])
 
-	// Get current mouse position (assume 0,0 if unknown)
-	startX, startY := 0.0, 0.0
+	// Start from where the pointer is; a fresh page puts it somewhere
+	// plausible in the viewport rather than at the origin
+	document, width, height := utils.PageDocument(page)
+	startX, startY := a.cursor.Begin(document, width, height)
 
 	// Generate Bezier path
 	path := a.bezier.GeneratePath(startX, startY, centerX, centerY)
@@ -283,7 +362,10 @@ func (a *Authenticator) clickWithRealism(page *rod.Page, element *rod.Element) e
 		if i > 0 && i-1 < len(durations) {
 			time.Sleep(durations[i-1])
 		}
-		page.Mouse.MustMoveTo(point.X, point.Y)
+		if err := page.Mouse.MoveTo(proto.Point{X: point.X, Y: point.Y}); err != nil {
+			return fmt.Errorf("failed to move mouse: %w", err)
+		}
+		a.cursor.MovedTo(point.X, point.Y)
 	}
 
 	// Small hover delay before click
@@ -295,7 +377,12 @@ func (a *Authenticator) clickWithRealism(page *rod.Page, element *rod.Element) e
 
 // checkSecurityChallenge detects security checkpoints
 func (a *Authenticator) checkSecurityChallenge(page *rod.Page) *LoginResult {
-	currentURL := page.MustInfo().URL
+	info, err := page.Info()
+	if err != nil {
+		a.logger.LogError("read page URL", err, nil)
+		return nil
+	}
+	currentURL := info.URL
 
 	// Check for 2FA
 	if strings.Contains(currentURL, "checkpoint") || strings.Contains(currentURL, "challenge") {
@@ -335,7 +422,12 @@ func (a *Authenticator) checkSecurityChallenge(page *rod.Page) *LoginResult {
 
 // isLoggedIn checks if user is successfully logged in
 func (a *Authenticator) isLoggedIn(page *rod.Page) bool {
-	currentURL := page.MustInfo().URL
+	info, err := page.Info()
+	if err != nil {
+		a.logger.LogError("read page URL", err, nil)
+		return false
+	}
+	currentURL := info.URL
 
 	// Check URL patterns
 	if strings.Contains(currentURL, "/feed") ||
@@ -370,23 +462,38 @@ func (a *Authenticator) getLoginError(page *rod.Page) string {
 	return "Login failed - unknown error"
 }
 
-// saveCookies saves session cookies to database
+// SaveSession saves the page's cookies and local storage, so the next run
+// restores the session as it is now
+func (a *Authenticator) SaveSession(page *rod.Page) error {
+	if err := a.saveCookies(page); err != nil {
+		return err
+	}
+	return a.saveLocalStorage(page)
+}
+
+// saveCookies saves the session cookies of linkedin.com and all of its
+// subdomains to the database
 func (a *Authenticator) saveCookies(page *rod.Page) error {
-	cookies, err := page.Cookies([]string{"https://www.linkedin.com"})
+	all, err := proto.NetworkGetAllCookies{}.Call(page)
 	if err != nil {
 		return err
 	}
 
+	root := rootDomain(a.site.BaseURL)
 	var dbCookies []database.SessionCookie
-	for _, c := range cookies {
+	for _, c := range all.Cookies {
+		if !cookieInDomain(c.Domain, root) {
+			continue
+		}
 		dbCookies = append(dbCookies, database.SessionCookie{
-			ID:        fmt.Sprintf("cookie_%s_%d", c.Name, time.Now().UnixNano()),
-			Name:      c.Name,
-			Value:     c.Value,
-			Domain:    c.Domain,
-			Path:      c.Path,
-			ExpiresAt: time.Unix(int64(c.Expires), 0),
-			CreatedAt: time.Now(),
+			ID:             fmt.Sprintf("cookie_%s_%d", c.Name, time.Now().UnixNano()),
+			Name:           c.Name,
+			Value:          c.Value,
+			Domain:         c.Domain,
+			Path:           c.Path,
+			ProxySessionID: a.proxySession,
+			ExpiresAt:      cookieExpiry(c),
+			CreatedAt:      time.Now(),
 		})
 	}
 
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/database"
+)
+
+// rootDomain returns the registrable domain of the LinkedIn host, e.g.
+// "linkedin.com" for https://www.linkedin.com
+func rootDomain(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "linkedin.com"
+	}
+	labels := strings.Split(strings.ToLower(parsed.Hostname()), ".")
+	if len(labels) < 2 {
+		return parsed.Hostname()
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+// cookieInDomain reports whether a cookie domain (with or without the
+// leading dot of a domain cookie) is root or one of its subdomains
+func cookieInDomain(cookieDomain, root string) bool {
+	d := strings.TrimPrefix(strings.ToLower(cookieDomain), ".")
+	return d == root || strings.HasSuffix(d, "."+root)
+}
+
+// cookieExpiry returns when a browser cookie expires, or the zero time for
+// a session cookie, which CDP reports with an expiry of -1
+func cookieExpiry(c *proto.NetworkCookie) time.Time {
+	if c.Session || c.Expires <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(c.Expires), 0)
+}
+
+// cookieParam converts a stored cookie back into a CDP cookie. Domain
+// cookies (leading dot) keep their Domain attribute; host-only cookies are
+// set through a URL instead, because passing a Domain would turn them into
+// domain cookies that no longer match what LinkedIn set
+func cookieParam(c database.SessionCookie) *proto.NetworkCookieParam {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+
+	param := &proto.NetworkCookieParam{
+		Name:   c.Name,
+		Value:  c.Value,
+		Path:   path,
+		Secure: true, // LinkedIn is HTTPS-only
+	}
+	// Without an expiry the cookie is set as a session cookie again
+	if !c.ExpiresAt.IsZero() {
+		param.Expires = proto.TimeSinceEpoch(c.ExpiresAt.Unix())
+	}
+	if strings.HasPrefix(c.Domain, ".") {
+		param.Domain = c.Domain
+	} else {
+		param.URL = "https://" + c.Domain + path
+	}
+	return param
+}
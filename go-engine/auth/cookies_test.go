@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/database"
+)
+
+func TestSessionCookieRoundTrip(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Now().Add(365 * 24 * time.Hour).Truncate(time.Second)
+	// cookies as CDP reports them; a session cookie has an expiry of -1
+	browser := []*proto.NetworkCookie{
+		{Name: "lang", Value: "v=2&lang=en-us", Domain: ".linkedin.com", Path: "/", Expires: -1, Session: true},
+		{Name: "li_at", Value: "AQEDAR", Domain: ".www.linkedin.com", Path: "/", Expires: proto.TimeSinceEpoch(expires.Unix())},
+		{Name: "lidc", Value: "b=VB", Domain: "www.linkedin.com", Path: "/", Expires: -1, Session: true},
+	}
+	var saved []database.SessionCookie
+	for i, c := range browser {
+		saved = append(saved, database.SessionCookie{
+			ID:        c.Name + string(rune('0'+i)),
+			Name:      c.Name,
+			Value:     c.Value,
+			Domain:    c.Domain,
+			Path:      c.Path,
+			ExpiresAt: cookieExpiry(c),
+			CreatedAt: time.Now(),
+		})
+	}
+	if err := db.SaveCookies(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies, err := db.GetCookies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != len(browser) {
+		t.Fatalf("%d cookies read back, want %d", len(cookies), len(browser))
+	}
+	byName := make(map[string]database.SessionCookie)
+	for _, c := range cookies {
+		byName[c.Name] = c
+	}
+
+	for _, c := range browser {
+		stored := byName[c.Name]
+		if stored.Expired(time.Now()) {
+			t.Errorf("%s: read back as expired", c.Name)
+		}
+		param := cookieParam(stored)
+		if c.Session {
+			if !stored.ExpiresAt.IsZero() {
+				t.Errorf("%s: session cookie read back expiring at %s", c.Name, stored.ExpiresAt)
+			}
+			if param.Expires != 0 {
+				t.Errorf("%s: session cookie restored with expiry %v", c.Name, param.Expires)
+			}
+		} else if param.Expires != c.Expires {
+			t.Errorf("%s: restored with expiry %v, want %v", c.Name, param.Expires, c.Expires)
+		}
+	}
+}
+
+func TestCookieScope(t *testing.T) {
+	root := rootDomain("https://www.linkedin.com")
+	if root != "linkedin.com" {
+		t.Fatalf("rootDomain = %q, want linkedin.com", root)
+	}
+	for domain, want := range map[string]bool{
+		".linkedin.com":       true,
+		"linkedin.com":        true,
+		".www.linkedin.com":   true,
+		"www.linkedin.com":    true,
+		"static.LinkedIn.com": true,
+		"notlinkedin.com":     false,
+		".licdn.com":          false,
+	} {
+		if got := cookieInDomain(domain, root); got != want {
+			t.Errorf("cookieInDomain(%q) = %v, want %v", domain, got, want)
+		}
+	}
+
+	tests := []struct {
+		domain, path string
+		wantDomain   string // Domain attribute, for domain cookies
+		wantURL      string // URL the cookie is set through, for host-only cookies
+	}{
+		{".linkedin.com", "/", ".linkedin.com", ""},
+		{".www.linkedin.com", "", ".www.linkedin.com", ""},
+		{"www.linkedin.com", "/", "", "https://www.linkedin.com/"},
+		{"www.linkedin.com", "/feed/", "", "https://www.linkedin.com/feed/"},
+	}
+	for _, tt := range tests {
+		p := cookieParam(database.SessionCookie{Name: "c", Value: "v", Domain: tt.domain, Path: tt.path})
+		if p.Domain != tt.wantDomain || p.URL != tt.wantURL {
+			t.Errorf("%s%s: restored with domain %q and URL %q, want %q and %q", tt.domain, tt.path, p.Domain, p.URL, tt.wantDomain, tt.wantURL)
+		}
+		if !p.Secure || p.Path == "" {
+			t.Errorf("%s%s: restored with secure %v and path %q", tt.domain, tt.path, p.Secure, p.Path)
+		}
+	}
+}
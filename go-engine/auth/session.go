@@ -1,20 +1,31 @@
 package auth
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/go-rod/rod"
+
 	"linkedin-automation/database"
 )
 
 // SessionManager manages LinkedIn sessions
 type SessionManager struct {
-	db         *database.DB
+	db         database.Store
 	isLoggedIn bool
 	lastCheck  time.Time
 }
 
+// Reauthenticator is what Refresh needs of an Authenticator: opening the
+// feed on the saved session, saving the session again and logging in
+type Reauthenticator interface {
+	TryProfileSession(browser *rod.Browser) (*rod.Page, bool, error)
+	SaveSession(page *rod.Page) error
+	Login(browser *rod.Browser) (*rod.Page, *LoginResult, error)
+}
+
 // NewSessionManager creates a new session manager
-func NewSessionManager(db *database.DB) *SessionManager {
+func NewSessionManager(db database.Store) *SessionManager {
 	return &SessionManager{
 		db: db,
 	}
@@ -32,7 +43,7 @@ func (sm *SessionManager) HasValidSession() bool {
 	for _, essential := range essentialCookies {
 		found := false
 		for _, cookie := range cookies {
-			if cookie.Name == essential && cookie.ExpiresAt.After(time.Now()) {
+			if cookie.Name == essential && !cookie.Expired(time.Now()) {
 				found = true
 				break
 			}
@@ -59,6 +70,9 @@ func (sm *SessionManager) IsLoggedIn() bool {
 // ClearSession clears the stored session
 func (sm *SessionManager) ClearSession() error {
 	sm.isLoggedIn = false
+	if err := sm.db.ClearLocalStorage(); err != nil {
+		return err
+	}
 	return sm.db.ClearCookies()
 }
 
@@ -85,3 +99,41 @@ func (sm *SessionManager) NeedsRefresh() bool {
 	// Refresh if session is older than 12 hours
 	return sm.GetSessionAge() > 12*time.Hour
 }
+
+// Refresh renews the saved session once NeedsRefresh says it is due, before
+// LinkedIn expires it: it opens the feed and, while still logged in, saves
+// the cookies and local storage again. A session found logged out is
+// replaced by a full login, and a security challenge on that login is
+// returned as a *ChallengeError
+func (sm *SessionManager) Refresh(browser *rod.Browser, authenticator Reauthenticator) error {
+	if !sm.NeedsRefresh() {
+		return nil
+	}
+
+	page, loggedIn, err := authenticator.TryProfileSession(browser)
+	if err != nil {
+		return fmt.Errorf("open feed: %w", err)
+	}
+	if loggedIn {
+		defer page.Close()
+		sm.SetLoggedIn(true)
+		return authenticator.SaveSession(page)
+	}
+
+	sm.SetLoggedIn(false)
+	page, login, err := authenticator.Login(browser)
+	if page != nil {
+		defer page.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if login.SecurityChallenge {
+		return fmt.Errorf("login: %w", &ChallengeError{Type: login.ChallengeType, Message: login.ErrorMessage})
+	}
+	if !login.Success {
+		return fmt.Errorf("login: %s", login.ErrorMessage)
+	}
+	sm.SetLoggedIn(true)
+	return nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/database"
+)
+
+// cookieStore is a Store holding only saved cookies; anything else it is
+// asked panics
+type cookieStore struct {
+	database.Store
+	cookies []database.SessionCookie
+}
+
+func (s *cookieStore) GetCookies() ([]database.SessionCookie, error) {
+	return s.cookies, nil
+}
+
+// savedSession returns li_at and JSESSIONID saved age ago
+func savedSession(age time.Duration) []database.SessionCookie {
+	saved := time.Now().Add(-age)
+	return []database.SessionCookie{
+		{Name: "li_at", Value: "AQEDAR", ExpiresAt: time.Now().Add(30 * 24 * time.Hour), CreatedAt: saved},
+		{Name: "JSESSIONID", Value: "ajax:1", CreatedAt: saved},
+	}
+}
+
+func TestNeedsRefresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		cookies []database.SessionCookie
+		want    bool
+	}{
+		{"no session", nil, false},
+		{"fresh session", savedSession(time.Hour), false},
+		{"old session", savedSession(13 * time.Hour), true},
+		{"one old cookie", append(savedSession(time.Hour), database.SessionCookie{Name: "lidc", CreatedAt: time.Now().Add(-2 * 24 * time.Hour)}), true},
+	}
+	for _, tt := range tests {
+		sm := NewSessionManager(&cookieStore{cookies: tt.cookies})
+		if got := sm.NeedsRefresh(); got != tt.want {
+			t.Errorf("%s: NeedsRefresh = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshSkipsFreshSession(t *testing.T) {
+	// with nothing due, Refresh must not touch the browser it is given
+	sm := NewSessionManager(&cookieStore{cookies: savedSession(time.Hour)})
+	if err := sm.Refresh(nil, nil); err != nil {
+		t.Errorf("Refresh of a fresh session = %v", err)
+	}
+}
+
+// fakeAuthenticator finds the saved session logged out and answers the
+// login that follows with login, counting the calls
+type fakeAuthenticator struct {
+	feedErr     error
+	login       *LoginResult
+	feedOpened  int
+	loginsTried int
+}
+
+func (f *fakeAuthenticator) TryProfileSession(*rod.Browser) (*rod.Page, bool, error) {
+	f.feedOpened++
+	return nil, false, f.feedErr
+}
+
+func (f *fakeAuthenticator) SaveSession(*rod.Page) error {
+	return errors.New("SaveSession called on a logged out session")
+}
+
+func (f *fakeAuthenticator) Login(*rod.Browser) (*rod.Page, *LoginResult, error) {
+	f.loginsTried++
+	return nil, f.login, nil
+}
+
+func TestRefreshOldSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      *fakeAuthenticator
+		logins    int
+		challenge bool
+		loggedIn  bool
+	}{
+		{"logs in again", &fakeAuthenticator{login: &LoginResult{Success: true}}, 1, false, true},
+		{"login challenged", &fakeAuthenticator{login: &LoginResult{SecurityChallenge: true, ChallengeType: "2fa"}}, 1, true, false},
+		{"feed unreachable", &fakeAuthenticator{feedErr: errors.New("net::ERR_TIMED_OUT")}, 0, false, false},
+	}
+	for _, tt := range tests {
+		sm := NewSessionManager(&cookieStore{cookies: savedSession(13 * time.Hour)})
+		err := sm.Refresh(nil, tt.auth)
+		if tt.auth.feedOpened != 1 {
+			t.Errorf("%s: feed opened %d times, want 1", tt.name, tt.auth.feedOpened)
+		}
+		if tt.auth.loginsTried != tt.logins {
+			t.Errorf("%s: %d logins, want %d", tt.name, tt.auth.loginsTried, tt.logins)
+		}
+		var challenge *ChallengeError
+		if got := errors.As(err, &challenge); got != tt.challenge {
+			t.Errorf("%s: Refresh = %v, want a challenge %v", tt.name, err, tt.challenge)
+		}
+		if tt.loggedIn != (err == nil) {
+			t.Errorf("%s: Refresh = %v", tt.name, err)
+		}
+		if sm.IsLoggedIn() != tt.loggedIn {
+			t.Errorf("%s: IsLoggedIn = %v, want %v", tt.name, sm.IsLoggedIn(), tt.loggedIn)
+		}
+	}
+}
+
+func TestChallengeErrorUnwraps(t *testing.T) {
+	err := fmt.Errorf("login: %w", &ChallengeError{Type: "captcha", Message: "Let's do a quick security check"})
+	var challenge *ChallengeError
+	if !errors.As(err, &challenge) || challenge.Type != "captcha" {
+		t.Errorf("errors.As(%v) = %+v", err, challenge)
+	}
+}
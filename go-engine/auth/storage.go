@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/database"
+)
+
+// maxStorageValueLength is the longest localStorage value saved, in UTF-16
+// code units. Larger entries are caches rather than session state, and are
+// left out
+const maxStorageValueLength = 256 << 10
+
+// encodingUTF16 marks a value saved as base64 of its UTF-16LE code units.
+// Strings with unpaired surrogates, which binary data stuffed into
+// localStorage often has, would be mangled on the way through JSON
+const encodingUTF16 = "utf16le-base64"
+
+// storageSnapshotScript lists the localStorage entries whose key matches
+// one of the patterns, where a trailing * matches any suffix
+const storageSnapshotScript = `(patterns, maxLength) => {
+	const wanted = key => patterns.some(p =>
+		p.endsWith('*') ? key.startsWith(p.slice(0, -1)) : key === p);
+	const unpaired = /[\uD800-\uDBFF](?![\uDC00-\uDFFF])|(?:^|[^\uD800-\uDBFF])[\uDC00-\uDFFF]/;
+	const items = [];
+	for (let i = 0; i < localStorage.length; i++) {
+		const key = localStorage.key(i);
+		if (!wanted(key)) continue;
+		const value = localStorage.getItem(key);
+		if (value.length > maxLength) {
+			items.push({key, skipped: value.length});
+			continue;
+		}
+		if (!unpaired.test(value)) {
+			items.push({key, value});
+			continue;
+		}
+		let bytes = '';
+		for (let j = 0; j < value.length; j++) {
+			const c = value.charCodeAt(j);
+			bytes += String.fromCharCode(c & 0xff, c >> 8);
+		}
+		items.push({key, value: btoa(bytes), encoding: '` + encodingUTF16 + `'});
+	}
+	return {origin: location.origin, items};
+}`
+
+// storageRestoreScript writes saved entries back into localStorage
+const storageRestoreScript = `(items) => {
+	for (const item of items) {
+		let value = item.value;
+		if (item.encoding === '` + encodingUTF16 + `') {
+			const bytes = atob(value);
+			value = '';
+			for (let j = 0; j + 1 < bytes.length; j += 2) {
+				value += String.fromCharCode(bytes.charCodeAt(j) | bytes.charCodeAt(j + 1) << 8);
+			}
+		}
+		try {
+			localStorage.setItem(item.key, value);
+		} catch (e) {}
+	}
+}`
+
+// storageSnapshot is what storageSnapshotScript returns
+type storageSnapshot struct {
+	Origin string `json:"origin"`
+	Items  []struct {
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		Encoding string `json:"encoding"`
+		Skipped  int    `json:"skipped"`
+	} `json:"items"`
+}
+
+// saveLocalStorage saves the page's localStorage entries matching
+// linkedin.local_storage_keys, next to the cookies. LinkedIn keeps state
+// there that a session restored from cookies alone can be challenged for
+func (a *Authenticator) saveLocalStorage(page *rod.Page) error {
+	if len(a.site.LocalStorageKeys) == 0 {
+		return nil
+	}
+	res, err := page.Eval(storageSnapshotScript, a.site.LocalStorageKeys, maxStorageValueLength)
+	if err != nil {
+		return err
+	}
+	var snapshot storageSnapshot
+	if err := res.Value.Unmarshal(&snapshot); err != nil {
+		return err
+	}
+
+	items, skipped := snapshot.storageItems(time.Now())
+	for key, length := range skipped {
+		a.logger.Debug("local storage entry too large to save", "key", key, "length", length)
+	}
+	return a.db.SaveLocalStorage(items)
+}
+
+// storageItems returns the entries of a snapshot to save, taken at now,
+// and the lengths of those too large to save by key
+func (s storageSnapshot) storageItems(now time.Time) ([]database.StorageItem, map[string]int) {
+	var items []database.StorageItem
+	skipped := make(map[string]int)
+	for _, item := range s.Items {
+		if item.Skipped > 0 {
+			skipped[item.Key] = item.Skipped
+			continue
+		}
+		items = append(items, database.StorageItem{
+			Origin:    s.Origin,
+			Key:       item.Key,
+			Value:     item.Value,
+			Encoding:  item.Encoding,
+			CreatedAt: now,
+		})
+	}
+	return items, skipped
+}
+
+// restoreLocalStorage writes the saved entries of the page's origin back
+// into its localStorage. The page must already be on that origin
+func (a *Authenticator) restoreLocalStorage(page *rod.Page) error {
+	saved, err := a.db.GetLocalStorage()
+	if err != nil || len(saved) == 0 {
+		return err
+	}
+	res, err := page.Eval(`() => location.origin`)
+	if err != nil {
+		return err
+	}
+	origin := res.Value.String()
+
+	var items []map[string]string
+	for _, item := range saved {
+		if item.Origin == origin {
+			items = append(items, map[string]string{"key": item.Key, "value": item.Value, "encoding": item.Encoding})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	_, err = page.Eval(storageRestoreScript, items)
+	return err
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"linkedin-automation/database"
+)
+
+// utf16Base64 encodes code units the way storageSnapshotScript does
+func utf16Base64(units ...uint16) string {
+	buf := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[2*i:], u)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	// a value with an unpaired surrogate, which JSON would mangle
+	binaryValue := []uint16{'i', 'd', 0xD800, 'x', 0xDC01}
+	body, err := json.Marshal(map[string]interface{}{
+		"origin": "https://www.linkedin.com",
+		"items": []map[string]interface{}{
+			{"key": "li_theme", "value": "dark"},
+			{"key": "voyager-web:badges", "value": utf16Base64(binaryValue...), "encoding": encodingUTF16},
+			{"key": "li_feed_cache", "skipped": maxStorageValueLength + 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot storageSnapshot
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	items, skipped := snapshot.storageItems(now)
+	if want := map[string]int{"li_feed_cache": maxStorageValueLength + 1}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+
+	for _, passphrase := range []string{"", "correct horse battery staple"} {
+		db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if err := db.Initialize(); err != nil {
+			t.Fatal(err)
+		}
+		if passphrase != "" {
+			if err := db.EnableEncryption(passphrase); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.SaveLocalStorage(items); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := db.GetLocalStorage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(saved) != 2 {
+			t.Fatalf("encrypted=%v: %d entries saved, want 2: %+v", passphrase != "", len(saved), saved)
+		}
+		for i, item := range saved {
+			if item.Origin != items[i].Origin || item.Key != items[i].Key || item.Value != items[i].Value ||
+				item.Encoding != items[i].Encoding || !item.CreatedAt.Equal(now) {
+				t.Errorf("encrypted=%v: entry %d = %+v, want %+v", passphrase != "", i, item, items[i])
+			}
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(saved[1].Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(raw[2*i:])
+		}
+		if !reflect.DeepEqual(units, binaryValue) {
+			t.Errorf("encrypted=%v: restored code units %x, want %x", passphrase != "", units, binaryValue)
+		}
+	}
+}
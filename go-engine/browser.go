@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/devices"
+	"github.com/go-rod/rod/lib/launcher"
+
+	"linkedin-automation/config"
+	"linkedin-automation/stealth"
+)
+
+// launchPlan is how a run gets its browser, decided before any is started
+type launchPlan struct {
+	attachURL string   // DevTools endpoint of a running Chrome; "" launches one
+	ignored   []string // configured settings that have no effect under this plan
+}
+
+// planLaunch decides between launching a browser and attaching to a
+// running one. An attached Chrome keeps the window, flags, user agent and
+// proxy it was started with; a configured proxy is reported as ignored
+// rather than silently dropped
+func planLaunch(cfg *config.Config) launchPlan {
+	plan := launchPlan{attachURL: cfg.Browser.AttachURL}
+	if plan.attachURL == "" {
+		return plan
+	}
+	if cfg.Proxy.URL != "" {
+		plan.ignored = append(plan.ignored, "proxy.url")
+	}
+	if len(cfg.Proxy.URLs) > 0 {
+		plan.ignored = append(plan.ignored, "proxy.urls")
+	}
+	if cfg.Browser.UserDataDir != "" {
+		plan.ignored = append(plan.ignored, "browser.user_data_dir")
+	}
+	if cfg.Browser.ExecutablePath != "" {
+		plan.ignored = append(plan.ignored, "browser.executable_path")
+	}
+	return plan
+}
+
+// desktopDevice is the device every page of a launched browser emulates:
+// the picked viewport and scale factor, and the launch user agent, which
+// the emulation would otherwise reset. Desktops are landscape and have no
+// touch screen
+func desktopDevice(viewport stealth.Viewport, userAgent string) devices.Device {
+	size := devices.ScreenSize{Width: viewport.Width, Height: viewport.Height}
+	return devices.Device{
+		Title:     "Desktop",
+		UserAgent: userAgent,
+		Screen: devices.Screen{
+			DevicePixelRatio: viewport.ScaleFactor,
+			Horizontal:       size,
+			Vertical:         size,
+		},
+	}.Landscape()
+}
+
+// profileLock is the file Chrome holds in a profile directory while a
+// browser uses it, a symlink to "<hostname>-<pid>" on Linux and macOS
+const profileLock = "SingletonLock"
+
+// prepareProfileDir creates the profile directory on first use and refuses
+// one another browser holds, which Chrome would otherwise hand over to that
+// browser instead of starting
+func prepareProfileDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create browser profile %s: %w", dir, err)
+	}
+	if owner, locked := profileLockOwner(dir); locked {
+		return fmt.Errorf("browser profile %s is in use by another Chrome (%s); close it or set another browser.user_data_dir", dir, owner)
+	}
+	return nil
+}
+
+// profileLockOwner reports whether a running browser holds the profile in
+// dir, and which. A lock left behind by a browser that died on this host
+// is stale, and Chrome takes it over; a lock from another host cannot be
+// checked and counts as held
+func profileLockOwner(dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	owner, err := os.Readlink(filepath.Join(dir, profileLock))
+	if err != nil {
+		return "", false
+	}
+
+	sep := strings.LastIndex(owner, "-")
+	if sep < 0 {
+		return owner, true
+	}
+	host, _ := os.Hostname()
+	pid, err := strconv.Atoi(owner[sep+1:])
+	if err != nil || owner[:sep] != host {
+		return owner, true
+	}
+	return owner, processAlive(pid)
+}
+
+// processAlive reports whether a process with the given ID is running
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// attachBrowser connects to the Chrome listening at endpoint. There is no
+// fallback to launching a browser: a user who asked to drive their own
+// profile should not end up in a fresh, logged-out one
+func (a *Automation) attachBrowser(endpoint string) error {
+	wsURL := endpoint
+	if u, err := url.Parse(endpoint); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		resolved, err := launcher.ResolveURL(endpoint)
+		if err != nil {
+			return fmt.Errorf("no browser to attach to at %s (is Chrome running with --remote-debugging-port?): %w", endpoint, err)
+		}
+		wsURL = resolved
+	}
+
+	browser := rod.New().ControlURL(wsURL)
+	if err := browser.Connect(); err != nil {
+		return fmt.Errorf("failed to attach to browser at %s: %w", endpoint, err)
+	}
+	// The real window decides the viewport, not an emulated device
+	a.browser = browser.NoDefaultDevice()
+	a.attached = true
+
+	// The masking scripts' navigator overrides follow the user agent the
+	// browser really sends
+	version, err := a.browser.Version()
+	if err != nil {
+		return fmt.Errorf("failed to read attached browser version: %w", err)
+	}
+	a.authenticator.SetUserAgent(version.UserAgent)
+
+	a.logger.Info("Browser attached", "endpoint", endpoint, "product", version.Product)
+	return nil
+}
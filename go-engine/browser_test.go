@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/config"
+	"linkedin-automation/stealth"
+)
+
+func TestPlanLaunch(t *testing.T) {
+	const endpoint = "http://127.0.0.1:9222"
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		attach  string
+		ignored []string
+	}{
+		{name: "launch"},
+		{
+			name: "launch keeps its settings",
+			cfg: config.Config{
+				Browser: config.BrowserConfig{UserDataDir: "profile", ExecutablePath: "/usr/bin/chromium"},
+				Proxy:   config.ProxyConfig{URL: "http://proxy:8080"},
+			},
+		},
+		{
+			name:   "attach",
+			cfg:    config.Config{Browser: config.BrowserConfig{AttachURL: endpoint}},
+			attach: endpoint,
+		},
+		{
+			name: "attach ignores launch settings",
+			cfg: config.Config{
+				Browser: config.BrowserConfig{AttachURL: endpoint, UserDataDir: "profile", ExecutablePath: "/usr/bin/chromium"},
+				Proxy:   config.ProxyConfig{URL: "http://proxy:8080", URLs: []string{"http://a:8080", "http://b:8080"}},
+			},
+			attach:  endpoint,
+			ignored: []string{"proxy.url", "proxy.urls", "browser.user_data_dir", "browser.executable_path"},
+		},
+		{
+			name: "attach ignores a proxy pool",
+			cfg: config.Config{
+				Browser: config.BrowserConfig{AttachURL: endpoint},
+				Proxy:   config.ProxyConfig{URLs: []string{"http://a:8080"}},
+			},
+			attach:  endpoint,
+			ignored: []string{"proxy.urls"},
+		},
+	}
+	for _, tt := range tests {
+		plan := planLaunch(&tt.cfg)
+		if plan.attachURL != tt.attach {
+			t.Errorf("%s: attachURL = %q, want %q", tt.name, plan.attachURL, tt.attach)
+		}
+		if !reflect.DeepEqual(plan.ignored, tt.ignored) {
+			t.Errorf("%s: ignored = %q, want %q", tt.name, plan.ignored, tt.ignored)
+		}
+	}
+}
+
+// TestDesktopDeviceInBrowser reads a randomized viewport back from a real
+// page, so it needs a local Chrome or Chromium and is skipped without one
+func TestDesktopDeviceInBrowser(t *testing.T) {
+	bin, ok := launcher.LookPath()
+	if !ok {
+		t.Skip("no Chrome or Chromium found")
+	}
+	u, err := launcher.New().Bin(bin).Headless(true).Leakless(false).Launch()
+	if err != nil {
+		t.Skipf("launch browser: %v", err)
+	}
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer browser.Close()
+
+	fm := stealth.NewFingerprintMasker(config.FingerprintConfig{RandomizeViewport: true, RotateUserAgent: true})
+	viewport, userAgent := fm.GetRandomViewport(), fm.GetRandomUserAgent()
+	browser.DefaultDevice(desktopDevice(viewport, userAgent))
+
+	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := page.Eval(`() => [window.innerWidth, window.innerHeight, window.devicePixelRatio, navigator.userAgent]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := res.Value.Get("0").Int(), res.Value.Get("1").Int(); w != viewport.Width || h != viewport.Height {
+		t.Errorf("window is %dx%d, want %dx%d", w, h, viewport.Width, viewport.Height)
+	}
+	if ratio := res.Value.Get("2").Num(); ratio != viewport.ScaleFactor {
+		t.Errorf("devicePixelRatio = %g, want %g", ratio, viewport.ScaleFactor)
+	}
+	if ua := res.Value.Get("3").Str(); ua != userAgent {
+		t.Errorf("navigator.userAgent = %q, want %q", ua, userAgent)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"linkedin-automation/database"
+	"linkedin-automation/utils"
+)
+
+// burstQuota is the share of the daily limits one burst may use. Inactive
+// quotas (a single burst per day) leave the daily limits alone
+type burstQuota struct {
+	active      bool
+	connections int
+	messages    int
+}
+
+// connectionsLeft reports whether the burst may send another invitation
+// after sent this run
+func (q burstQuota) connectionsLeft(sent int) bool {
+	return !q.active || sent < q.connections
+}
+
+// messagesLeft reports whether the burst may send another message after
+// sent this run
+func (q burstQuota) messagesLeft(sent int) bool {
+	return !q.active || sent < q.messages
+}
+
+// planBursts spreads n burst starts over the rest of the day's active
+// window. The first burst starts now; each later one starts at a random
+// point in the first half of its share of the window, leaving it time to
+// work before the next
+func planBursts(now, windowEnd time.Time, n int) []time.Time {
+	starts := []time.Time{now}
+	if n <= 1 || !windowEnd.After(now) {
+		return starts
+	}
+
+	rng := utils.NewRand("bursts")
+	slot := windowEnd.Sub(now) / time.Duration(n)
+	for i := 1; i < n; i++ {
+		jitter := time.Duration(rng.Int63n(int64(slot/2) + 1))
+		starts = append(starts, now.Add(time.Duration(i)*slot+jitter).Truncate(time.Minute))
+	}
+	return starts
+}
+
+// activeWindowEnd returns when today's business hours end, or midnight
+// when business hours are not respected
+func (a *Automation) activeWindowEnd(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	if !a.config.Stealth.Scheduling.RespectBusinessHours {
+		return midnight
+	}
+	end := time.Date(now.Year(), now.Month(), now.Day(), a.config.RateLimits.BusinessHoursEnd, 0, 0, 0, now.Location())
+	if end.After(midnight) {
+		return midnight
+	}
+	return end
+}
+
+// burstPlan loads today's burst plan, making one on the first run of the
+// day. It returns nil when the day is a single burst
+func (a *Automation) burstPlan() *database.BurstPlan {
+	cfg := a.config.Workflow
+	if cfg.BurstsPerDayMax <= 1 {
+		return nil
+	}
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	plan, err := a.db.GetBurstPlan(today)
+	if err != nil {
+		a.logger.LogError("load burst plan", err, nil)
+	}
+	if plan != nil {
+		return plan
+	}
+
+	n := cfg.BurstsPerDayMin
+	if cfg.BurstsPerDayMax > cfg.BurstsPerDayMin {
+		n += utils.NewRand("bursts").Intn(cfg.BurstsPerDayMax - cfg.BurstsPerDayMin + 1)
+	}
+	plan = &database.BurstPlan{Date: today, Starts: planBursts(now, a.activeWindowEnd(now), n)}
+	if err := a.db.SaveBurstPlan(plan); err != nil {
+		a.logger.LogError("save burst plan", err, nil)
+	}
+	a.logger.Info("planned activity bursts", "date", today, "bursts", len(plan.Starts), "starts", formatBurstStarts(plan.Starts))
+	return plan
+}
+
+// startBurst decides whether this run is a burst. Between bursts the run
+// ends with the next start time, or idles until it with --continuous. On
+// the way in, the burst's share of the remaining daily limits is set. It
+// returns false when the run should end
+func (a *Automation) startBurst(result *RunResult) bool {
+	a.burst = burstQuota{}
+	plan := a.burstPlan()
+	if plan == nil {
+		return true
+	}
+	a.plan = plan
+	result.Bursts = len(plan.Starts)
+
+	if plan.Completed >= len(plan.Starts) {
+		fmt.Printf("\nAll %d activity bursts for today are done (%s)\n", len(plan.Starts), formatBurstStarts(plan.Starts))
+		result.StopReason = StopBurstsDone
+		return false
+	}
+
+	next := plan.Starts[plan.Completed]
+	if wait := time.Until(next); wait > 0 {
+		if !a.continuous {
+			fmt.Printf("\nNext activity burst (%d of %d) starts at %s, idling until then\n", plan.Completed+1, len(plan.Starts), next.Format("15:04"))
+			result.StopReason = StopBetweenBursts
+			return false
+		}
+
+		a.logger.Info("idling until the next burst", "burst", plan.Completed+1, "start", next.Format("15:04"))
+		fmt.Printf("\nIdling until activity burst %d of %d at %s\n", plan.Completed+1, len(plan.Starts), next.Format("15:04"))
+		timer := time.NewTimer(wait)
+		select {
+		case <-a.stopChan:
+			timer.Stop()
+			result.StopReason = StopInterrupted
+			return false
+		case <-timer.C:
+		}
+		a.clock = newClockWatch()
+		if !a.config.IsBusinessHours() {
+			result.StopReason = StopOutsideHours
+			return false
+		}
+	}
+
+	left := len(plan.Starts) - plan.Completed
+	activity, err := a.db.GetOrCreateDailyActivity()
+	if err != nil {
+		a.logger.LogError("load daily activity", err, nil)
+		return true
+	}
+	connections := a.config.Connection.EffectiveDailyLimit(time.Now()) - activity.ConnectionsSent
+	if allowance, err := a.connectionManager.ConnectionAllowance(); err == nil {
+		connections = allowance.Remaining
+	}
+	a.burst = burstQuota{
+		active:      true,
+		connections: burstShare(connections, left),
+		messages:    burstShare(a.config.Messaging.EffectiveDailyLimit(time.Now())-activity.MessagesSent, left),
+	}
+	result.Burst = plan.Completed + 1
+
+	a.logger.Info("starting activity burst", "burst", result.Burst, "of", len(plan.Starts),
+		"connections", a.burst.connections, "messages", a.burst.messages)
+	fmt.Printf("\nActivity burst %d of %d: up to %d connections and %d messages\n",
+		result.Burst, len(plan.Starts), a.burst.connections, a.burst.messages)
+	return true
+}
+
+// finishBurst records the current burst as run
+func (a *Automation) finishBurst() {
+	if !a.burst.active || a.plan == nil {
+		return
+	}
+	a.plan.Completed++
+	if err := a.db.SaveBurstPlan(a.plan); err != nil {
+		a.logger.LogError("save burst plan", err, nil)
+	}
+}
+
+// burstShare splits what is left of a daily limit evenly over the bursts
+// still to run, rounding up
+func burstShare(remaining, bursts int) int {
+	if remaining <= 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(remaining) / float64(bursts)))
+}
+
+// formatBurstStarts lists burst start times as "09:00, 11:42, 15:10"
+func formatBurstStarts(starts []time.Time) string {
+	times := make([]string, len(starts))
+	for i, t := range starts {
+		times[i] = t.Format("15:04")
+	}
+	return strings.Join(times, ", ")
+}
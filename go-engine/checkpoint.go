@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"linkedin-automation/database"
+)
+
+// offerResume reports an interrupted run found at startup. With --resume
+// the next run continues its connect queue instead of searching again
+func (a *Automation) offerResume(resume bool) {
+	cp, err := a.db.LoadRunCheckpoint()
+	if err != nil {
+		a.logger.LogError("load run checkpoint", err, nil)
+		return
+	}
+
+	switch {
+	case cp != nil && resume:
+		a.resume = cp
+		a.runID = cp.RunID
+		a.logger.Info("resuming interrupted run", "run_id", cp.RunID, "remaining", len(cp.Profiles))
+		fmt.Printf("Resuming run %s: %d queued profiles left\n", cp.RunID, len(cp.Profiles))
+	case cp != nil:
+		fmt.Printf("⚠ Run %s stopped on %s after %d profiles, %d left; pass --resume to continue it\n",
+			cp.RunID, cp.UpdatedAt.Format("Mon Jan 2 15:04"), cp.Index, len(cp.Profiles))
+	case resume:
+		fmt.Println("No interrupted run to resume, starting a new one")
+	}
+}
+
+// beginRun gives a run its ID. A resumed run keeps the interrupted run's
+func (a *Automation) beginRun() {
+	if a.resume == nil {
+		a.runID = fmt.Sprintf("run_%d", time.Now().UnixNano())
+	}
+}
+
+// endRun drops the checkpoint of a run that completed. Interrupted and
+// failed runs keep theirs so they can be resumed
+func (a *Automation) endRun(result *RunResult) {
+	if result.StopReason != StopCompleted {
+		return
+	}
+	if err := a.db.ClearRunCheckpoint(a.runID); err != nil {
+		a.logger.LogError("clear run checkpoint", err, map[string]interface{}{"run_id": a.runID})
+	}
+	a.resume = nil
+}
+
+// connectQueue returns the profiles the connect phase works through: the
+// rest of the resumed run's queue, less profiles handled since it was
+// saved, or the persisted search queue
+func (a *Automation) connectQueue() ([]database.QueuedProfile, error) {
+	if a.resume == nil {
+		return a.db.GetQueuedProfiles()
+	}
+
+	var queue []database.QueuedProfile
+	for _, p := range a.resume.Profiles {
+		processed, err := a.db.IsProfileProcessed(p.ProfileURL)
+		if err != nil {
+			return nil, err
+		}
+		if !processed {
+			queue = append(queue, p)
+		}
+	}
+	return queue, nil
+}
+
+// checkpoint records that the connect phase is about to handle queue[i]
+func (a *Automation) checkpoint(i int, queue []database.QueuedProfile) {
+	if err := a.db.SaveRunCheckpoint(a.runID, i, queue); err != nil {
+		a.logger.LogError("save run checkpoint", err, map[string]interface{}{"run_id": a.runID})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// clockWatch detects wall clock jumps between checks. Go's monotonic clock
+// stops while the machine sleeps but the wall clock keeps going, so after a
+// suspend and resume the wall clock has moved further than the monotonic one
+type clockWatch struct {
+	last time.Time
+}
+
+func newClockWatch() *clockWatch {
+	return &clockWatch{last: time.Now()}
+}
+
+// jump returns how far the wall clock moved beyond the monotonic clock
+// since the previous call. Manual clock changes show up the same way
+func (c *clockWatch) jump() time.Duration {
+	now := time.Now()
+	monotonic := now.Sub(c.last)
+	wall := now.Round(0).Sub(c.last.Round(0))
+	c.last = now
+	return wall - monotonic
+}
+
+// afterClockJump checks for a wall clock jump before the next action of
+// step. After one, queued actions must not go out in a burst on skewed
+// timers: business hours and the step's active hours are re-evaluated and
+// the run pauses for workflow.clock_jump_pause. proceed is false when the
+// step should end; stop is set when the whole run should end too
+func (a *Automation) afterClockJump(step string, result *RunResult) (proceed, stop bool) {
+	threshold := a.config.Workflow.ClockJumpThreshold
+	jump := a.clock.jump()
+	if threshold == 0 || (jump < threshold && jump > -threshold) {
+		return true, false
+	}
+
+	result.ClockJumps++
+	a.logger.Warn("wall clock jumped, the system probably slept", "jump", jump.Round(time.Second), "step", step)
+	fmt.Printf("\n⚠ System clock jumped by %s (sleep/resume?), re-checking the schedule\n", jump.Round(time.Second))
+
+	if !a.config.IsBusinessHours() {
+		fmt.Println("Outside business hours after resume, ending the run")
+		result.StopReason = StopOutsideHours
+		return false, true
+	}
+	if hours, effective, ok := a.stepHours(step); ok && !a.config.InActiveHours(hours) {
+		fmt.Printf("Outside %s active hours (%s) after resume, ending the step\n", step, effective)
+		return false, false
+	}
+
+	pause := a.config.Workflow.ClockJumpPause
+	fmt.Printf("Pausing %s before resuming\n", pause)
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-a.stopChan:
+		return false, true
+	case <-timer.C:
+	}
+
+	// The pause itself is not a jump
+	a.clock = newClockWatch()
+	return true, false
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadYAML writes yaml to a config file and loads it
+func loadYAML(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoadValidTemplates(t *testing.T) {
+	cfg, err := loadYAML(t, `
+connection:
+  templates:
+    - "Hi {{firstName|there}}, I saw your work at {{company}}."
+    - "Hi {{firstName}}{{#if mutualName}}, {{mutualName}} and I are connected{{/if}}."
+messaging:
+  templates:
+    - "Thanks for connecting, {{firstName}}!"
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Connection.Templates) != 2 || len(cfg.Messaging.Templates) != 1 {
+		t.Errorf("loaded %d connection and %d message templates, want 2 and 1",
+			len(cfg.Connection.Templates), len(cfg.Messaging.Templates))
+	}
+}
+
+func TestLoadRejectsBadTemplates(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		wants []string // each must appear in the error
+	}{
+		{"unknown variable", `
+connection:
+  templates:
+    - "Hi {{firstName}}"
+    - "Hi {{firstname}}, great to meet you"
+`, []string{`connection.templates[1]`, `unknown variable "firstname" at position 3`}},
+		{"unclosed placeholder", `
+messaging:
+  templates:
+    - "Thanks {{firstName"
+`, []string{`messaging.templates[0]`, `unclosed placeholder "{{firstName" at position 7`}},
+		{"every problem at once", `
+connection:
+  templates:
+    - "{{#if company}}At {{company}}"
+messaging:
+  templates:
+    - "Hi {{name}}"
+`, []string{`connection.templates[0]: {{#if}} without a matching {{/if}} at position 0`, `messaging.templates[0]: unknown variable "name"`}},
+	}
+	for _, tt := range tests {
+		_, err := loadYAML(t, tt.yaml)
+		if err == nil {
+			t.Errorf("%s: Load succeeded", tt.name)
+			continue
+		}
+		for _, want := range tt.wants {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+func TestLoadRejectsBadSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // must appear in the error
+	}{
+		{"delay distribution", `
+stealth:
+  timing:
+    distribution: gaussian
+`, `stealth.timing.distribution must be uniform, normal or lognormal, got "gaussian"`},
+		{"link preview", `
+messaging:
+  link_preview: drop
+`, `messaging.link_preview must be keep, remove or ignore, got "drop"`},
+		{"capped templates", `
+connection:
+  when_templates_capped: skip
+`, `connection.when_templates_capped must be no_note or stop, got "skip"`},
+		{"template caps", `
+connection:
+  templates:
+    - "Hi {{firstName}}"
+    - "Hello {{firstName}}"
+  template_daily_caps: [10]
+`, `connection.template_daily_caps has 1 entries for 2 templates`},
+		{"private profiles", `
+connection:
+  private_profiles: skipp
+`, `connection.private_profiles must be allow or skip, got "skipp"`},
+		{"profile redirects", `
+connection:
+  profile_redirects: follow
+`, `connection.profile_redirects must be reconcile, skip or ignore, got "follow"`},
+		{"wildcard accept language", `
+stealth:
+  fingerprint:
+    accept_language: "*"
+`, `stealth.fingerprint.accept_language must name at least one language, got "*"`},
+		{"accept language without tags", `
+stealth:
+  fingerprint:
+    accept_language: " ,;q=0.5"
+`, `stealth.fingerprint.accept_language must name at least one language, got " ,;q=0.5"`},
+	}
+	for _, tt := range tests {
+		_, err := loadYAML(t, tt.yaml)
+		if err == nil {
+			t.Errorf("%s: Load succeeded", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %q does not mention %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadShippedConfig(t *testing.T) {
+	if _, err := Load(filepath.Join("..", "config.yaml")); err != nil {
+		t.Fatalf("Load(config.yaml): %v", err)
+	}
+}
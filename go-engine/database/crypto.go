@@ -0,0 +1,162 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// encryptedPrefix marks a column value written by valueCipher so that
+	// plaintext rows from before encryption was enabled can still be read
+	encryptedPrefix = "enc:v1:"
+
+	// Argon2id parameters: passes, memory in KiB and lanes
+	argon2Time    = 3
+	argon2Memory  = 64 << 10
+	argon2Threads = 4
+
+	kdfSaltSize = 16
+	keySize     = 32
+
+	// keyVerifier is encrypted with the derived key and stored alongside the
+	// salt, so a wrong passphrase is reported at startup rather than on the
+	// first cookie read
+	keyVerifier = "linkedin-automation"
+)
+
+// ErrEncryptionKeyMissing is returned when encrypted values are read without
+// a passphrase configured
+var ErrEncryptionKeyMissing = errors.New("stored data is encrypted but no encryption passphrase is configured (set LINKEDIN_DB_KEY or database.encryption_passphrase)")
+
+// ErrEncryptionKeyInvalid is returned when the configured passphrase does not
+// match the one the data was encrypted with
+var ErrEncryptionKeyInvalid = errors.New("encryption passphrase does not match the one used to encrypt the database")
+
+// valueCipher encrypts individual column values with AES-256-GCM
+type valueCipher struct {
+	aead cipher.AEAD
+}
+
+func newValueCipher(key []byte) (*valueCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &valueCipher{aead: aead}, nil
+}
+
+// encrypt returns the prefixed, base64 encoded nonce+ciphertext of plaintext
+func (c *valueCipher) encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt reverses encrypt. Authentication failures are reported as
+// ErrEncryptionKeyInvalid so a wrong key never yields garbage
+func (c *valueCipher) decrypt(value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("malformed encrypted value: too short")
+	}
+	plaintext, err := c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", ErrEncryptionKeyInvalid
+	}
+	return string(plaintext), nil
+}
+
+// isEncrypted reports whether value was written by valueCipher
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// EnableEncryption derives the AES key from passphrase and turns on
+// encryption for sensitive columns (session cookie and localStorage values). The
+// salt and a key verifier are stored in the encryption_meta table on first use; later runs with a different
+// passphrase fail with ErrEncryptionKeyInvalid
+func (db *DB) EnableEncryption(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("encryption passphrase must not be empty")
+	}
+
+	var salt []byte
+	var verifier string
+	err := db.QueryRow(`SELECT salt, verifier FROM encryption_meta WHERE id = 1`).Scan(&salt, &verifier)
+	switch {
+	case err == sql.ErrNoRows:
+		salt = make([]byte, kdfSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		c, err := newValueCipher(deriveKey(passphrase, salt))
+		if err != nil {
+			return err
+		}
+		verifier, err = c.encrypt(keyVerifier)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(`INSERT INTO encryption_meta (id, salt, verifier) VALUES (1, ?, ?)`, salt, verifier); err != nil {
+			return fmt.Errorf("failed to store encryption salt: %w", err)
+		}
+		db.cipher = c
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to load encryption salt: %w", err)
+	}
+
+	c, err := newValueCipher(deriveKey(passphrase, salt))
+	if err != nil {
+		return err
+	}
+	if plain, err := c.decrypt(verifier); err != nil || plain != keyVerifier {
+		return ErrEncryptionKeyInvalid
+	}
+	db.cipher = c
+	return nil
+}
+
+// sealValue encrypts value when encryption is enabled
+func (db *DB) sealValue(value string) (string, error) {
+	if db.cipher == nil {
+		return value, nil
+	}
+	return db.cipher.encrypt(value)
+}
+
+// openValue decrypts value if it was stored encrypted. Plaintext values
+// written before encryption was enabled are returned unchanged
+func (db *DB) openValue(value string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	if db.cipher == nil {
+		return "", ErrEncryptionKeyMissing
+	}
+	return db.cipher.decrypt(value)
+}
+
+// deriveKey stretches passphrase into an AES-256 key with Argon2id
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, keySize)
+}
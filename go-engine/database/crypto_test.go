@@ -0,0 +1,59 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+func TestNewEncryptedRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewEncrypted(path, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := SessionCookie{ID: "c1", Name: "li_at", Value: "AQEDAR-secret-session", Domain: ".linkedin.com", Path: "/", CreatedAt: time.Now()}
+	if err := db.SaveCookies([]SessionCookie{cookie}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := db.QueryRow(`SELECT value FROM session_cookies`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(stored) || strings.Contains(stored, cookie.Value) {
+		t.Errorf("stored value %q is not the encrypted cookie", stored)
+	}
+	db.Close()
+
+	// reopened with the same passphrase
+	db, err = NewEncrypted(path, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cookies, err := db.GetCookies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || cookies[0].Value != cookie.Value {
+		t.Errorf("cookies = %+v, want the saved li_at", cookies)
+	}
+}
+
+func TestNewEncryptedWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewEncrypted(path, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := NewEncrypted(path, "Correct horse battery staple"); !errors.Is(err, ErrEncryptionKeyInvalid) {
+		t.Errorf("NewEncrypted with the wrong passphrase = %v, want ErrEncryptionKeyInvalid", err)
+	}
+}
@@ -2,31 +2,39 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
-// DB represents the database connection
+// DB is the SQLite implementation of Store. SQLite-specific statements
+// (pragmas, schema DDL, migrations) live in sqlite.go; the queries here are
+// kept to portable SQL
 type DB struct {
 	*sql.DB
+	cipher *valueCipher // nil unless EnableEncryption succeeded
 }
 
 // Connection represents a LinkedIn connection
 type Connection struct {
-	ID                string
-	ProfileURL        string
-	FirstName         string
-	LastName          string
-	JobTitle          string
-	Company           string
-	Location          string
-	NoteSent          string
-	Status            string // pending, accepted, declined, failed
-	SearchCriteriaID  string
-	CreatedAt         time.Time
-	AcceptedAt        *time.Time
+	ID               string
+	ProfileURL       string
+	FirstName        string
+	LastName         string
+	JobTitle         string
+	Company          string
+	Location         string
+	NoteSent         string
+	Status           string // pending, accepted, declined, failed
+	SearchCriteriaID string // search criteria or suggestion filter that found the profile
+	PageNumber       int    // search results page the profile was found on
+	Position         int    // 1-based position of the profile on that page
+	Variant          string // A/B test tag, "<test>:<A|B>", empty when not in a test
+	SnapshotSource   string // where JobTitle and Company were read at send time: profile, search_card, suggestion_card, invitation_card
+	Incoming         bool   // the member invited us and we accepted
+	CreatedAt        time.Time
+	AcceptedAt       *time.Time
 }
 
 // Message represents a sent message
@@ -41,125 +49,238 @@ type Message struct {
 
 // DailyActivity tracks daily activity for rate limiting
 type DailyActivity struct {
-	ID                string
-	Date              string
-	ConnectionsSent   int
-	MessagesSent      int
-	LastConnectionAt  *time.Time
-	LastMessageAt     *time.Time
+	ID                  string
+	Date                string
+	ConnectionsSent     int
+	MessagesSent        int
+	InvitationsAccepted int // incoming invitations accepted
+	LastConnectionAt    *time.Time
+	LastMessageAt       *time.Time
 }
 
 // SessionCookie stores LinkedIn session cookies
 type SessionCookie struct {
-	ID        string
-	Name      string
+	ID             string
+	Name           string
+	Value          string
+	Domain         string
+	Path           string
+	ProxySessionID string    // run the cookie set was captured in
+	ExpiresAt      time.Time // zero for a session cookie, which has no expiry
+	CreatedAt      time.Time
+}
+
+// Expired reports whether the cookie expired by now. Session cookies never
+// do: they last as long as the session they belong to
+func (c SessionCookie) Expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !c.ExpiresAt.After(now)
+}
+
+// StorageItem is a localStorage entry saved with the session
+type StorageItem struct {
+	Origin    string // page origin the entry belongs to, e.g. https://www.linkedin.com
+	Key       string
 	Value     string
-	Domain    string
-	Path      string
-	ExpiresAt time.Time
+	Encoding  string // how Value is encoded; empty for plain text
 	CreatedAt time.Time
 }
 
-// New creates a new database connection
-func New(dbPath string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+// ProxySession records which proxy and egress IP a run used, so security
+// challenges can be correlated with IPs
+type ProxySession struct {
+	ID         string
+	Proxy      string
+	EgressIP   string
+	Challenged bool
+	StartedAt  time.Time
+}
 
-	// Enable WAL mode for better concurrent access
-	_, err = db.Exec("PRAGMA journal_mode=WAL")
-	if err != nil {
-		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
-	}
-
-	return &DB{db}, nil
-}
-
-// Initialize creates all required tables
-func (db *DB) Initialize() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS connections (
-		id TEXT PRIMARY KEY,
-		profile_url TEXT NOT NULL UNIQUE,
-		first_name TEXT,
-		last_name TEXT,
-		job_title TEXT,
-		company TEXT,
-		location TEXT,
-		note_sent TEXT,
-		status TEXT CHECK(status IN ('pending', 'accepted', 'declined', 'failed')) DEFAULT 'pending',
-		search_criteria_id TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		accepted_at DATETIME
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_connections_status ON connections(status);
-	CREATE INDEX IF NOT EXISTS idx_connections_created ON connections(created_at);
-
-	CREATE TABLE IF NOT EXISTS messages (
-		id TEXT PRIMARY KEY,
-		connection_id TEXT NOT NULL,
-		content TEXT NOT NULL,
-		template_id TEXT,
-		status TEXT CHECK(status IN ('sent', 'delivered', 'read', 'failed')) DEFAULT 'sent',
-		sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (connection_id) REFERENCES connections(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_messages_connection ON messages(connection_id);
-	CREATE INDEX IF NOT EXISTS idx_messages_sent ON messages(sent_at);
-
-	CREATE TABLE IF NOT EXISTS daily_activity (
-		id TEXT PRIMARY KEY,
-		date TEXT NOT NULL UNIQUE,
-		connections_sent INTEGER DEFAULT 0,
-		messages_sent INTEGER DEFAULT 0,
-		last_connection_at DATETIME,
-		last_message_at DATETIME
-	);
-
-	CREATE TABLE IF NOT EXISTS session_cookies (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		value TEXT NOT NULL,
-		domain TEXT,
-		path TEXT,
-		expires_at DATETIME,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS processed_profiles (
-		profile_url TEXT PRIMARY KEY,
-		processed_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`
+// QueuedProfile is a search result waiting for a connection request. The
+// queue lets the search and connect phases run in separate invocations
+type QueuedProfile struct {
+	ProfileURL string
+	FirstName  string
+	LastName   string
+	JobTitle   string
+	Company    string
+	Location   string
+	PageNumber int
+	Position   int
+	CriteriaID string // search criteria the profile was found with
+	QueuedAt   time.Time
+}
 
-	_, err := db.Exec(schema)
-	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
-	}
+// ProfileDetails holds enrichment read from a profile or its search card
+type ProfileDetails struct {
+	ProfileURL  string
+	Headline    string
+	MutualCount int
+	MutualNames []string
+	UpdatedAt   time.Time
+}
+
+// ProxyStats summarises challenge history for one proxy
+type ProxyStats struct {
+	Proxy      string
+	Sessions   int
+	Challenged int
+}
 
-	return nil
+// LimitHit records LinkedIn refusing invitations for hitting a limit
+type LimitHit struct {
+	Kind         string // e.g. "weekly"
+	SentInWindow int    // invitations sent in the limit's window when it was hit
+	DetectedAt   time.Time
 }
 
 // ============== Connection Methods ==============
 
+// execer is implemented by both *sql.DB and *sql.Tx, so write helpers can
+// run standalone or as part of a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// withTx runs fn in a transaction, committing if it succeeds and rolling
+// back otherwise
+func (db *DB) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // SaveConnection saves a new connection to the database
 func (db *DB) SaveConnection(conn *Connection) error {
+	return saveConnection(db, conn)
+}
+
+func saveConnection(ex execer, conn *Connection) error {
 	query := `
-	INSERT INTO connections (id, profile_url, first_name, last_name, job_title, company, location, note_sent, status, search_criteria_id, created_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO connections (id, profile_url, first_name, last_name, job_title, company, location, note_sent, status, search_criteria_id, page_number, position, variant, snapshot_source, incoming, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(profile_url) DO UPDATE SET
+		job_title = excluded.job_title,
+		company = excluded.company,
 		note_sent = excluded.note_sent,
-		status = excluded.status
+		status = excluded.status,
+		search_criteria_id = excluded.search_criteria_id,
+		page_number = excluded.page_number,
+		position = excluded.position,
+		variant = excluded.variant,
+		snapshot_source = excluded.snapshot_source
 	`
-	_, err := db.Exec(query, conn.ID, conn.ProfileURL, conn.FirstName, conn.LastName, 
+	_, err := ex.Exec(query, conn.ID, conn.ProfileURL, conn.FirstName, conn.LastName, 
 		conn.JobTitle, conn.Company, conn.Location, conn.NoteSent, conn.Status, 
-		conn.SearchCriteriaID, conn.CreatedAt)
+		conn.SearchCriteriaID, conn.PageNumber, conn.Position, conn.Variant, conn.SnapshotSource, conn.Incoming, conn.CreatedAt)
 	return err
 }
 
+// RecordConnectionSent writes everything that follows a sent invitation in
+// one transaction: the connection row and the processed marker. An
+// interruption leaves either both or neither. Today's connection count was
+// taken by ReserveConnectionSlot before the invitation went out
+func (db *DB) RecordConnectionSent(conn *Connection) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := saveConnection(tx, conn); err != nil {
+			return fmt.Errorf("save connection: %w", err)
+		}
+		if err := markProfileProcessed(tx, conn.ProfileURL); err != nil {
+			return fmt.Errorf("mark profile processed: %w", err)
+		}
+		return nil
+	})
+}
+
+// RecordInvitationAccepted records an accepted incoming invitation in one
+// transaction: the connection row, already accepted, today's accepted
+// invitation count and the processed marker
+func (db *DB) RecordInvitationAccepted(conn *Connection) error {
+	conn.Status = "accepted"
+	conn.Incoming = true
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := saveConnection(tx, conn); err != nil {
+			return fmt.Errorf("save connection: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE connections SET accepted_at = CURRENT_TIMESTAMP WHERE profile_url = ?`, conn.ProfileURL); err != nil {
+			return fmt.Errorf("stamp acceptance: %w", err)
+		}
+		if err := incrementDailyCount(tx, "invitations_accepted", "last_invitation_accepted_at"); err != nil {
+			return fmt.Errorf("increment accepted invitation count: %w", err)
+		}
+		if err := markProfileProcessed(tx, conn.ProfileURL); err != nil {
+			return fmt.Errorf("mark profile processed: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetConnectionsSentSince returns the send times of connection requests
+// made at or after since, oldest first. Accepted incoming invitations are
+// not requests we sent and are left out
+func (db *DB) GetConnectionsSentSince(since time.Time) ([]time.Time, error) {
+	rows, err := db.Query(`SELECT created_at FROM connections WHERE created_at >= ? AND COALESCE(incoming, 0) = 0 ORDER BY created_at`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sent []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		sent = append(sent, t)
+	}
+	return sent, rows.Err()
+}
+
+// GetWeeklyConnectionCount returns the invitations sent in the last 7 days
+func (db *DB) GetWeeklyConnectionCount() (int, error) {
+	return db.countConnectionsSince(time.Now().AddDate(0, 0, -7))
+}
+
+// GetMonthlyConnectionCount returns the invitations sent in the last 30
+// days
+func (db *DB) GetMonthlyConnectionCount() (int, error) {
+	return db.countConnectionsSince(time.Now().AddDate(0, 0, -30))
+}
+
+func (db *DB) countConnectionsSince(since time.Time) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM connections WHERE created_at >= ? AND COALESCE(incoming, 0) = 0`, since).Scan(&n)
+	return n, err
+}
+
+// ============== Limit Methods ==============
+
+// RecordLimitHit stores a detected limit
+func (db *DB) RecordLimitHit(hit *LimitHit) error {
+	_, err := db.Exec(`INSERT INTO limit_hits (kind, sent_in_window, detected_at) VALUES (?, ?, ?)`,
+		hit.Kind, hit.SentInWindow, hit.DetectedAt)
+	return err
+}
+
+// GetLastLimitHit returns the most recent hit of the given kind, or nil
+func (db *DB) GetLastLimitHit(kind string) (*LimitHit, error) {
+	var hit LimitHit
+	err := db.QueryRow(`SELECT kind, sent_in_window, detected_at FROM limit_hits WHERE kind = ? ORDER BY id DESC LIMIT 1`, kind).
+		Scan(&hit.Kind, &hit.SentInWindow, &hit.DetectedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hit, nil
+}
+
 // UpdateConnectionStatus updates the status of a connection
 func (db *DB) UpdateConnectionStatus(profileURL, status string) error {
 	query := `UPDATE connections SET status = ? WHERE profile_url = ?`
@@ -170,10 +291,28 @@ func (db *DB) UpdateConnectionStatus(profileURL, status string) error {
 	return err
 }
 
+// connectionColumns is the column list used when reading connections
+const connectionColumns = `id, profile_url, first_name, last_name, job_title, company, location, note_sent, status, COALESCE(search_criteria_id, ''), page_number, position, COALESCE(variant, ''), COALESCE(snapshot_source, ''), COALESCE(incoming, 0), created_at, accepted_at`
+
 // GetPendingConnections returns all pending connections
 func (db *DB) GetPendingConnections() ([]Connection, error) {
-	query := `SELECT id, profile_url, first_name, last_name, job_title, company, location, note_sent, status, search_criteria_id, created_at, accepted_at FROM connections WHERE status = 'pending'`
-	rows, err := db.Query(query)
+	return db.queryConnections(`SELECT ` + connectionColumns + ` FROM connections WHERE status = 'pending'`)
+}
+
+// GetAcceptedConnections returns all accepted connections
+func (db *DB) GetAcceptedConnections() ([]Connection, error) {
+	return db.queryConnections(`SELECT ` + connectionColumns + ` FROM connections WHERE status = 'accepted'`)
+}
+
+// ExportConnections returns every connection, oldest first, as the audit
+// record of who was contacted, why and with which note
+func (db *DB) ExportConnections() ([]Connection, error) {
+	return db.queryConnections(`SELECT ` + connectionColumns + ` FROM connections ORDER BY created_at`)
+}
+
+// queryConnections runs a query selecting connectionColumns and scans the rows
+func (db *DB) queryConnections(query string, args ...interface{}) ([]Connection, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -184,7 +323,7 @@ func (db *DB) GetPendingConnections() ([]Connection, error) {
 		var c Connection
 		err := rows.Scan(&c.ID, &c.ProfileURL, &c.FirstName, &c.LastName, &c.JobTitle, 
 			&c.Company, &c.Location, &c.NoteSent, &c.Status, &c.SearchCriteriaID, 
-			&c.CreatedAt, &c.AcceptedAt)
+			&c.PageNumber, &c.Position, &c.Variant, &c.SnapshotSource, &c.Incoming, &c.CreatedAt, &c.AcceptedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -193,27 +332,60 @@ func (db *DB) GetPendingConnections() ([]Connection, error) {
 	return connections, nil
 }
 
-// GetAcceptedConnections returns all accepted connections
-func (db *DB) GetAcceptedConnections() ([]Connection, error) {
-	query := `SELECT id, profile_url, first_name, last_name, job_title, company, location, note_sent, status, search_criteria_id, created_at, accepted_at FROM connections WHERE status = 'accepted'`
-	rows, err := db.Query(query)
+// PageStats summarizes connection outcomes for one search results page
+type PageStats struct {
+	PageNumber int
+	Sent       int
+	Accepted   int
+}
+
+// GetAcceptanceByPage returns sent and accepted connection counts grouped by
+// the search page the profile originated from
+func (db *DB) GetAcceptanceByPage() ([]PageStats, error) {
+	rows, err := db.Query(`SELECT page_number, COUNT(*), SUM(CASE WHEN status = 'accepted' THEN 1 ELSE 0 END) FROM connections WHERE COALESCE(incoming, 0) = 0 GROUP BY page_number ORDER BY page_number`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var connections []Connection
+	var stats []PageStats
 	for rows.Next() {
-		var c Connection
-		err := rows.Scan(&c.ID, &c.ProfileURL, &c.FirstName, &c.LastName, &c.JobTitle, 
-			&c.Company, &c.Location, &c.NoteSent, &c.Status, &c.SearchCriteriaID, 
-			&c.CreatedAt, &c.AcceptedAt)
-		if err != nil {
+		var ps PageStats
+		if err := rows.Scan(&ps.PageNumber, &ps.Sent, &ps.Accepted); err != nil {
 			return nil, err
 		}
-		connections = append(connections, c)
+		stats = append(stats, ps)
 	}
-	return connections, nil
+	return stats, rows.Err()
+}
+
+// VariantStats summarizes connection outcomes for one A/B test variant
+type VariantStats struct {
+	Variant  string
+	Sent     int
+	Accepted int
+}
+
+// GetVariantStats returns sent and accepted counts per A/B test variant
+func (db *DB) GetVariantStats() ([]VariantStats, error) {
+	rows, err := db.Query(`SELECT variant, COUNT(*), SUM(CASE WHEN status = 'accepted' THEN 1 ELSE 0 END) FROM connections WHERE variant IS NOT NULL AND variant != '' GROUP BY variant ORDER BY variant`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []VariantStats
+	for rows.Next() {
+		var vs VariantStats
+		if err := rows.Scan(&vs.Variant, &vs.Sent, &vs.Accepted); err != nil {
+			return nil, err
+		}
+		stats = append(stats, vs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
 }
 
 // IsProfileProcessed checks if a profile URL has been processed
@@ -225,19 +397,174 @@ func (db *DB) IsProfileProcessed(profileURL string) (bool, error) {
 
 // MarkProfileProcessed marks a profile URL as processed
 func (db *DB) MarkProfileProcessed(profileURL string) error {
-	_, err := db.Exec(`INSERT OR IGNORE INTO processed_profiles (profile_url) VALUES (?)`, profileURL)
+	return markProfileProcessed(db, profileURL)
+}
+
+func markProfileProcessed(ex execer, profileURL string) error {
+	_, err := ex.Exec(`INSERT INTO processed_profiles (profile_url) VALUES (?) ON CONFLICT(profile_url) DO NOTHING`, profileURL)
+	return err
+}
+
+// ReconcileProfileURL moves the record for a profile whose URL changed
+// (e.g. a renamed vanity slug) onto its new URL. If a connection already
+// exists under the new URL, the old row is merged into it: its messages are
+// re-pointed and the old row is removed. If the old URL was marked
+// processed, the new one is marked too
+func (db *DB) ReconcileProfileURL(oldURL, newURL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldID, newID sql.NullString
+	if err := tx.QueryRow(`SELECT id FROM connections WHERE profile_url = ?`, oldURL).Scan(&oldID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err := tx.QueryRow(`SELECT id FROM connections WHERE profile_url = ?`, newURL).Scan(&newID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	switch {
+	case oldID.Valid && newID.Valid:
+		if _, err := tx.Exec(`UPDATE messages SET connection_id = ? WHERE connection_id = ?`, newID.String, oldID.String); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM connections WHERE id = ?`, oldID.String); err != nil {
+			return err
+		}
+	case oldID.Valid:
+		if _, err := tx.Exec(`UPDATE connections SET profile_url = ? WHERE id = ?`, newURL, oldID.String); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO processed_profiles (profile_url) SELECT ? WHERE EXISTS(SELECT 1 FROM processed_profiles WHERE profile_url = ?) ON CONFLICT(profile_url) DO NOTHING`, newURL, oldURL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ============== Search Queue Methods ==============
+
+// EnqueueProfile adds a search result to the connection queue. Profiles
+// already queued keep their original entry
+func (db *DB) EnqueueProfile(p *QueuedProfile) error {
+	_, err := db.Exec(`INSERT INTO search_queue (profile_url, first_name, last_name, job_title, company, location, page_number, position, criteria_id, queued_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT(profile_url) DO NOTHING`,
+		p.ProfileURL, p.FirstName, p.LastName, p.JobTitle, p.Company, p.Location, p.PageNumber, p.Position, p.CriteriaID, p.QueuedAt)
 	return err
 }
 
+// GetQueuedProfiles returns queued profiles that have not been processed
+// yet, oldest first and in search result order
+func (db *DB) GetQueuedProfiles() ([]QueuedProfile, error) {
+	rows, err := db.Query(`SELECT profile_url, first_name, last_name, job_title, company, location, page_number, position, COALESCE(criteria_id, ''), queued_at
+		FROM search_queue
+		WHERE profile_url NOT IN (SELECT profile_url FROM processed_profiles)
+		ORDER BY queued_at, page_number, position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []QueuedProfile
+	for rows.Next() {
+		var p QueuedProfile
+		if err := rows.Scan(&p.ProfileURL, &p.FirstName, &p.LastName, &p.JobTitle, &p.Company, &p.Location, &p.PageNumber, &p.Position, &p.CriteriaID, &p.QueuedAt); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// ============== Profile Details Methods ==============
+
+// SaveProfileDetails stores enrichment for a profile, replacing what was
+// stored before. An empty headline keeps the previous one
+func (db *DB) SaveProfileDetails(d *ProfileDetails) error {
+	_, err := db.Exec(`INSERT INTO profile_details (profile_url, headline, mutual_count, mutual_names, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(profile_url) DO UPDATE SET
+			headline = CASE WHEN excluded.headline != '' THEN excluded.headline ELSE profile_details.headline END,
+			mutual_count = excluded.mutual_count,
+			mutual_names = excluded.mutual_names,
+			updated_at = excluded.updated_at`,
+		d.ProfileURL, d.Headline, d.MutualCount, strings.Join(d.MutualNames, "\n"), d.UpdatedAt)
+	return err
+}
+
+// GetProfileDetails returns the stored enrichment for a profile, or nil if
+// there is none
+func (db *DB) GetProfileDetails(profileURL string) (*ProfileDetails, error) {
+	d := ProfileDetails{ProfileURL: profileURL}
+	var names string
+	err := db.QueryRow(`SELECT COALESCE(headline, ''), mutual_count, COALESCE(mutual_names, ''), updated_at FROM profile_details WHERE profile_url = ?`, profileURL).
+		Scan(&d.Headline, &d.MutualCount, &names, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if names != "" {
+		d.MutualNames = strings.Split(names, "\n")
+	}
+	return &d, nil
+}
+
+// ============== Geo Cache Methods ==============
+
+// GetGeoUrn returns the cached geo URN of a location, or "" when none is
+// cached. Locations are matched case-insensitively
+func (db *DB) GetGeoUrn(location string) (string, error) {
+	var urn string
+	err := db.QueryRow(`SELECT urn FROM geo_cache WHERE location = ?`, geoCacheKey(location)).Scan(&urn)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return urn, err
+}
+
+// SaveGeoUrn caches the geo URN resolved for a location
+func (db *DB) SaveGeoUrn(location, urn string) error {
+	_, err := db.Exec(`INSERT INTO geo_cache (location, urn, resolved_at) VALUES (?, ?, ?)
+		ON CONFLICT(location) DO UPDATE SET urn = excluded.urn, resolved_at = excluded.resolved_at`,
+		geoCacheKey(location), urn, time.Now())
+	return err
+}
+
+func geoCacheKey(location string) string {
+	return strings.ToLower(strings.Join(strings.Fields(location), " "))
+}
+
 // ============== Message Methods ==============
 
 // SaveMessage saves a new message to the database
 func (db *DB) SaveMessage(msg *Message) error {
+	return saveMessage(db, msg)
+}
+
+func saveMessage(ex execer, msg *Message) error {
 	query := `INSERT INTO messages (id, connection_id, content, template_id, status, sent_at) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := db.Exec(query, msg.ID, msg.ConnectionID, msg.Content, msg.TemplateID, msg.Status, msg.SentAt)
+	_, err := ex.Exec(query, msg.ID, msg.ConnectionID, msg.Content, msg.TemplateID, msg.Status, msg.SentAt)
 	return err
 }
 
+// RecordMessageSent saves a sent message and counts it toward today's
+// message total in one transaction
+func (db *DB) RecordMessageSent(msg *Message) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := saveMessage(tx, msg); err != nil {
+			return fmt.Errorf("save message: %w", err)
+		}
+		if err := incrementDailyCount(tx, "messages_sent", "last_message_at"); err != nil {
+			return fmt.Errorf("increment message count: %w", err)
+		}
+		return nil
+	})
+}
+
 // GetMessagesForConnection returns all messages for a specific connection
 func (db *DB) GetMessagesForConnection(connectionID string) ([]Message, error) {
 	query := `SELECT id, connection_id, content, template_id, status, sent_at FROM messages WHERE connection_id = ? ORDER BY sent_at DESC`
@@ -259,6 +586,42 @@ func (db *DB) GetMessagesForConnection(connectionID string) ([]Message, error) {
 	return messages, nil
 }
 
+// ExportedMessage is a sent message with the name and profile of its
+// connection. ConnectionMissing is set when the connection row is gone
+type ExportedMessage struct {
+	Message
+	FirstName         string
+	LastName          string
+	ProfileURL        string
+	ConnectionMissing bool
+}
+
+// ExportMessages returns every message, oldest first, joined to its
+// connection where that still exists
+func (db *DB) ExportMessages() ([]ExportedMessage, error) {
+	query := `SELECT m.id, m.connection_id, m.content, COALESCE(m.template_id, ''), m.status, m.sent_at,
+		COALESCE(c.first_name, ''), COALESCE(c.last_name, ''), COALESCE(c.profile_url, ''), c.id IS NULL
+		FROM messages m LEFT JOIN connections c ON c.id = m.connection_id
+		ORDER BY m.sent_at`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []ExportedMessage
+	for rows.Next() {
+		var m ExportedMessage
+		err := rows.Scan(&m.ID, &m.ConnectionID, &m.Content, &m.TemplateID, &m.Status, &m.SentAt,
+			&m.FirstName, &m.LastName, &m.ProfileURL, &m.ConnectionMissing)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
 // HasSentFollowUp checks if a follow-up message has been sent to a connection
 func (db *DB) HasSentFollowUp(connectionID string) (bool, error) {
 	var exists bool
@@ -268,66 +631,251 @@ func (db *DB) HasSentFollowUp(connectionID string) (bool, error) {
 
 // ============== Daily Activity Methods ==============
 
-// GetOrCreateDailyActivity gets or creates today's activity record
+// GetOrCreateDailyActivity gets or creates today's activity record. The
+// row is created with an insert that yields to an existing one and read
+// back in the same transaction, so callers racing to create it all get the
+// one row
 func (db *DB) GetOrCreateDailyActivity() (*DailyActivity, error) {
 	today := time.Now().Format("2006-01-02")
-	
+
 	var activity DailyActivity
-	err := db.QueryRow(`SELECT id, date, connections_sent, messages_sent, last_connection_at, last_message_at FROM daily_activity WHERE date = ?`, today).Scan(
-		&activity.ID, &activity.Date, &activity.ConnectionsSent, &activity.MessagesSent, 
-		&activity.LastConnectionAt, &activity.LastMessageAt)
-	
-	if err == sql.ErrNoRows {
-		// Create new record for today
-		id := fmt.Sprintf("activity_%s", today)
-		_, err = db.Exec(`INSERT INTO daily_activity (id, date, connections_sent, messages_sent) VALUES (?, ?, 0, 0)`, id, today)
+	err := db.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO daily_activity (id, date, connections_sent, messages_sent) VALUES (?, ?, 0, 0) ON CONFLICT DO NOTHING`, "activity_"+today, today)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		return &DailyActivity{ID: id, Date: today}, nil
+		return tx.QueryRow(`SELECT id, date, connections_sent, messages_sent, COALESCE(invitations_accepted, 0), last_connection_at, last_message_at FROM daily_activity WHERE date = ?`, today).Scan(
+			&activity.ID, &activity.Date, &activity.ConnectionsSent, &activity.MessagesSent, &activity.InvitationsAccepted,
+			&activity.LastConnectionAt, &activity.LastMessageAt)
+	})
+	if err != nil {
+		return nil, err
 	}
-	
-	return &activity, err
+	return &activity, nil
 }
 
 // IncrementConnectionCount increments today's connection count
 func (db *DB) IncrementConnectionCount() error {
+	return incrementDailyCount(db, "connections_sent", "last_connection_at")
+}
+
+// ReserveConnectionSlot takes one of today's connection slots when fewer
+// than limit invitations have been counted, and reports whether it got
+// one and the date it was counted on. That date must be passed to
+// ReleaseConnectionSlot if the invitation does not go out. The check and
+// the increment are a single conditional upsert, so two senders can never
+// both take the last slot
+func (db *DB) ReserveConnectionSlot(limit int) (string, bool, error) {
 	today := time.Now().Format("2006-01-02")
-	_, err := db.Exec(`UPDATE daily_activity SET connections_sent = connections_sent + 1, last_connection_at = CURRENT_TIMESTAMP WHERE date = ?`, today)
+	if limit <= 0 {
+		return today, false, nil
+	}
+	res, err := db.Exec(`INSERT INTO daily_activity (id, date, connections_sent, last_connection_at) VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT(date) DO UPDATE SET connections_sent = connections_sent + 1, last_connection_at = CURRENT_TIMESTAMP
+		WHERE connections_sent < ?`, "activity_"+today, today, limit)
+	if err != nil {
+		return today, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return today, false, err
+	}
+	return today, n == 1, nil
+}
+
+// ReleaseConnectionSlot gives back a slot taken by ReserveConnectionSlot
+// for an invitation that did not go out. date is the one the slot was
+// reserved on, so a send failing after midnight gives back the day it was
+// counted against
+func (db *DB) ReleaseConnectionSlot(date string) error {
+	_, err := db.Exec(`UPDATE daily_activity SET connections_sent = connections_sent - 1 WHERE date = ? AND connections_sent > 0`, date)
 	return err
 }
 
 // IncrementMessageCount increments today's message count
 func (db *DB) IncrementMessageCount() error {
+	return incrementDailyCount(db, "messages_sent", "last_message_at")
+}
+
+// incrementDailyCount bumps one of today's counters, creating today's row
+// if it does not exist yet. column and stamp are fixed column names, never
+// user input
+func incrementDailyCount(ex execer, column, stamp string) error {
 	today := time.Now().Format("2006-01-02")
-	_, err := db.Exec(`UPDATE daily_activity SET messages_sent = messages_sent + 1, last_message_at = CURRENT_TIMESTAMP WHERE date = ?`, today)
+	_, err := ex.Exec(fmt.Sprintf(`INSERT INTO daily_activity (id, date, %[1]s, %[2]s) VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT(date) DO UPDATE SET %[1]s = %[1]s + 1, %[2]s = CURRENT_TIMESTAMP`, column, stamp),
+		"activity_"+today, today)
 	return err
 }
 
-// ============== Session Cookie Methods ==============
+// ActivityDay is one day of outreach activity
+type ActivityDay struct {
+	Date            string // YYYY-MM-DD
+	ConnectionsSent int
+	MessagesSent    int
+	Accepted        int // connections whose acceptance was detected that day
+}
 
-// SaveCookies saves session cookies
-func (db *DB) SaveCookies(cookies []SessionCookie) error {
-	// Clear existing cookies
-	_, err := db.Exec(`DELETE FROM session_cookies`)
+// GetActivityTimeSeries returns one row per day for the last days days,
+// oldest first and ending today. Days without activity are zero rows
+func (db *DB) GetActivityTimeSeries(days int) ([]ActivityDay, error) {
+	if days <= 0 {
+		return nil, nil
+	}
+	today := time.Now()
+	start := today.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+
+	byDate := make(map[string]*ActivityDay)
+	series := make([]ActivityDay, days)
+	for i := range series {
+		series[i].Date = today.AddDate(0, 0, i-(days-1)).Format("2006-01-02")
+		byDate[series[i].Date] = &series[i]
+	}
+
+	rows, err := db.Query(`SELECT date, connections_sent, messages_sent FROM daily_activity WHERE date >= ?`, start)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for rows.Next() {
+		var date string
+		var connections, messages int
+		if err := rows.Scan(&date, &connections, &messages); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if day, ok := byDate[date]; ok {
+			day.ConnectionsSent = connections
+			day.MessagesSent = messages
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
 	}
 
-	// Insert new cookies
-	for _, cookie := range cookies {
-		_, err = db.Exec(`INSERT INTO session_cookies (id, name, value, domain, path, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			cookie.ID, cookie.Name, cookie.Value, cookie.Domain, cookie.Path, cookie.ExpiresAt, cookie.CreatedAt)
-		if err != nil {
-			return err
+	// accepted_at is a UTC CURRENT_TIMESTAMP while the days are local, so
+	// acceptances are bucketed here rather than with SQLite's DATE()
+	windowStart, err := time.ParseInLocation("2006-01-02", start, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	rows, err = db.Query(`SELECT accepted_at FROM connections WHERE accepted_at IS NOT NULL AND COALESCE(incoming, 0) = 0 AND accepted_at >= ?`,
+		windowStart.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var acceptedAt time.Time
+		if err := rows.Scan(&acceptedAt); err != nil {
+			return nil, err
+		}
+		if day, ok := byDate[acceptedAt.In(time.Local).Format("2006-01-02")]; ok {
+			day.Accepted++
 		}
 	}
-	return nil
+	return series, rows.Err()
 }
 
-// GetCookies retrieves stored session cookies
+// ============== Template Usage Methods ==============
+
+// GetTemplateUsage returns today's use count per template index for kind
+func (db *DB) GetTemplateUsage(kind string) (map[int]int, error) {
+	today := time.Now().Format("2006-01-02")
+	rows, err := db.Query(`SELECT template_idx, uses FROM template_usage WHERE date = ? AND kind = ?`, today, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int]int)
+	for rows.Next() {
+		var idx, uses int
+		if err := rows.Scan(&idx, &uses); err != nil {
+			return nil, err
+		}
+		usage[idx] = uses
+	}
+	return usage, rows.Err()
+}
+
+// TemplateStat is how often a template was used across all runs, and when last
+type TemplateStat struct {
+	Uses       int
+	LastUsedAt time.Time
+}
+
+// GetTemplateStats returns each template's use count and last use for kind,
+// summed over every day recorded
+func (db *DB) GetTemplateStats(kind string) (map[int]TemplateStat, error) {
+	rows, err := db.Query(`SELECT template_idx, uses, last_used_at FROM template_usage WHERE kind = ?`, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[int]TemplateStat)
+	for rows.Next() {
+		var idx, uses int
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&idx, &uses, &lastUsed); err != nil {
+			return nil, err
+		}
+		stat := stats[idx]
+		stat.Uses += uses
+		if lastUsed.Valid && lastUsed.Time.After(stat.LastUsedAt) {
+			stat.LastUsedAt = lastUsed.Time
+		}
+		stats[idx] = stat
+	}
+	return stats, rows.Err()
+}
+
+// IncrementTemplateUsage records one use of a template today
+func (db *DB) IncrementTemplateUsage(kind string, idx int) error {
+	today := time.Now().Format("2006-01-02")
+	_, err := db.Exec(`INSERT INTO template_usage (date, kind, template_idx, uses, last_used_at) VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT(date, kind, template_idx) DO UPDATE SET uses = uses + 1, last_used_at = excluded.last_used_at`,
+		today, kind, idx, time.Now())
+	return err
+}
+
+// ============== Session Cookie Methods ==============
+
+// SaveCookies saves session cookies
+func (db *DB) SaveCookies(cookies []SessionCookie) error {
+	// The old cookies are replaced in one transaction, so a failure
+	// halfway keeps the saved session rather than a part of the new one
+	return db.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM session_cookies`); err != nil {
+			return err
+		}
+
+		for _, cookie := range cookies {
+			value, err := db.sealValue(cookie.Value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt cookie %s: %w", cookie.Name, err)
+			}
+			// Session cookies are stored without an expiry
+			var expiresAt sql.NullTime
+			if !cookie.ExpiresAt.IsZero() {
+				expiresAt = sql.NullTime{Time: cookie.ExpiresAt, Valid: true}
+			}
+			_, err = tx.Exec(`INSERT INTO session_cookies (id, name, value, domain, path, proxy_session_id, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				cookie.ID, cookie.Name, value, cookie.Domain, cookie.Path, cookie.ProxySessionID, expiresAt, cookie.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCookies retrieves stored session cookies, decrypting values that were
+// stored encrypted
 func (db *DB) GetCookies() ([]SessionCookie, error) {
-	rows, err := db.Query(`SELECT id, name, value, domain, path, expires_at, created_at FROM session_cookies`)
+	rows, err := db.Query(`SELECT id, name, value, domain, path, COALESCE(proxy_session_id, ''), expires_at, created_at FROM session_cookies`)
 	if err != nil {
 		return nil, err
 	}
@@ -336,12 +884,17 @@ func (db *DB) GetCookies() ([]SessionCookie, error) {
 	var cookies []SessionCookie
 	for rows.Next() {
 		var c SessionCookie
-		err := rows.Scan(&c.ID, &c.Name, &c.Value, &c.Domain, &c.Path, &c.ExpiresAt, &c.CreatedAt)
+		var expiresAt sql.NullTime
+		err := rows.Scan(&c.ID, &c.Name, &c.Value, &c.Domain, &c.Path, &c.ProxySessionID, &expiresAt, &c.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		c.ExpiresAt = expiresAt.Time
+		if c.Value, err = db.openValue(c.Value); err != nil {
+			return nil, fmt.Errorf("failed to decrypt cookie %s: %w", c.Name, err)
+		}
 		// Skip expired cookies
-		if c.ExpiresAt.After(time.Now()) {
+		if !c.Expired(time.Now()) {
 			cookies = append(cookies, c)
 		}
 	}
@@ -354,6 +907,242 @@ func (db *DB) ClearCookies() error {
 	return err
 }
 
+// ============== Session Storage Methods ==============
+
+// SaveLocalStorage replaces the saved localStorage entries in one
+// transaction, like SaveCookies. Values are encrypted like cookie values
+func (db *DB) SaveLocalStorage(items []StorageItem) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM session_storage`); err != nil {
+			return err
+		}
+		for _, item := range items {
+			value, err := db.sealValue(item.Value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt local storage %s: %w", item.Key, err)
+			}
+			_, err = tx.Exec(`INSERT INTO session_storage (origin, key, value, encoding, created_at) VALUES (?, ?, ?, ?, ?)`,
+				item.Origin, item.Key, value, item.Encoding, item.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLocalStorage returns the saved localStorage entries
+func (db *DB) GetLocalStorage() ([]StorageItem, error) {
+	rows, err := db.Query(`SELECT origin, key, value, COALESCE(encoding, ''), created_at FROM session_storage`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []StorageItem
+	for rows.Next() {
+		var item StorageItem
+		if err := rows.Scan(&item.Origin, &item.Key, &item.Value, &item.Encoding, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		if item.Value, err = db.openValue(item.Value); err != nil {
+			return nil, fmt.Errorf("failed to decrypt local storage %s: %w", item.Key, err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ClearLocalStorage removes the saved localStorage entries
+func (db *DB) ClearLocalStorage() error {
+	_, err := db.Exec(`DELETE FROM session_storage`)
+	return err
+}
+
+// ============== Burst Plan Methods ==============
+
+// BurstPlan is one day's schedule of activity bursts
+type BurstPlan struct {
+	Date      string      // YYYY-MM-DD
+	Starts    []time.Time // planned burst start times, in order
+	Completed int         // bursts already run
+}
+
+// GetBurstPlan returns the burst plan for date, or nil when none was made
+func (db *DB) GetBurstPlan(date string) (*BurstPlan, error) {
+	var starts string
+	p := &BurstPlan{Date: date}
+	err := db.QueryRow(`SELECT starts, completed FROM burst_plans WHERE date = ?`, date).Scan(&starts, &p.Completed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range strings.Split(starts, "\n") {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("burst plan for %s: %w", date, err)
+		}
+		p.Starts = append(p.Starts, t)
+	}
+	return p, nil
+}
+
+// SaveBurstPlan stores a burst plan, replacing the one for the same date
+func (db *DB) SaveBurstPlan(p *BurstPlan) error {
+	starts := make([]string, len(p.Starts))
+	for i, t := range p.Starts {
+		starts[i] = t.Format(time.RFC3339)
+	}
+	_, err := db.Exec(`INSERT INTO burst_plans (date, starts, completed) VALUES (?, ?, ?)
+		ON CONFLICT(date) DO UPDATE SET starts = excluded.starts, completed = excluded.completed`,
+		p.Date, strings.Join(starts, "\n"), p.Completed)
+	return err
+}
+
+// ============== Run Checkpoint Methods ==============
+
+// RunCheckpoint is where the connect phase of an unfinished run stopped
+type RunCheckpoint struct {
+	RunID     string
+	Index     int             // profiles of the run's queue handled before the checkpoint
+	Profiles  []QueuedProfile // the rest of the queue, next profile first
+	UpdatedAt time.Time
+}
+
+// SaveRunCheckpoint records that runID is about to handle profiles[index],
+// storing the rest of its queue. Only the latest run can be resumed, so
+// checkpoints of other runs are dropped
+func (db *DB) SaveRunCheckpoint(runID string, index int, profiles []QueuedProfile) error {
+	if index < 0 || index > len(profiles) {
+		return fmt.Errorf("checkpoint index %d outside a queue of %d", index, len(profiles))
+	}
+	queue, err := json.Marshal(profiles[index:])
+	if err != nil {
+		return fmt.Errorf("encode checkpoint queue: %w", err)
+	}
+
+	return db.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM run_checkpoints WHERE run_id != ?`, runID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO run_checkpoints (run_id, position, queue, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(run_id) DO UPDATE SET position = excluded.position, queue = excluded.queue, updated_at = excluded.updated_at`,
+			runID, index, string(queue), time.Now())
+		return err
+	})
+}
+
+// LoadRunCheckpoint returns the checkpoint of the latest unfinished run, or
+// nil when the last run finished
+func (db *DB) LoadRunCheckpoint() (*RunCheckpoint, error) {
+	var queue string
+	cp := &RunCheckpoint{}
+	err := db.QueryRow(`SELECT run_id, position, queue, updated_at FROM run_checkpoints ORDER BY updated_at DESC LIMIT 1`).
+		Scan(&cp.RunID, &cp.Index, &queue, &cp.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(queue), &cp.Profiles); err != nil {
+		return nil, fmt.Errorf("checkpoint of run %s: %w", cp.RunID, err)
+	}
+	return cp, nil
+}
+
+// ClearRunCheckpoint drops the checkpoint of a run that finished
+func (db *DB) ClearRunCheckpoint(runID string) error {
+	_, err := db.Exec(`DELETE FROM run_checkpoints WHERE run_id = ?`, runID)
+	return err
+}
+
+// ============== Proxy Session Methods ==============
+
+// StartProxySession records the proxy and egress IP used by this run
+func (db *DB) StartProxySession(s *ProxySession) error {
+	_, err := db.Exec(`INSERT INTO proxy_sessions (id, proxy, egress_ip, challenged, started_at) VALUES (?, ?, ?, ?, ?)`,
+		s.ID, s.Proxy, s.EgressIP, s.Challenged, s.StartedAt)
+	return err
+}
+
+// MarkProxySessionChallenged flags a run as having hit a security challenge
+func (db *DB) MarkProxySessionChallenged(id string) error {
+	_, err := db.Exec(`UPDATE proxy_sessions SET challenged = 1 WHERE id = ?`, id)
+	return err
+}
+
+// MarkProxySessionAuthenticated flags a run as having logged in
+func (db *DB) MarkProxySessionAuthenticated(id string) error {
+	_, err := db.Exec(`UPDATE proxy_sessions SET authenticated = 1 WHERE id = ?`, id)
+	return err
+}
+
+// CountAuthenticatedSessions returns how many runs got past login. It is
+// the account's run count for the observe-only break-in period
+func (db *DB) CountAuthenticatedSessions() (int, error) {
+	var runs int
+	err := db.QueryRow(`SELECT COUNT(*) FROM proxy_sessions WHERE authenticated = 1`).Scan(&runs)
+	return runs, err
+}
+
+// GetRunsSinceChallenge returns when the most recent challenged session
+// started and how many sessions started after it. The time is nil when no
+// session was ever challenged
+func (db *DB) GetRunsSinceChallenge() (int, *time.Time, error) {
+	var last time.Time
+	err := db.QueryRow(`SELECT started_at FROM proxy_sessions WHERE challenged = 1 ORDER BY started_at DESC LIMIT 1`).Scan(&last)
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var runs int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM proxy_sessions WHERE started_at > ?`, last).Scan(&runs); err != nil {
+		return 0, nil, err
+	}
+	return runs, &last, nil
+}
+
+// GetProxyStats returns session and challenge counts per proxy
+func (db *DB) GetProxyStats() ([]ProxyStats, error) {
+	rows, err := db.Query(`SELECT proxy, COUNT(*), SUM(challenged) FROM proxy_sessions GROUP BY proxy ORDER BY proxy`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ProxyStats
+	for rows.Next() {
+		var ps ProxyStats
+		if err := rows.Scan(&ps.Proxy, &ps.Sessions, &ps.Challenged); err != nil {
+			return nil, err
+		}
+		stats = append(stats, ps)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetLastProxy returns the proxy the most recent session used, "" when
+// there was none
+func (db *DB) GetLastProxy() (string, error) {
+	var proxy string
+	err := db.QueryRow(`SELECT proxy FROM proxy_sessions ORDER BY started_at DESC LIMIT 1`).Scan(&proxy)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return proxy, err
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	return db.DB.Close()
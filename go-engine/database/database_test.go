@@ -0,0 +1,299 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDB opens a fresh SQLite database in a temporary directory
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// count returns the number of rows in table
+func count(t *testing.T, db *DB, table string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSaveCookiesKeepsSessionOnFailure(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	saved := []SessionCookie{{ID: "c1", Name: "li_at", Value: "old", Domain: ".linkedin.com", Path: "/", CreatedAt: now}}
+	if err := db.SaveCookies(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// The duplicate ID fails the second insert
+	fresh := []SessionCookie{
+		{ID: "c2", Name: "li_at", Value: "new", Domain: ".linkedin.com", Path: "/", CreatedAt: now},
+		{ID: "c2", Name: "JSESSIONID", Value: "new", Domain: ".linkedin.com", Path: "/", CreatedAt: now},
+	}
+	if err := db.SaveCookies(fresh); err == nil {
+		t.Fatal("SaveCookies with a duplicate ID succeeded")
+	}
+
+	cookies, err := db.GetCookies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || cookies[0].Value != "old" {
+		t.Fatalf("cookies after a failed save = %+v, want the old li_at only", cookies)
+	}
+}
+
+// failInserts makes every insert into table fail from inside SQLite
+func failInserts(t *testing.T, db *DB, table string) {
+	t.Helper()
+	_, err := db.Exec(`CREATE TRIGGER fail_` + table + ` BEFORE INSERT ON ` + table + ` BEGIN SELECT RAISE(ABORT, 'forced failure'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordConnectionSentRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	failInserts(t, db, "processed_profiles")
+
+	conn := &Connection{ID: "conn_1", ProfileURL: "https://www.linkedin.com/in/jane/", Status: "pending", CreatedAt: time.Now()}
+	if err := db.RecordConnectionSent(conn); err == nil {
+		t.Fatal("RecordConnectionSent succeeded with processed_profiles failing")
+	}
+	if n := count(t, db, "connections"); n != 0 {
+		t.Errorf("%d connection rows left after the rollback, want 0", n)
+	}
+}
+
+func TestRecordMessageSentRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	failInserts(t, db, "daily_activity")
+
+	msg := &Message{ID: "msg_1", ConnectionID: "conn_1", Content: "Hi", Status: "sent", SentAt: time.Now()}
+	if err := db.RecordMessageSent(msg); err == nil {
+		t.Fatal("RecordMessageSent succeeded with daily_activity failing")
+	}
+	if n := count(t, db, "messages"); n != 0 {
+		t.Errorf("%d message rows left after the rollback, want 0", n)
+	}
+}
+
+func TestActivityTimeSeriesBucketsAcceptancesByLocalDay(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-8", -8*60*60)
+	defer func() { time.Local = local }()
+
+	db := newTestDB(t)
+	today := time.Now().In(time.Local)
+	lateEvening := time.Date(today.Year(), today.Month(), today.Day(), 20, 0, 0, 0, time.Local)
+	yesterdayMorning := lateEvening.AddDate(0, 0, -1).Add(-12 * time.Hour)
+	for i, at := range []time.Time{lateEvening, yesterdayMorning} {
+		// Stored the way CURRENT_TIMESTAMP writes it: UTC, no zone.
+		// 20:00 at UTC-8 is already the next day in UTC
+		_, err := db.Exec(`INSERT INTO connections (id, profile_url, status, created_at, accepted_at) VALUES (?, ?, 'accepted', ?, ?)`,
+			i, i, at, at.UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := db.GetActivityTimeSeries(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("got %d days, want 2", len(series))
+	}
+	for i, want := range []int{1, 1} {
+		if series[i].Accepted != want {
+			t.Errorf("%s: %d accepted, want %d", series[i].Date, series[i].Accepted, want)
+		}
+	}
+	if series[1].Date != today.Format("2006-01-02") {
+		t.Errorf("last day is %s, want today %s", series[1].Date, today.Format("2006-01-02"))
+	}
+}
+
+func TestConnectionRollups(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	for i, days := range []int{0, 1, 6, 8, 20, 29, 31, 45} {
+		conn := &Connection{
+			ID:         fmt.Sprintf("c%d", i),
+			ProfileURL: fmt.Sprintf("https://www.linkedin.com/in/sent-%d/", i),
+			Status:     "pending",
+			CreatedAt:  now.AddDate(0, 0, -days),
+		}
+		if err := db.SaveConnection(conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// accepted incoming invitations are not invitations we sent
+	for i, days := range []int{0, 3, 15} {
+		conn := &Connection{
+			ID:         fmt.Sprintf("in%d", i),
+			ProfileURL: fmt.Sprintf("https://www.linkedin.com/in/incoming-%d/", i),
+			CreatedAt:  now.AddDate(0, 0, -days),
+		}
+		if err := db.RecordInvitationAccepted(conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	weekly, err := db.GetWeeklyConnectionCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weekly != 3 {
+		t.Errorf("weekly count = %d, want 3", weekly)
+	}
+	monthly, err := db.GetMonthlyConnectionCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if monthly != 6 {
+		t.Errorf("monthly count = %d, want 6", monthly)
+	}
+}
+
+func TestSaveLocalStorageKeepsEntriesOnFailure(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	saved := []StorageItem{{Origin: "https://www.linkedin.com", Key: "li_theme", Value: "dark", CreatedAt: now}}
+	if err := db.SaveLocalStorage(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	failInserts(t, db, "session_storage")
+	fresh := []StorageItem{{Origin: "https://www.linkedin.com", Key: "li_theme", Value: "light", CreatedAt: now}}
+	if err := db.SaveLocalStorage(fresh); err == nil {
+		t.Fatal("SaveLocalStorage succeeded with inserts failing")
+	}
+
+	items, err := db.GetLocalStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Value != "dark" {
+		t.Fatalf("entries after a failed save = %+v, want the old li_theme only", items)
+	}
+}
+
+// concurrently runs f from n goroutines at once and fails on any error
+func concurrently(t *testing.T, n int, f func() error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- f()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentConnectionCount(t *testing.T) {
+	db := newTestDB(t)
+	const n = 40
+	concurrently(t, n, func() error {
+		if _, err := db.GetOrCreateDailyActivity(); err != nil {
+			return err
+		}
+		return db.IncrementConnectionCount()
+	})
+
+	activity, err := db.GetOrCreateDailyActivity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity.ConnectionsSent != n {
+		t.Errorf("connections sent = %d, want %d", activity.ConnectionsSent, n)
+	}
+	if rows := count(t, db, "daily_activity"); rows != 1 {
+		t.Errorf("%d daily_activity rows, want 1", rows)
+	}
+}
+
+func TestConcurrentReserveConnectionSlot(t *testing.T) {
+	db := newTestDB(t)
+	const limit = 7
+	var mu sync.Mutex
+	granted := 0
+	concurrently(t, 30, func() error {
+		_, ok, err := db.ReserveConnectionSlot(limit)
+		if ok {
+			mu.Lock()
+			granted++
+			mu.Unlock()
+		}
+		return err
+	})
+
+	if granted != limit {
+		t.Errorf("%d slots granted, want %d", granted, limit)
+	}
+	activity, err := db.GetOrCreateDailyActivity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity.ConnectionsSent != limit {
+		t.Errorf("connections sent = %d, want %d", activity.ConnectionsSent, limit)
+	}
+}
+
+func TestReleaseConnectionSlotUsesReservedDate(t *testing.T) {
+	db := newTestDB(t)
+	date, ok, err := db.ReserveConnectionSlot(10)
+	if err != nil || !ok {
+		t.Fatalf("ReserveConnectionSlot = %v, %v", ok, err)
+	}
+	if today := time.Now().Format("2006-01-02"); date != today {
+		t.Fatalf("slot reserved on %s, want %s", date, today)
+	}
+
+	// a slot taken yesterday and released after midnight
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	if _, err := db.Exec(`INSERT INTO daily_activity (id, date, connections_sent) VALUES (?, ?, 3)`, "activity_"+yesterday, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReleaseConnectionSlot(yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, today int
+	if err := db.QueryRow(`SELECT connections_sent FROM daily_activity WHERE date = ?`, yesterday).Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT connections_sent FROM daily_activity WHERE date = ?`, date).Scan(&today); err != nil {
+		t.Fatal(err)
+	}
+	if before != 2 || today != 1 {
+		t.Errorf("after release: yesterday %d, today %d, want 2 and 1", before, today)
+	}
+}
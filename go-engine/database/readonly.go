@@ -0,0 +1,44 @@
+package database
+
+// readOnlyStore passes reads through to the wrapped Store and drops every
+// write, so a live dry run can walk the whole workflow against the real
+// history without leaving a trace in it
+type readOnlyStore struct {
+	Store
+}
+
+// ReadOnly wraps s so that writes succeed without changing anything.
+// Initialize, Close and EnableEncryption still reach s, as does
+// GetOrCreateDailyActivity, whose empty row for today any run would create.
+// Slot reservations are granted since nothing will be sent with them
+func ReadOnly(s Store) Store {
+	return readOnlyStore{Store: s}
+}
+
+func (readOnlyStore) SaveConnection(*Connection) error                     { return nil }
+func (readOnlyStore) UpdateConnectionStatus(string, string) error          { return nil }
+func (readOnlyStore) MarkProfileProcessed(string) error                    { return nil }
+func (readOnlyStore) RecordConnectionSent(*Connection) error               { return nil }
+func (readOnlyStore) RecordInvitationAccepted(*Connection) error           { return nil }
+func (readOnlyStore) ReconcileProfileURL(string, string) error             { return nil }
+func (readOnlyStore) RecordLimitHit(*LimitHit) error                       { return nil }
+func (readOnlyStore) EnqueueProfile(*QueuedProfile) error                  { return nil }
+func (readOnlyStore) SaveProfileDetails(*ProfileDetails) error             { return nil }
+func (readOnlyStore) SaveGeoUrn(string, string) error                      { return nil }
+func (readOnlyStore) SaveMessage(*Message) error                           { return nil }
+func (readOnlyStore) RecordMessageSent(*Message) error                     { return nil }
+func (readOnlyStore) IncrementConnectionCount() error                      { return nil }
+func (readOnlyStore) ReserveConnectionSlot(int) (string, bool, error)      { return "", true, nil }
+func (readOnlyStore) ReleaseConnectionSlot(string) error                   { return nil }
+func (readOnlyStore) IncrementMessageCount() error                         { return nil }
+func (readOnlyStore) IncrementTemplateUsage(string, int) error             { return nil }
+func (readOnlyStore) SaveCookies([]SessionCookie) error                    { return nil }
+func (readOnlyStore) ClearCookies() error                                  { return nil }
+func (readOnlyStore) SaveLocalStorage([]StorageItem) error                 { return nil }
+func (readOnlyStore) ClearLocalStorage() error                             { return nil }
+func (readOnlyStore) StartProxySession(*ProxySession) error                { return nil }
+func (readOnlyStore) MarkProxySessionChallenged(string) error              { return nil }
+func (readOnlyStore) MarkProxySessionAuthenticated(string) error           { return nil }
+func (readOnlyStore) SaveBurstPlan(*BurstPlan) error                       { return nil }
+func (readOnlyStore) SaveRunCheckpoint(string, int, []QueuedProfile) error { return nil }
+func (readOnlyStore) ClearRunCheckpoint(string) error                      { return nil }
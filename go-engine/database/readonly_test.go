@@ -0,0 +1,120 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// snapshot returns the rows of every table, for comparing the database
+// before and after
+func snapshot(t *testing.T, db *DB) map[string][]string {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	snap := make(map[string][]string)
+	for _, table := range tables {
+		rows, err := db.Query(`SELECT * FROM ` + table + ` ORDER BY rowid`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cols, _ := rows.Columns()
+		snap[table] = []string{}
+		for rows.Next() {
+			values := make([]interface{}, len(cols))
+			ptrs := make([]interface{}, len(cols))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				t.Fatal(err)
+			}
+			snap[table] = append(snap[table], fmt.Sprintf("%v", values))
+		}
+		rows.Close()
+	}
+	return snap
+}
+
+func TestReadOnlyLeavesDatabaseUnchanged(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	pending := &Connection{ID: "c1", ProfileURL: "https://www.linkedin.com/in/jane/", Status: "pending", CreatedAt: now}
+	if err := db.RecordConnectionSent(pending); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.StartProxySession(&ProxySession{ID: "s1", Proxy: "direct", StartedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveCookies([]SessionCookie{{ID: "k1", Name: "li_at", Value: "v", Domain: ".linkedin.com", Path: "/", CreatedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	// the one write a dry run makes, as any run would
+	if _, err := db.GetOrCreateDailyActivity(); err != nil {
+		t.Fatal(err)
+	}
+	before := snapshot(t, db)
+
+	ro := ReadOnly(db)
+	other := &Connection{ID: "c2", ProfileURL: "https://www.linkedin.com/in/tom/", Status: "pending", CreatedAt: now}
+	msg := &Message{ID: "m1", ConnectionID: "c1", Content: "Thanks!", Status: "sent", SentAt: now}
+	writes := map[string]error{
+		"SaveConnection":                ro.SaveConnection(other),
+		"UpdateConnectionStatus":        ro.UpdateConnectionStatus(pending.ProfileURL, "accepted"),
+		"MarkProfileProcessed":          ro.MarkProfileProcessed(other.ProfileURL),
+		"RecordConnectionSent":          ro.RecordConnectionSent(other),
+		"RecordInvitationAccepted":      ro.RecordInvitationAccepted(&Connection{ID: "c3", ProfileURL: "https://www.linkedin.com/in/ann/", CreatedAt: now}),
+		"ReconcileProfileURL":           ro.ReconcileProfileURL(pending.ProfileURL, "https://www.linkedin.com/in/jane-doe/"),
+		"RecordLimitHit":                ro.RecordLimitHit(&LimitHit{Kind: "weekly", DetectedAt: now}),
+		"EnqueueProfile":                ro.EnqueueProfile(&QueuedProfile{ProfileURL: other.ProfileURL, QueuedAt: now}),
+		"SaveProfileDetails":            ro.SaveProfileDetails(&ProfileDetails{ProfileURL: other.ProfileURL, UpdatedAt: now}),
+		"SaveGeoUrn":                    ro.SaveGeoUrn("lisbon", "105047339"),
+		"SaveMessage":                   ro.SaveMessage(msg),
+		"RecordMessageSent":             ro.RecordMessageSent(msg),
+		"IncrementConnectionCount":      ro.IncrementConnectionCount(),
+		"IncrementMessageCount":         ro.IncrementMessageCount(),
+		"IncrementTemplateUsage":        ro.IncrementTemplateUsage("connection", 0),
+		"SaveCookies":                   ro.SaveCookies([]SessionCookie{{ID: "k2", Name: "JSESSIONID", Value: "x", CreatedAt: now}}),
+		"ClearCookies":                  ro.ClearCookies(),
+		"SaveLocalStorage":              ro.SaveLocalStorage([]StorageItem{{Origin: "https://www.linkedin.com", Key: "k", Value: "v", CreatedAt: now}}),
+		"ClearLocalStorage":             ro.ClearLocalStorage(),
+		"StartProxySession":             ro.StartProxySession(&ProxySession{ID: "s2", Proxy: "direct", StartedAt: now}),
+		"MarkProxySessionChallenged":    ro.MarkProxySessionChallenged("s1"),
+		"MarkProxySessionAuthenticated": ro.MarkProxySessionAuthenticated("s1"),
+		"SaveBurstPlan":                 ro.SaveBurstPlan(&BurstPlan{Date: now.Format("2006-01-02"), Starts: []time.Time{now}}),
+		"SaveRunCheckpoint":             ro.SaveRunCheckpoint("run1", 1, []QueuedProfile{{ProfileURL: other.ProfileURL}}),
+		"ClearRunCheckpoint":            ro.ClearRunCheckpoint("run1"),
+		"ReleaseConnectionSlot":         ro.ReleaseConnectionSlot(now.Format("2006-01-02")),
+	}
+	_, granted, err := ro.ReserveConnectionSlot(1)
+	writes["ReserveConnectionSlot"] = err
+	for name, err := range writes {
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if !granted {
+		t.Error("ReserveConnectionSlot was refused")
+	}
+
+	if after := snapshot(t, db); !reflect.DeepEqual(after, before) {
+		for table := range before {
+			if !reflect.DeepEqual(after[table], before[table]) {
+				t.Errorf("table %s changed:\nbefore %v\nafter  %v", table, before[table], after[table])
+			}
+		}
+	}
+}
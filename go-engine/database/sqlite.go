@@ -0,0 +1,277 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	Register("sqlite3", func(dsn string) (Store, error) {
+		return New(dsn)
+	})
+}
+
+// busyTimeoutMs is how long a connection waits for another to release the
+// write lock before failing with "database is locked"
+const busyTimeoutMs = 10000
+
+// sqliteDSN adds the connection settings concurrent writers need to dbPath.
+// Transactions take the write lock when they begin, so one that reads then
+// writes cannot deadlock against another and fail instead of waiting.
+// Settings already in dbPath take precedence
+func sqliteDSN(dbPath string) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_busy_timeout=%d&_txlock=immediate", dbPath, sep, busyTimeoutMs)
+}
+
+// New opens a SQLite database at dbPath
+func New(dbPath string) (*DB, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Enable WAL mode for better concurrent access
+	_, err = db.Exec("PRAGMA journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+
+	return &DB{DB: db}, nil
+}
+
+// NewEncrypted opens the SQLite database at dbPath, creates its schema and
+// encrypts sensitive values with a key derived from passphrase, as
+// EnableEncryption does
+func NewEncrypted(dbPath, passphrase string) (*DB, error) {
+	db, err := New(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Initialize(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.EnableEncryption(passphrase); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Initialize creates all required tables
+func (db *DB) Initialize() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS connections (
+		id TEXT PRIMARY KEY,
+		profile_url TEXT NOT NULL UNIQUE,
+		first_name TEXT,
+		last_name TEXT,
+		job_title TEXT,
+		company TEXT,
+		location TEXT,
+		note_sent TEXT,
+		status TEXT CHECK(status IN ('pending', 'accepted', 'declined', 'failed')) DEFAULT 'pending',
+		search_criteria_id TEXT,
+		page_number INTEGER DEFAULT 0,
+		position INTEGER DEFAULT 0,
+		variant TEXT DEFAULT '',
+		snapshot_source TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		accepted_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_connections_status ON connections(status);
+	CREATE INDEX IF NOT EXISTS idx_connections_created ON connections(created_at);
+
+	CREATE TABLE IF NOT EXISTS messages (
+		id TEXT PRIMARY KEY,
+		connection_id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		template_id TEXT,
+		status TEXT CHECK(status IN ('sent', 'delivered', 'read', 'failed')) DEFAULT 'sent',
+		sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (connection_id) REFERENCES connections(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_messages_connection ON messages(connection_id);
+	CREATE INDEX IF NOT EXISTS idx_messages_sent ON messages(sent_at);
+
+	CREATE TABLE IF NOT EXISTS daily_activity (
+		id TEXT PRIMARY KEY,
+		date TEXT NOT NULL UNIQUE,
+		connections_sent INTEGER DEFAULT 0,
+		messages_sent INTEGER DEFAULT 0,
+		last_connection_at DATETIME,
+		last_message_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS session_cookies (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		value TEXT NOT NULL,
+		domain TEXT,
+		path TEXT,
+		proxy_session_id TEXT,
+		expires_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS session_storage (
+		origin TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		encoding TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (origin, key)
+	);
+
+	CREATE TABLE IF NOT EXISTS processed_profiles (
+		profile_url TEXT PRIMARY KEY,
+		processed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS search_queue (
+		profile_url TEXT PRIMARY KEY,
+		first_name TEXT,
+		last_name TEXT,
+		job_title TEXT,
+		company TEXT,
+		location TEXT,
+		page_number INTEGER DEFAULT 0,
+		position INTEGER DEFAULT 0,
+		criteria_id TEXT DEFAULT '',
+		queued_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS profile_details (
+		profile_url TEXT PRIMARY KEY,
+		headline TEXT,
+		mutual_count INTEGER DEFAULT 0,
+		mutual_names TEXT,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS template_usage (
+		date TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		template_idx INTEGER NOT NULL,
+		uses INTEGER DEFAULT 0,
+		last_used_at DATETIME,
+		PRIMARY KEY (date, kind, template_idx)
+	);
+
+	CREATE TABLE IF NOT EXISTS proxy_sessions (
+		id TEXT PRIMARY KEY,
+		proxy TEXT NOT NULL,
+		egress_ip TEXT,
+		challenged INTEGER DEFAULT 0,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_proxy_sessions_proxy ON proxy_sessions(proxy);
+
+	CREATE TABLE IF NOT EXISTS limit_hits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		sent_in_window INTEGER DEFAULT 0,
+		detected_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS burst_plans (
+		date TEXT PRIMARY KEY,
+		starts TEXT NOT NULL,
+		completed INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS geo_cache (
+		location TEXT PRIMARY KEY,
+		urn TEXT NOT NULL,
+		resolved_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS run_checkpoints (
+		run_id TEXT PRIMARY KEY,
+		position INTEGER DEFAULT 0,
+		queue TEXT NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS encryption_meta (
+		id INTEGER PRIMARY KEY CHECK(id = 1),
+		salt BLOB NOT NULL,
+		verifier TEXT NOT NULL
+	);
+	`
+
+	_, err := db.Exec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return db.migrate()
+}
+
+// migrate adds columns introduced after a table was first created so that
+// existing database files keep working
+func (db *DB) migrate() error {
+	migrations := []struct {
+		table, column, definition string
+	}{
+		{"connections", "page_number", "INTEGER DEFAULT 0"},
+		{"connections", "position", "INTEGER DEFAULT 0"},
+		{"session_cookies", "proxy_session_id", "TEXT"},
+		{"connections", "variant", "TEXT DEFAULT ''"},
+		{"connections", "snapshot_source", "TEXT DEFAULT ''"},
+		{"search_queue", "criteria_id", "TEXT DEFAULT ''"},
+		{"proxy_sessions", "authenticated", "INTEGER DEFAULT 0"},
+		{"connections", "incoming", "INTEGER DEFAULT 0"},
+		{"daily_activity", "invitations_accepted", "INTEGER DEFAULT 0"},
+		{"daily_activity", "last_invitation_accepted_at", "DATETIME"},
+	}
+
+	for _, m := range migrations {
+		if err := db.addColumnIfMissing(m.table, m.column, m.definition); err != nil {
+			return fmt.Errorf("failed to migrate %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func (db *DB) addColumnIfMissing(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store is the data access interface used by the automation modules.
+// DB (SQLite) is the default implementation; other backends register an
+// Opener under their driver name
+type Store interface {
+	Initialize() error
+	Close() error
+
+	// EnableEncryption turns on encryption at rest for sensitive values
+	// such as session cookies
+	EnableEncryption(passphrase string) error
+
+	// Connections
+	SaveConnection(conn *Connection) error
+	UpdateConnectionStatus(profileURL, status string) error
+	GetPendingConnections() ([]Connection, error)
+	GetAcceptedConnections() ([]Connection, error)
+	ExportConnections() ([]Connection, error)
+	GetAcceptanceByPage() ([]PageStats, error)
+	GetVariantStats() ([]VariantStats, error)
+	IsProfileProcessed(profileURL string) (bool, error)
+	MarkProfileProcessed(profileURL string) error
+	RecordConnectionSent(conn *Connection) error
+	RecordInvitationAccepted(conn *Connection) error
+	ReconcileProfileURL(oldURL, newURL string) error
+	GetConnectionsSentSince(since time.Time) ([]time.Time, error)
+	GetWeeklyConnectionCount() (int, error)
+	GetMonthlyConnectionCount() (int, error)
+
+	// Limits
+	RecordLimitHit(hit *LimitHit) error
+	GetLastLimitHit(kind string) (*LimitHit, error)
+
+	// Search queue
+	EnqueueProfile(p *QueuedProfile) error
+	GetQueuedProfiles() ([]QueuedProfile, error)
+
+	// Profile details
+	SaveProfileDetails(d *ProfileDetails) error
+	GetProfileDetails(profileURL string) (*ProfileDetails, error)
+
+	// Geo URN cache
+	GetGeoUrn(location string) (string, error)
+	SaveGeoUrn(location, urn string) error
+
+	// Messages
+	SaveMessage(msg *Message) error
+	RecordMessageSent(msg *Message) error
+	GetMessagesForConnection(connectionID string) ([]Message, error)
+	ExportMessages() ([]ExportedMessage, error)
+	HasSentFollowUp(connectionID string) (bool, error)
+
+	// Daily activity
+	GetOrCreateDailyActivity() (*DailyActivity, error)
+	IncrementConnectionCount() error
+	ReserveConnectionSlot(limit int) (date string, ok bool, err error)
+	ReleaseConnectionSlot(date string) error
+	IncrementMessageCount() error
+	GetActivityTimeSeries(days int) ([]ActivityDay, error)
+
+	// Template usage
+	GetTemplateUsage(kind string) (map[int]int, error)
+	GetTemplateStats(kind string) (map[int]TemplateStat, error)
+	IncrementTemplateUsage(kind string, idx int) error
+
+	// Session cookies
+	SaveCookies(cookies []SessionCookie) error
+	GetCookies() ([]SessionCookie, error)
+	ClearCookies() error
+	SaveLocalStorage(items []StorageItem) error
+	GetLocalStorage() ([]StorageItem, error)
+	ClearLocalStorage() error
+
+	// Proxy sessions
+	StartProxySession(s *ProxySession) error
+	MarkProxySessionChallenged(id string) error
+	MarkProxySessionAuthenticated(id string) error
+	CountAuthenticatedSessions() (int, error)
+	GetRunsSinceChallenge() (int, *time.Time, error)
+	GetProxyStats() ([]ProxyStats, error)
+	GetLastProxy() (string, error)
+
+	// Burst plans
+	GetBurstPlan(date string) (*BurstPlan, error)
+	SaveBurstPlan(p *BurstPlan) error
+
+	// Run checkpoints
+	SaveRunCheckpoint(runID string, index int, profiles []QueuedProfile) error
+	LoadRunCheckpoint() (*RunCheckpoint, error)
+	ClearRunCheckpoint(runID string) error
+}
+
+// Opener opens a Store from a driver-specific DSN
+type Opener func(dsn string) (Store, error)
+
+var (
+	openersMu sync.RWMutex
+	openers   = make(map[string]Opener)
+)
+
+// Register makes a storage backend available under the given driver name.
+// Backends typically call it from an init function
+func Register(driver string, opener Opener) {
+	openersMu.Lock()
+	defer openersMu.Unlock()
+	openers[driver] = opener
+}
+
+// Open opens a Store using the registered backend for driver
+func Open(driver, dsn string) (Store, error) {
+	openersMu.RLock()
+	opener, ok := openers[driver]
+	openersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver %q (available: %v)", driver, Drivers())
+	}
+	return opener(dsn)
+}
+
+// Drivers returns the names of the registered backends
+func Drivers() []string {
+	openersMu.RLock()
+	defer openersMu.RUnlock()
+	var names []string
+	for name := range openers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/messaging"
+)
+
+// RehearseLive is the --dry-run-live mode: it logs in, runs the search and
+// takes each profile found, then each connection due a follow-up, up to the
+// Send click, logging what would have gone out. The run's store is
+// read-only, so the database is left as it was
+func (a *Automation) RehearseLive() (result *RunResult, err error) {
+	a.isRunning.Store(true)
+	result = newRunResult()
+	defer func() {
+		a.isRunning.Store(false)
+		result.finish(err)
+	}()
+
+	a.logger.Info("Starting live dry run")
+
+	fmt.Println("\n[Step 1] Authenticating...")
+	if ok, err := a.authenticate(result); !ok {
+		return result, err
+	}
+
+	fmt.Println("\n[Step 2] Searching for profiles...")
+	searchResult, err := a.searchModule.Search(a.page)
+	if err != nil {
+		return result, fmt.Errorf("search: %w", err)
+	}
+	result.ProfilesFound = len(searchResult.Profiles)
+	fmt.Printf("✓ Found %d profiles\n", len(searchResult.Profiles))
+
+	fmt.Println("\n[Step 3] Rehearsing connection requests...")
+	limit := a.config.Connection.EffectiveDailyLimit(time.Now())
+	rehearsed := 0
+	for _, profile := range searchResult.Profiles {
+		select {
+		case <-a.stopChan:
+			result.StopReason = StopInterrupted
+			return result, nil
+		default:
+		}
+		if rehearsed == limit {
+			fmt.Printf("Stopping at the daily limit of %d\n", limit)
+			break
+		}
+
+		req := &messaging.ConnectionRequest{
+			ProfileURL: profile.ProfileURL,
+			FirstName:  profile.FirstName,
+			LastName:   profile.LastName,
+			JobTitle:   profile.JobTitle,
+			Company:    profile.Company,
+			PageNumber: profile.PageNumber,
+			Position:   profile.Position,
+			Location:   profile.Location,
+		}
+		var conn *messaging.ConnectionResult
+		a.inFlight(func(page *rod.Page) {
+			conn, err = a.connectionManager.SendConnectionRequest(page, req, true)
+		})
+		switch {
+		case err != nil:
+			a.logger.LogError("rehearse connection", err, map[string]interface{}{"profile": profile.ProfileURL})
+			fmt.Printf("  ✗ %s %s: %v\n", profile.FirstName, profile.LastName, err)
+		case !conn.Success:
+			fmt.Printf("  - %s %s: skipped (%s)\n", profile.FirstName, profile.LastName, conn.Reason)
+		default:
+			rehearsed++
+			fmt.Printf("  ✓ Would invite %s %s\n", req.FirstName, req.LastName)
+			if req.Note != "" {
+				fmt.Printf("    Note: %q\n", req.Note)
+			}
+		}
+		a.waitBetweenActions()
+	}
+
+	fmt.Println("\n[Step 4] Rehearsing follow-up messages...")
+	followUps, err := a.messageManager.GetConnectionsNeedingFollowUp()
+	if err != nil {
+		return result, fmt.Errorf("load follow-ups: %w", err)
+	}
+	limit = a.config.Messaging.EffectiveDailyLimit(time.Now())
+	messaged := 0
+	for _, conn := range followUps {
+		select {
+		case <-a.stopChan:
+			result.StopReason = StopInterrupted
+			return result, nil
+		default:
+		}
+		if messaged == limit {
+			fmt.Printf("Stopping at the daily limit of %d\n", limit)
+			break
+		}
+
+		req := &messaging.MessageRequest{
+			ConnectionID: conn.ID,
+			ProfileURL:   conn.ProfileURL,
+			FirstName:    conn.FirstName,
+			LastName:     conn.LastName,
+			JobTitle:     conn.JobTitle,
+			Company:      conn.Company,
+			AcceptDelay:  conn.AcceptDelay,
+		}
+		var msg *messaging.MessageResult
+		a.inFlight(func(page *rod.Page) {
+			msg, err = a.messageManager.SendMessage(page, req, true)
+		})
+		switch {
+		case err != nil:
+			a.logger.LogError("rehearse message", err, map[string]interface{}{"connection": conn.ID})
+			fmt.Printf("  ✗ %s %s: %v\n", conn.FirstName, conn.LastName, err)
+		case !msg.Success:
+			fmt.Printf("  - %s %s: %s\n", conn.FirstName, conn.LastName, msg.ErrorMessage)
+		default:
+			messaged++
+			fmt.Printf("  ✓ Would message %s %s: %q\n", conn.FirstName, conn.LastName, req.Message)
+		}
+		a.waitBetweenActions()
+	}
+
+	fmt.Printf("\n✓ Dry run done: %d connection requests and %d messages would have been sent\n", rehearsed, messaged)
+	return result, nil
+}
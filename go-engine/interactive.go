@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"linkedin-automation/messaging"
+)
+
+// interactivePrompt shows each prepared connection request on the terminal
+// and waits for the operator to send, skip or quit
+type interactivePrompt struct {
+	lines <-chan string
+	stop  <-chan struct{}
+}
+
+// newInteractivePrompt reads answers from in until it closes. Reading
+// happens in the background so a shutdown signal is not stuck behind a
+// pending prompt
+func newInteractivePrompt(in io.Reader, stop <-chan struct{}) *interactivePrompt {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return &interactivePrompt{lines: lines, stop: stop}
+}
+
+// stdinIsTerminal reports whether stdin is attached to a terminal rather
+// than a pipe, file or /dev/null
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm implements messaging.ConfirmFunc
+func (p *interactivePrompt) confirm(req *messaging.ConnectionRequest) messaging.Decision {
+	fmt.Println("\n--- Connection request preview ---")
+	fmt.Printf("Name:    %s %s\n", req.FirstName, req.LastName)
+	fmt.Printf("Title:   %s\n", req.JobTitle)
+	fmt.Printf("Company: %s\n", req.Company)
+	fmt.Printf("Profile: %s\n", req.ProfileURL)
+	if req.Note != "" && req.Language != "" {
+		fmt.Printf("Note (%s):\n%s\n", req.Language, req.Note)
+	} else if req.Note != "" {
+		fmt.Printf("Note:\n%s\n", req.Note)
+	} else {
+		fmt.Println("Note:    (none)")
+	}
+
+	for {
+		fmt.Print("Send? [y]es / [n]o / [q]uit: ")
+		select {
+		case <-p.stop:
+			fmt.Println()
+			return messaging.DecisionQuit
+		case line, ok := <-p.lines:
+			if !ok {
+				fmt.Println()
+				return messaging.DecisionQuit
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "y", "yes":
+				return messaging.DecisionSend
+			case "n", "no":
+				return messaging.DecisionSkip
+			case "q", "quit":
+				return messaging.DecisionQuit
+			}
+		}
+	}
+}
@@ -12,8 +12,10 @@ import (
 // Logger provides structured logging
 type Logger struct {
 	*slog.Logger
-	level  string
-	format string
+	level     string
+	format    string
+	component string
+	tracer    *TraceRecorder
 }
 
 // LogEntry represents a structured log entry
@@ -77,9 +79,11 @@ func New(level, format, filePath string) (*Logger, error) {
 // WithComponent returns a logger with a component context
 func (l *Logger) WithComponent(component string) *Logger {
 	return &Logger{
-		Logger: l.Logger.With("component", component),
-		level:  l.level,
-		format: l.format,
+		Logger:    l.Logger.With("component", component),
+		level:     l.level,
+		format:    l.format,
+		component: component,
+		tracer:    l.tracer,
 	}
 }
 
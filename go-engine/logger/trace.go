@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Trace event kinds
+const (
+	TraceNavigate = "navigate"
+	TraceClick    = "click"
+	TraceSelector = "selector"
+)
+
+// TraceEvent is a single recorded browser interaction
+type TraceEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Component string    `json:"component,omitempty"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target"`
+	Outcome   string    `json:"outcome"` // ok, not_found, error
+	Error     string    `json:"error,omitempty"`
+}
+
+// TraceRecorder appends trace events to a JSONL file
+type TraceRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewTraceRecorder opens (or creates) the trace file for appending
+func NewTraceRecorder(path string) (*TraceRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &TraceRecorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Record writes one event
+func (tr *TraceRecorder) Record(event TraceEvent) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.enc.Encode(event)
+}
+
+// Close closes the trace file
+func (tr *TraceRecorder) Close() error {
+	return tr.file.Close()
+}
+
+// EnableTrace starts recording trace events to path. Loggers derived with
+// WithComponent afterwards share the same recorder
+func (l *Logger) EnableTrace(path string) error {
+	tr, err := NewTraceRecorder(path)
+	if err != nil {
+		return err
+	}
+	l.tracer = tr
+	return nil
+}
+
+// CloseTrace stops recording and closes the trace file
+func (l *Logger) CloseTrace() error {
+	if l.tracer == nil {
+		return nil
+	}
+	return l.tracer.Close()
+}
+
+// Trace records a browser interaction when tracing is enabled. It is a
+// no-op otherwise
+func (l *Logger) Trace(kind, target string, err error) {
+	if l.tracer == nil {
+		return
+	}
+
+	event := TraceEvent{
+		Timestamp: time.Now(),
+		Component: l.component,
+		Kind:      kind,
+		Target:    target,
+		Outcome:   "ok",
+	}
+	if err != nil {
+		event.Outcome = "error"
+		event.Error = err.Error()
+	}
+	if recErr := l.tracer.Record(event); recErr != nil {
+		l.Warn("failed to record trace event", "error", recErr)
+	}
+}
+
+// LoadTrace reads all events from a JSONL trace file
+func LoadTrace(path string) ([]TraceEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	defer file.Close()
+
+	var events []TraceEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid trace line %d: %w", len(events)+1, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// TracePlayer performs the high-level actions of a recorded trace, e.g.
+// against a browser pointed at local HTML fixtures
+type TracePlayer interface {
+	Navigate(target string) error
+	Click(target string) error
+	Lookup(selector string) error
+}
+
+// ReplayTrace re-issues the recorded navigations, clicks and selector
+// lookups in order. It stops at the first step whose outcome differs from
+// the recording and reports that step
+func ReplayTrace(events []TraceEvent, player TracePlayer) error {
+	for i, event := range events {
+		var err error
+		switch event.Kind {
+		case TraceNavigate:
+			err = player.Navigate(event.Target)
+		case TraceClick:
+			err = player.Click(event.Target)
+		case TraceSelector:
+			err = player.Lookup(event.Target)
+		default:
+			continue
+		}
+
+		recordedOK := event.Outcome == "ok"
+		if (err == nil) != recordedOK {
+			return fmt.Errorf("step %d (%s %s) diverged: recorded %s, replay error: %v",
+				i+1, event.Kind, event.Target, event.Outcome, err)
+		}
+	}
+	return nil
+}
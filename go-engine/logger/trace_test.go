@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePlayer fails the targets in missing and logs every step it takes
+type fakePlayer struct {
+	missing map[string]bool
+	steps   []string
+}
+
+func (p *fakePlayer) do(kind, target string) error {
+	p.steps = append(p.steps, kind+" "+target)
+	if p.missing[target] {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func (p *fakePlayer) Navigate(target string) error { return p.do(TraceNavigate, target) }
+func (p *fakePlayer) Click(target string) error    { return p.do(TraceClick, target) }
+func (p *fakePlayer) Lookup(target string) error   { return p.do(TraceSelector, target) }
+
+// recordTrace writes a trace through a Logger and loads it back
+func recordTrace(t *testing.T, record func(l *Logger)) []TraceEvent {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	l, err := New("error", "text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.EnableTrace(path); err != nil {
+		t.Fatal(err)
+	}
+	record(l)
+	if err := l.CloseTrace(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := LoadTrace(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestReplayTrace(t *testing.T) {
+	events := recordTrace(t, func(l *Logger) {
+		l.Trace(TraceNavigate, "https://www.linkedin.com/in/jane/", nil)
+		l.Trace(TraceSelector, "connect_button", nil)
+		l.Trace(TraceClick, "connect_button", nil)
+		l.Trace(TraceSelector, "add_note_button", errors.New("timeout"))
+	})
+	if len(events) != 4 {
+		t.Fatalf("loaded %d events, want 4", len(events))
+	}
+
+	tests := []struct {
+		name     string
+		missing  map[string]bool
+		diverges string // step the replay should stop at, empty when it matches
+	}{
+		{"same outcomes", map[string]bool{"add_note_button": true}, ""},
+		{"element gone", map[string]bool{"connect_button": true, "add_note_button": true}, "step 2"},
+		{"element appeared", nil, "step 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReplayTrace(events, &fakePlayer{missing: tt.missing})
+			switch {
+			case tt.diverges == "" && err != nil:
+				t.Fatalf("replay diverged: %v", err)
+			case tt.diverges != "" && (err == nil || !strings.Contains(err.Error(), tt.diverges)):
+				t.Fatalf("replay error %v, want divergence at %s", err, tt.diverges)
+			}
+		})
+	}
+}
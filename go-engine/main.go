@@ -1,45 +1,97 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
 
 	"linkedin-automation/auth"
 	"linkedin-automation/config"
 	"linkedin-automation/database"
 	"linkedin-automation/logger"
 	"linkedin-automation/messaging"
+	"linkedin-automation/proxy"
 	"linkedin-automation/search"
+	"linkedin-automation/selectors"
 	"linkedin-automation/stealth"
+	"linkedin-automation/utils"
 )
 
 // Automation represents the main automation controller
 type Automation struct {
 	config            *config.Config
-	db                *database.DB
+	db                database.Store
 	logger            *logger.Logger
 	browser           *rod.Browser
 	page              *rod.Page
 	authenticator     *auth.Authenticator
+	session           *auth.SessionManager
 	searchModule      *search.Searcher
 	connectionManager *messaging.ConnectionManager
 	messageManager    *messaging.MessageManager
+	selectors         *selectors.Registry
 	stopChan          chan struct{}
-	isRunning         bool
+	stopMu            sync.Mutex // guards closing and replacing stopChan
+	isRunning         atomic.Bool // read by Stop from the signal handler
+	proxySession      *database.ProxySession
+	rhythm            *stealth.SessionRhythm
+	phases            phaseList // phases selected with --only, empty for all
+	retryBudget       *utils.RetryBudget
+	clock             *clockWatch // detects sleep/resume during a run
+	observeRemaining  int         // observe-only runs left, this one included
+	continuous        bool        // idle between activity bursts instead of exiting
+	plan              *database.BurstPlan
+	burst             burstQuota       // this burst's share of the daily limits
+	throttle          *throttleMonitor // nil unless rate_limits.watch_throttling
+	runID             string
+	work              *runWork                // state shared by this run's workflow units
+	resume            *database.RunCheckpoint // interrupted run continued with --resume
+	attached          bool                    // the browser is the user's own Chrome, left open on exit
+	display           string                  // display the browser was launched with
+	proxies           *proxy.Pool             // nil unless rotating through proxy.urls
 }
 
 func main() {
 	// Parse command line flags
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
-	headless := flag.Bool("headless", true, "Run browser in headless mode")
+	headless := flag.Bool("headless", true, "Run browser in headless mode, overrides stealth.headless")
 	dryRun := flag.Bool("dry-run", false, "Run without actually sending requests")
+	dryRunLive := flag.Bool("dry-run-live", false, "Log in, search and fill in each request and message, but never send or record anything")
+	proxyURL := flag.String("proxy", "", "Proxy URL for this run, overrides proxy.url")
+	detectAccepted := flag.Bool("detect-accepted", false, "Only refresh accepted connections, then exit")
+	abReport := flag.Bool("ab-report", false, "Print acceptance per connection note A/B test variant, then exit")
+	activityReport := flag.Bool("activity-report", false, "Print daily outreach activity, then exit")
+	reportDays := flag.Int("days", 30, "Days covered by --activity-report")
+	reportCSV := flag.String("csv", "", "Write --activity-report as CSV to this file instead of printing it")
+	exportMessages := flag.String("export-messages", "", "Write every sent message to this file, then exit")
+	exportConnections := flag.String("export-connections", "", "Write the audit record of every connection request to this file, then exit")
+	exportFormat := flag.String("export-format", "", "csv or json for --export-messages and --export-connections, defaults to the file extension")
+	seed := flag.Int64("seed", 0, "Master random seed for a reproducible run, overrides debug.seed")
+	interactive := flag.Bool("interactive", false, "Preview each connection request and confirm it on the terminal")
+	continuous := flag.Bool("continuous", false, "Keep running through the day's activity bursts, idling between them")
+	resume := flag.Bool("resume", false, "Continue the connection queue of an interrupted run instead of searching again")
+	attach := flag.String("attach", "", "DevTools URL of a running Chrome to drive instead of launching one, overrides browser.attach_url")
+	userDataDir := flag.String("user-data-dir", "", "Browser profile directory kept across runs, overrides browser.user_data_dir")
+	replayTrace := flag.String("replay-trace", "", "Replay the navigations, clicks and lookups of a recorded trace, then exit")
+	replayFixtures := flag.String("replay-fixtures", "", "Directory of saved pages --replay-trace loads instead of the live site")
+	serve := flag.String("serve", "", "Serve the control API on this address (e.g. :8080) instead of running once")
+	var only phaseList
+	flag.Var(&only, "only", "Run only this phase: search, connect, message or detect (repeatable)")
 	flag.Parse()
 
 	fmt.Println("==================================================")
@@ -55,6 +107,36 @@ func main() {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if *interactive && !stdinIsTerminal() {
+		fmt.Println("--interactive needs a terminal on stdin")
+		os.Exit(1)
+	}
+	if *proxyURL != "" {
+		cfg.Proxy.URL = *proxyURL
+		if err := cfg.Proxy.Validate(); err != nil {
+			fmt.Printf("Invalid --proxy: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *attach != "" {
+		cfg.Browser.AttachURL = *attach
+		if err := cfg.Browser.Validate(); err != nil {
+			fmt.Printf("Invalid --attach: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *userDataDir != "" {
+		cfg.Browser.UserDataDir = *userDataDir
+	}
+	if *serve != "" && cfg.API.Token == "" {
+		fmt.Println("--serve needs api.token or LINKEDIN_API_TOKEN")
+		os.Exit(1)
+	}
+	// Each API run replaces stopChan, the prompt would keep watching the first
+	if *serve != "" && *interactive {
+		fmt.Println("--interactive cannot be used with --serve")
+		os.Exit(1)
+	}
 
 	// Initialize logger
 	log, err := logger.New(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.File)
@@ -65,9 +147,28 @@ func main() {
 
 	log.Info("LinkedIn Automation starting", "version", "1.0.0")
 
+	// Seed every RNG before any component is built so the run can be replayed
+	if *seed != 0 {
+		cfg.Debug.Seed = *seed
+	}
+	if cfg.Debug.Seed != 0 {
+		utils.SetMasterSeed(cfg.Debug.Seed)
+	}
+	log.Info("random seed", "seed", utils.MasterSeed())
+	fmt.Printf("Random seed: %d (replay with --seed %d)\n", utils.MasterSeed(), utils.MasterSeed())
+
+	if cfg.Debug.RecordTrace {
+		if err := log.EnableTrace(cfg.Debug.TracePath); err != nil {
+			log.Error("Failed to enable trace recording", "error", err)
+			os.Exit(1)
+		}
+		defer log.CloseTrace()
+		log.Info("Recording action trace", "path", cfg.Debug.TracePath)
+	}
+
 	// Initialize database
-	fmt.Printf("Initializing database at %s...\n", cfg.Database.Path)
-	db, err := database.New(cfg.Database.Path)
+	fmt.Printf("Initializing %s database at %s...\n", cfg.Database.Driver, cfg.Database.ConnectionString())
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.ConnectionString())
 	if err != nil {
 		log.Error("Failed to initialize database", "error", err)
 		os.Exit(1)
@@ -79,19 +180,95 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.Database.EncryptionPassphrase != "" {
+		if err := db.EnableEncryption(cfg.Database.EncryptionPassphrase); err != nil {
+			log.Error("Failed to enable database encryption", "error", err)
+			os.Exit(1)
+		}
+		log.Info("Database encryption at rest enabled")
+	} else {
+		log.Warn("Session cookies are stored unencrypted; set LINKEDIN_DB_KEY to encrypt them")
+	}
+
+	if *exportConnections != "" {
+		if err := exportConnectionHistory(db, *exportConnections, *exportFormat); err != nil {
+			log.Error("Failed to export connections", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *exportMessages != "" {
+		if err := exportMessageHistory(db, *exportMessages, *exportFormat); err != nil {
+			log.Error("Failed to export messages", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *activityReport {
+		if err := printActivityReport(db, *reportDays, *reportCSV); err != nil {
+			log.Error("Failed to build activity report", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *abReport {
+		if err := printABReport(db); err != nil {
+			log.Error("Failed to build A/B report", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A live dry run reads the history but never writes to it
+	if *dryRunLive {
+		db = database.ReadOnly(db)
+	}
+
 	// Create automation instance
 	auto := &Automation{
 		config:   cfg,
 		db:       db,
 		logger:   log,
 		stopChan: make(chan struct{}),
+		rhythm:   stealth.NewSessionRhythm(cfg.RateLimits.DelayRampAmplitude),
+		phases:   only,
 	}
 
 	// Initialize modules
-	auto.authenticator = auth.NewAuthenticator(cfg.Credentials, db, log, cfg.Stealth)
-	auto.searchModule = search.NewSearcher(cfg.Search, db, log, cfg.Stealth)
-	auto.connectionManager = messaging.NewConnectionManager(cfg.Connection, db, log, cfg.Stealth)
-	auto.messageManager = messaging.NewMessageManager(cfg.Messaging, db, log, cfg.Stealth)
+	pacer := stealth.NewNavigationPacer(cfg.RateLimits.MinNavigationIntervalMs)
+	cursor := stealth.NewCursor()
+	auto.selectors = selectors.NewRegistry()
+	auto.authenticator = auth.NewAuthenticator(cfg.Credentials, db, log, cfg.Stealth, cursor, cfg.LinkedIn)
+	auto.session = auth.NewSessionManager(db)
+	auto.searchModule = search.NewSearcher(cfg.Search, db, log, cfg.Stealth, pacer, cfg.LinkedIn)
+	auto.connectionManager = messaging.NewConnectionManager(cfg.Connection, db, log, cfg.Stealth, pacer, cursor, auto.selectors, cfg.LinkedIn)
+	auto.connectionManager.SetSuggestionFilter(
+		"pymk_"+strings.TrimPrefix(cfg.Search.CriteriaID(), "search_"),
+		append(append([]string(nil), cfg.Search.JobTitles...), cfg.Search.Keywords...))
+	auto.messageManager = messaging.NewMessageManager(cfg.Messaging, db, log, cfg.Stealth, pacer, cursor, auto.selectors, cfg.LinkedIn)
+	auto.retryBudget = utils.NewRetryBudget(cfg.RateLimits.RetryBudget, func() {
+		log.Warn("retry budget depleted, stopping the run", "budget", cfg.RateLimits.RetryBudget)
+		fmt.Printf("\n⚠ Retry budget of %d used up, stopping after the current action\n", cfg.RateLimits.RetryBudget)
+	})
+	auto.connectionManager.SetRetryBudget(auto.retryBudget)
+	auto.messageManager.SetRetryBudget(auto.retryBudget)
+	variation := messaging.NewVariation(cfg.Messaging.Greetings, cfg.Messaging.Closings)
+	auto.connectionManager.SetVariation(variation)
+	auto.messageManager.SetVariation(variation)
+	if cfg.RateLimits.WatchThrottling {
+		auto.throttle = newThrottleMonitor(time.Duration(cfg.RateLimits.ThrottleCooldownSecs)*time.Second, db, log)
+	}
+	var errorPagePersisted func(url string)
+	if auto.throttle != nil {
+		errorPagePersisted = func(url string) { auto.throttle.flag("error_page", url) }
+	}
+	utils.SetErrorPageRecovery(cfg.LinkedIn.ErrorPageRetries, cfg.LinkedIn.ErrorPageBackoff, errorPagePersisted)
+	if *interactive {
+		auto.connectionManager.SetConfirm(newInteractivePrompt(os.Stdin, auto.stopChan).confirm)
+	}
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -112,42 +289,206 @@ func main() {
 
 	// Launch browser
 	fmt.Println("\nLaunching browser...")
-	err = auto.launchBrowser(*headless)
+	if err := auto.pickProxy(); err != nil {
+		log.Error("Failed to pick a proxy", "error", err)
+		os.Exit(1)
+	}
+	display := auto.resolveDisplay(headless, flagPassed("headless"))
+	err = auto.launchBrowser(display)
 	if err != nil {
 		log.Error("Failed to launch browser", "error", err)
 		os.Exit(1)
 	}
 	defer auto.closeBrowser()
 
-	// Run automation
-	err = auto.Run()
-	if err != nil {
-		log.Error("Automation error", "error", err)
-		os.Exit(1)
+	if *replayTrace != "" {
+		if err := auto.replayTrace(*replayTrace, *replayFixtures); err != nil {
+			log.Error("Trace replay failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	auto.startProxySession()
+
+	if *dryRunLive {
+		fmt.Println("\n[LIVE DRY RUN] - Requests and messages are filled in but never sent")
+		result, err := auto.RehearseLive()
+		if err != nil {
+			log.Error("Live dry run error", "error", err)
+			os.Exit(1)
+		}
+		if result.StopReason == StopChallenge {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *detectAccepted {
+		result, err := auto.DetectAcceptedOnly()
+		if err != nil {
+			log.Error("Detect accepted error", "error", err)
+			os.Exit(1)
+		}
+		if result.StopReason == StopChallenge {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *serve != "" {
+		if err := auto.serve(*serve); err != nil {
+			log.Error("API server error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run automation. With --continuous, each finished burst is followed
+	// by the next one until the day's bursts are done
+	auto.continuous = *continuous
+	auto.offerResume(*resume)
+	for {
+		result, err := auto.Run()
+		auto.printSummary(result)
+		auto.afterRun(result, err)
+		if err != nil {
+			log.Error("Automation error", "error", err)
+			os.Exit(1)
+		}
+		if !auto.continuous || result.Burst == 0 || result.StopReason != StopCompleted {
+			break
+		}
 	}
 
 	fmt.Println("\nAutomation completed successfully!")
 }
 
-// launchBrowser starts the Chromium browser
-func (a *Automation) launchBrowser(headless bool) error {
+// Browser display modes
+const (
+	DisplayHeadless    = "headless"
+	DisplayHeadlessNew = "new" // Chrome's new headless mode
+	DisplayHeadful     = "headful"
+)
+
+// flagPassed reports whether a flag was set on the command line
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// resolveDisplay picks the browser display mode. An explicit --headless
+// wins; otherwise stealth.headless decides, and under auto a challenge
+// within window_hours switches the next runs to after_challenge
+func (a *Automation) resolveDisplay(headless *bool, explicit bool) string {
+	if explicit {
+		if *headless {
+			return DisplayHeadless
+		}
+		return DisplayHeadful
+	}
+
+	cfg := a.config.Stealth.Headless
+	switch cfg.Mode {
+	case "headless":
+		return DisplayHeadless
+	case "headful":
+		return DisplayHeadful
+	}
+
+	runs, last, err := a.db.GetRunsSinceChallenge()
+	if err != nil {
+		a.logger.LogError("load challenge history", err, nil)
+		return DisplayHeadless
+	}
+	if last == nil || time.Since(*last) > time.Duration(cfg.WindowHours)*time.Hour || runs >= cfg.Runs {
+		return DisplayHeadless
+	}
+
+	a.logger.Info("recent security challenge, changing browser display",
+		"challenged_at", *last, "runs_since", runs, "display", cfg.AfterChallenge)
+	fmt.Printf("⚠ Security challenge on %s, running %s (run %d of %d)\n",
+		last.Format("Jan 2 15:04"), cfg.AfterChallenge, runs+1, cfg.Runs)
+	if cfg.AfterChallenge == DisplayHeadlessNew {
+		return DisplayHeadlessNew
+	}
+	return DisplayHeadful
+}
+
+// launchBrowser starts the Chromium browser, or attaches to a running
+// Chrome when browser.attach_url is set
+func (a *Automation) launchBrowser(display string) error {
+	a.display = display
+	plan := planLaunch(a.config)
+	for _, setting := range plan.ignored {
+		a.logger.Warn("setting ignored when attaching to a running browser", "setting", setting)
+		fmt.Printf("⚠ %s is ignored when attaching to a running browser\n", setting)
+	}
+	if plan.attachURL != "" {
+		return a.attachBrowser(plan.attachURL)
+	}
+
 	// Create launcher with stealth options
 	fm := stealth.NewFingerprintMasker(a.config.Stealth.Fingerprint)
 
 	l := launcher.New().
-		Headless(headless).
+		Headless(display == DisplayHeadless).
 		Set("disable-blink-features", "AutomationControlled").
 		Set("disable-dev-shm-usage").
 		Set("no-first-run").
 		Set("no-default-browser-check").
 		Set("disable-infobars")
 
-	// Set user agent
+	// A visible window opens at a random spot that keeps it on screen
+	viewport := fm.GetRandomViewport()
+	if display == DisplayHeadful && a.config.Stealth.Fingerprint.RandomizeViewport {
+		l.Set("window-position", fm.WindowPositionArg(viewport))
+	}
+
+	// Set user agent, and have the navigator overrides match it
 	userAgent := fm.GetRandomUserAgent()
+	if problems := stealth.CheckUserAgentConsistency(userAgent); len(problems) > 0 {
+		for _, p := range problems {
+			a.logger.Warn("user agent inconsistency", "userAgent", userAgent, "problem", p)
+		}
+		if a.config.Stealth.Fingerprint.UAConsistency == stealth.ConsistencyStrict {
+			return fmt.Errorf("user agent fails consistency checks: %s", strings.Join(problems, "; "))
+		}
+		fmt.Printf("⚠ User agent inconsistencies: %s\n", strings.Join(problems, "; "))
+	}
 	l.Set("user-agent", userAgent)
+	a.authenticator.SetUserAgent(userAgent)
+
+	if display == DisplayHeadlessNew {
+		l.Set("headless", "new")
+	}
+
+	if a.config.Proxy.URL != "" {
+		l.Proxy(a.config.Proxy.Server())
+	}
+
+	if path := a.config.Browser.ExecutablePath; path != "" {
+		l.Bin(path)
+	}
+	if dir := a.config.Browser.UserDataDir; dir != "" {
+		if err := prepareProfileDir(dir); err != nil {
+			return err
+		}
+		l.UserDataDir(dir)
+	}
 
 	url, err := l.Launch()
 	if err != nil {
+		// Chrome hands a locked profile to the instance holding it and
+		// exits, which surfaces here as a bare launch failure
+		if owner, locked := profileLockOwner(a.config.Browser.UserDataDir); locked {
+			return fmt.Errorf("browser profile %s is in use by another Chrome (%s): %w", a.config.Browser.UserDataDir, owner, err)
+		}
 		return fmt.Errorf("failed to launch browser: %w", err)
 	}
 
@@ -158,28 +499,105 @@ func (a *Automation) launchBrowser(headless bool) error {
 		return fmt.Errorf("failed to connect to browser: %w", err)
 	}
 
-	// Set viewport
-	viewport := fm.GetRandomViewport()
-	a.browser.DefaultDevice(devices.Device{
-		Title:  "Desktop",
-		Screen: devices.Screen{Width: viewport.Width, Height: viewport.Height},
-	})
+	if user, pass := a.config.Proxy.Auth(); user != "" {
+		go func() {
+			if err := a.browser.HandleAuth(user, pass)(); err != nil {
+				a.logger.Error("proxy authentication failed", "error", err)
+			}
+		}()
+	}
 
-	a.logger.Info("Browser launched", "headless", headless, "userAgent", userAgent[:50]+"...")
+	// Emulate the viewport, scale factor and user agent on every page
+	a.browser.DefaultDevice(desktopDevice(viewport, userAgent))
+
+	a.logger.Info("Browser launched", "display", display, "userAgent", userAgent[:50]+"...", "proxy", a.config.Proxy.ID(),
+		"viewport", fmt.Sprintf("%dx%d@%gx", viewport.Width, viewport.Height, viewport.ScaleFactor))
 	return nil
 }
 
-// closeBrowser closes the browser
+// startProxySession records the proxy and egress IP this run uses and warns
+// when the proxy has a history of drawing security challenges
+func (a *Automation) startProxySession() {
+	session := &database.ProxySession{
+		ID:        fmt.Sprintf("session_%d", time.Now().UnixNano()),
+		Proxy:     a.config.Proxy.ID(),
+		StartedAt: time.Now(),
+	}
+
+	if a.config.Proxy.IPCheckURL != "" {
+		ip, err := a.detectEgressIP()
+		if err != nil {
+			a.logger.Warn("could not determine egress IP", "error", err)
+		}
+		session.EgressIP = ip
+	}
+
+	if stats, err := a.db.GetProxyStats(); err == nil {
+		for _, ps := range stats {
+			if ps.Proxy == session.Proxy && ps.Challenged > 0 {
+				fmt.Printf("⚠ Proxy %s drew challenges in %d of %d previous sessions\n", ps.Proxy, ps.Challenged, ps.Sessions)
+			}
+		}
+	}
+
+	if err := a.db.StartProxySession(session); err != nil {
+		a.logger.LogError("record proxy session", err, nil)
+		return
+	}
+	a.proxySession = session
+	a.authenticator.SetProxySession(session.ID)
+
+	a.logger.Info("session started", "proxy", session.Proxy, "egressIP", session.EgressIP)
+	fmt.Printf("✓ Proxy: %s, egress IP: %s\n", session.Proxy, session.EgressIP)
+}
+
+// detectEgressIP loads the configured IP check URL and returns its body
+func (a *Automation) detectEgressIP() (string, error) {
+	page, err := a.browser.Page(proto.TargetCreateTarget{URL: a.config.Proxy.IPCheckURL})
+	if err != nil {
+		return "", err
+	}
+	defer page.Close()
+
+	page = page.Timeout(15 * time.Second)
+	if err := utils.WaitLoadBounded(page, a.config.LinkedIn.PageLoadTimeout, a.logger); err != nil {
+		return "", err
+	}
+	body, err := page.Element("body")
+	if err != nil {
+		return "", err
+	}
+	text, err := body.Text()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// closeBrowser closes the browser. An attached browser belongs to the
+// user and stays open
 func (a *Automation) closeBrowser() {
-	if a.browser != nil {
-		a.browser.Close()
+	if a.browser == nil {
+		return
+	}
+	if a.attached {
+		a.logger.Info("leaving attached browser open")
+		return
 	}
+	a.browser.Close()
 }
 
 // Run executes the main automation workflow
-func (a *Automation) Run() error {
-	a.isRunning = true
-	defer func() { a.isRunning = false }()
+func (a *Automation) Run() (result *RunResult, err error) {
+	a.isRunning.Store(true)
+	result = newRunResult()
+	a.beginRun()
+	defer func() {
+		a.isRunning.Store(false)
+		result.finish(err)
+		a.endRun(result)
+	}()
+	a.clock = newClockWatch()
 
 	a.logger.Info("Starting automation workflow")
 
@@ -190,186 +608,262 @@ func (a *Automation) Run() error {
 		fmt.Printf("Business hours: %d:00 - %d:00\n",
 			a.config.RateLimits.BusinessHoursStart,
 			a.config.RateLimits.BusinessHoursEnd)
-		return nil
+		result.StopReason = StopOutsideHours
+		return result, nil
+	}
+	if !a.startBurst(result) {
+		return result, nil
 	}
 
+	// Counted before this run's login is recorded
+	a.observeRemaining = a.observeOnlyRemaining()
+
 	// Step 1: Authenticate
 	fmt.Println("\n[Step 1] Authenticating...")
+	if ok, err := a.authenticate(result); !ok {
+		return result, err
+	}
+	defer a.finishBurst()
+	if a.observing() {
+		a.runObserveStart(result)
+	}
+
+	// Remaining steps run in the configured (or randomized) order
+	order := resolveStepOrder(a.config.Workflow)
+	a.logger.Info("workflow step order", "order", order, "only", a.phases.String())
+	fmt.Printf("Step order: %v\n", order)
+	if len(a.phases) > 0 {
+		fmt.Printf("Running only: %s\n", a.phases.String())
+	}
+
+	var selected []string
+	for _, step := range order {
+		if a.stepSelected(step) {
+			selected = append(selected, step)
+		}
+	}
+	a.work = newRunWork()
+	scheduler := newWorkScheduler(selected, a.config.Stealth.Scheduling.RandomizeWorkflowOrder)
+
+	n := 2
+	for {
+		unit, ok := scheduler.next()
+		if !ok {
+			break
+		}
+		stopped, more := a.runStep(unit, n, result)
+		if !more {
+			scheduler.done(unit.step)
+		}
+		if a.retryBudget.Depleted() {
+			result.StopReason = StopRetryBudget
+			return result, nil
+		}
+		if stopped {
+			if result.StopReason == "" {
+				result.StopReason = StopInterrupted
+			}
+			return result, nil
+		}
+		n++
+	}
+
+	return result, nil
+}
+
+// restoreSession reuses a LinkedIn session: first the one a persistent
+// browser profile still holds, then the cookies saved in the database
+// unless browser.inject_cookies turned them off for such profiles
+func (a *Automation) restoreSession() (*rod.Page, bool) {
+	if a.config.Browser.PersistentProfile() {
+		page, restored, err := a.authenticator.TryProfileSession(a.browser)
+		if err != nil {
+			a.logger.LogError("profile session", err, nil)
+		}
+		if restored {
+			fmt.Println("✓ Session restored from the browser profile")
+			return page, true
+		}
+		if !a.config.Browser.InjectCookies {
+			return nil, false
+		}
+	}
 
-	// Try session restore first
 	page, restored, err := a.authenticator.TrySessionRestore(a.browser)
 	if err != nil {
 		a.logger.LogError("session restore", err, nil)
 	}
-
 	if restored {
 		fmt.Println("✓ Session restored from saved cookies")
-		a.page = page
+	}
+	return page, restored
+}
+
+// authenticate restores the saved session or logs in, then detects the UI
+// variant. It returns false when the run cannot continue, either because of
+// an error or because a security challenge was recorded in result
+func (a *Automation) authenticate(result *RunResult) (bool, error) {
+	// Try session restore first
+	if page, restored := a.restoreSession(); restored {
+		a.usePage(page)
+		// A session near the end of its life is renewed now rather than
+		// expiring halfway through the run
+		if a.session.NeedsRefresh() {
+			if err := a.session.Refresh(a.browser, a.authenticator); err != nil {
+				var challenge *auth.ChallengeError
+				if errors.As(err, &challenge) {
+					a.stopOnChallenge(result, challenge.Type, challenge.Message)
+					return false, nil
+				}
+				return false, fmt.Errorf("session refresh: %w", err)
+			}
+			fmt.Println("✓ Session refreshed")
+		}
 	} else {
 		// Perform fresh login
-		page, result, err := a.authenticator.Login(a.browser)
+		page, login, err := a.authenticator.Login(a.browser)
 		if err != nil {
-			return fmt.Errorf("login failed: %w", err)
+			// A dead proxy is swapped for the pool's next, then login
+			// starts over
+			if a.rotateProxy(err) {
+				return a.authenticate(result)
+			}
+			return false, fmt.Errorf("login failed: %w", err)
 		}
 
-		if result.SecurityChallenge {
-			fmt.Printf("\n⚠ Security challenge detected: %s\n", result.ChallengeType)
-			fmt.Println(result.ErrorMessage)
-			fmt.Println("\nPlease complete the verification manually and restart the automation.")
-			return nil
+		if login.SecurityChallenge {
+			a.stopOnChallenge(result, login.ChallengeType, login.ErrorMessage)
+			return false, nil
 		}
 
-		if !result.Success {
-			return fmt.Errorf("login failed: %s", result.ErrorMessage)
+		if !login.Success {
+			return false, fmt.Errorf("login failed: %s", login.ErrorMessage)
 		}
 
 		fmt.Println("✓ Login successful")
-		a.page = page
+		a.usePage(page)
 	}
 
-	// Step 2: Search for profiles
-	fmt.Println("\n[Step 2] Searching for profiles...")
-	searchResult, err := a.searchModule.Search(a.page)
-	if err != nil {
-		a.logger.LogError("search", err, nil)
-		fmt.Printf("⚠ Search error: %v\n", err)
-	} else {
-		fmt.Printf("✓ Found %d profiles (%d unique, %d duplicates)\n",
-			searchResult.TotalFound,
-			len(searchResult.Profiles),
-			searchResult.Duplicates)
+	if a.throttle != nil {
+		a.throttle.watch(a.page)
+	}
+	if a.proxySession != nil {
+		if err := a.db.MarkProxySessionAuthenticated(a.proxySession.ID); err != nil {
+			a.logger.LogError("mark proxy session authenticated", err, nil)
+		}
 	}
 
-	// Step 3: Send connection requests
-	fmt.Println("\n[Step 3] Sending connection requests...")
-
-	canSend, remaining, _ := a.connectionManager.CanSendMoreToday()
-	if !canSend {
-		fmt.Println("⚠ Daily connection limit reached")
+	// The UI variant markers are on profile and search result pages, not
+	// on the feed login lands on: the first profile visited detects it
+	if variant := a.selectors.Variant(); variant != selectors.VariantUnknown {
+		fmt.Printf("✓ LinkedIn UI variant: %s\n", variant)
 	} else {
-		fmt.Printf("Remaining connections today: %d\n", remaining)
-
-		connectionsSent := 0
-		for i, profile := range searchResult.Profiles {
-			select {
-			case <-a.stopChan:
-				fmt.Println("\nStopping...")
-				return nil
-			default:
-			}
-
-			// Check if we can send more
-			canSend, _, _ = a.connectionManager.CanSendMoreToday()
-			if !canSend {
-				fmt.Println("\n⚠ Daily limit reached, stopping connection requests")
-				break
-			}
+		fmt.Println("LinkedIn UI variant is detected on the first profile, trying all known selector sets until then")
+	}
+	return true, nil
+}
 
-			req := &messaging.ConnectionRequest{
-				ProfileURL:  profile.ProfileURL,
-				FirstName:   profile.FirstName,
-				LastName:    profile.LastName,
-				JobTitle:    profile.JobTitle,
-				Company:     profile.Company,
-				TemplateIdx: i,
-			}
+// stopOnChallenge records a security challenge met while authenticating:
+// the proxy session is marked challenged and the run stops with
+// StopChallenge
+func (a *Automation) stopOnChallenge(result *RunResult, challengeType, message string) {
+	if a.proxySession != nil {
+		if err := a.db.MarkProxySessionChallenged(a.proxySession.ID); err != nil {
+			a.logger.LogError("mark proxy session challenged", err, nil)
+		}
+	}
+	fmt.Printf("\n⚠ Security challenge detected: %s\n", challengeType)
+	fmt.Println(message)
+	fmt.Println("\nPlease complete the verification manually and restart the automation.")
+	result.Challenge = challengeType
+	result.StopReason = StopChallenge
+}
 
-			result, err := a.connectionManager.SendConnectionRequest(a.page, req)
-			if err != nil {
-				a.logger.LogError("connection request", err, map[string]interface{}{"profile": profile.ProfileURL})
-				continue
-			}
+// DetectAcceptedOnly logs in, refreshes the status of pending invitations
+// and lists the newly accepted ones, without sending anything
+func (a *Automation) DetectAcceptedOnly() (result *RunResult, err error) {
+	a.isRunning.Store(true)
+	result = newRunResult()
+	defer func() {
+		a.isRunning.Store(false)
+		result.finish(err)
+	}()
 
-			if result.Success {
-				connectionsSent++
-				fmt.Printf("  ✓ Sent to %s %s\n", profile.FirstName, profile.LastName)
-			} else {
-				fmt.Printf("  ⚠ Failed: %s\n", result.ErrorMessage)
-			}
+	a.logger.Info("Detecting accepted connections")
 
-			// Rate limiting delay
-			a.waitBetweenActions()
-		}
+	if !a.config.IsBusinessHours() {
+		fmt.Printf("\nOutside business hours (%d:00 - %d:00), not logging in.\n",
+			a.config.RateLimits.BusinessHoursStart,
+			a.config.RateLimits.BusinessHoursEnd)
+		result.StopReason = StopOutsideHours
+		return result, nil
+	}
 
-		fmt.Printf("\n✓ Sent %d connection requests\n", connectionsSent)
+	fmt.Println("\n[Step 1] Authenticating...")
+	if ok, err := a.authenticate(result); !ok {
+		return result, err
 	}
 
-	// Step 4: Check for accepted connections and send follow-ups
-	fmt.Println("\n[Step 4] Checking accepted connections...")
+	fmt.Println("\n[Step 2] Checking accepted connections...")
 	accepted, err := a.messageManager.DetectAcceptedConnections(a.page)
 	if err != nil {
-		a.logger.LogError("detect accepted", err, nil)
-	} else {
-		fmt.Printf("✓ Found %d newly accepted connections\n", len(accepted))
+		return result, fmt.Errorf("detect accepted: %w", err)
 	}
 
-	// Send follow-up messages
-	if len(a.config.Messaging.Templates) > 0 {
-		needFollowUp, _ := a.messageManager.GetConnectionsNeedingFollowUp()
-		if len(needFollowUp) > 0 {
-			fmt.Printf("\n[Step 5] Sending follow-up messages to %d connections...\n", len(needFollowUp))
-
-			messagesSent := 0
-			for i, conn := range needFollowUp {
-				select {
-				case <-a.stopChan:
-					return nil
-				default:
-				}
-
-				canSend, _, _ := a.messageManager.CanSendMoreMessagesToday()
-				if !canSend {
-					fmt.Println("\n⚠ Daily message limit reached")
-					break
-				}
-
-				req := &messaging.MessageRequest{
-					ConnectionID: conn.ID,
-					ProfileURL:   conn.ProfileURL,
-					FirstName:    conn.FirstName,
-					LastName:     conn.LastName,
-					JobTitle:     conn.JobTitle,
-					Company:      conn.Company,
-					TemplateIdx:  i,
-				}
-
-				result, err := a.messageManager.SendMessage(a.page, req)
-				if err != nil {
-					a.logger.LogError("send message", err, nil)
-					continue
-				}
-
-				if result.Success {
-					messagesSent++
-					fmt.Printf("  ✓ Message sent to %s %s\n", conn.FirstName, conn.LastName)
-				}
-
-				a.waitBetweenActions()
-			}
-
-			fmt.Printf("\n✓ Sent %d follow-up messages\n", messagesSent)
-		}
+	result.AcceptedDetected = len(accepted)
+	fmt.Printf("✓ Found %d newly accepted connections\n", len(accepted))
+	for _, conn := range accepted {
+		fmt.Printf("  • %s %s  %s\n", conn.FirstName, conn.LastName, conn.ProfileURL)
 	}
-
-	// Print summary
-	a.printSummary()
-
-	return nil
+	return result, nil
 }
 
-// waitBetweenActions waits with randomized delay between actions
+// waitBetweenActions waits with randomized delay between actions, scaled by
+// the session rhythm
 func (a *Automation) waitBetweenActions() {
 	timing := stealth.NewTimingController(a.config.Stealth.Timing)
 	delay := timing.GetRandomizedDelay(
 		a.config.RateLimits.MinActionDelayMs,
 		a.config.RateLimits.MaxActionDelayMs,
 	)
+	time.Sleep(a.rhythm.Apply(delay))
+}
+
+// waitAfterError pauses for the configured error recovery range, modelling
+// a person stopping to work out what went wrong before carrying on
+func (a *Automation) waitAfterError() {
+	timing := stealth.NewTimingController(a.config.Stealth.Timing)
+	delay := timing.GetRandomizedDelay(
+		a.config.RateLimits.ErrorRecoveryMinMs,
+		a.config.RateLimits.ErrorRecoveryMaxMs,
+	)
+	a.logger.Debug("pausing after error", "delay", delay)
 	time.Sleep(delay)
 }
 
-// Stop signals the automation to stop
+// usePage makes page the one runs work in. Under --serve the browser
+// outlives a run, so the page an earlier run left open is closed
+func (a *Automation) usePage(page *rod.Page) {
+	if a.page != nil && a.page != page {
+		a.page.Close()
+	}
+	a.page = page
+}
+
+// Stop signals the automation to stop. Calling it again is harmless
 func (a *Automation) Stop() {
-	if a.isRunning {
+	if a.isRunning.Load() {
+		a.closeStop()
+	}
+}
+
+// closeStop closes stopChan unless it already is
+func (a *Automation) closeStop() {
+	a.stopMu.Lock()
+	defer a.stopMu.Unlock()
+	select {
+	case <-a.stopChan:
+	default:
 		close(a.stopChan)
 	}
 }
@@ -382,11 +876,26 @@ func (a *Automation) runDryRun() {
 	fmt.Printf("Job Titles: %v\n", a.config.Search.JobTitles)
 	fmt.Printf("Locations: %v\n", a.config.Search.Locations)
 	fmt.Printf("Max Pages: %d\n", a.config.Search.MaxPages)
-	fmt.Printf("Daily Connection Limit: %d\n", a.config.Connection.DailyLimit)
-	fmt.Printf("Daily Message Limit: %d\n", a.config.Messaging.DailyLimit)
+	fmt.Printf("Daily Connection Limit: %d\n", a.config.Connection.EffectiveDailyLimit(time.Now()))
+	fmt.Printf("Weekly / Monthly Connection Limit: %d / %d (0 = none)\n", a.config.Connection.WeeklyLimit, a.config.Connection.MonthlyLimit)
+	fmt.Printf("Connection Source: %s\n", a.config.Connection.Source)
+	fmt.Printf("Daily Message Limit: %d\n", a.config.Messaging.EffectiveDailyLimit(time.Now()))
 	fmt.Printf("Business Hours: %d:00 - %d:00\n",
 		a.config.RateLimits.BusinessHoursStart,
 		a.config.RateLimits.BusinessHoursEnd)
+	fmt.Printf("Connection Hours: %s\n", a.config.ConnectionHours())
+	fmt.Printf("Messaging Hours: %s\n", a.config.MessagingHours())
+	fmt.Printf("Step Order: %v (randomized: %v)\n", resolveStepOrder(a.config.Workflow), a.config.Workflow.RandomizeOrder)
+	fmt.Printf("Activity Bursts per Day: %d-%d\n", a.config.Workflow.BurstsPerDayMin, a.config.Workflow.BurstsPerDayMax)
+	if plan, err := a.db.GetBurstPlan(time.Now().Format("2006-01-02")); err == nil && plan != nil {
+		fmt.Printf("Today's Bursts: %s (%d run)\n", formatBurstStarts(plan.Starts), plan.Completed)
+	}
+	if len(a.phases) > 0 {
+		fmt.Printf("Only Phases: %s\n", a.phases.String())
+	}
+	if remaining := a.observeOnlyRemaining(); remaining > 0 {
+		fmt.Printf("Observe-only Runs Left: %d of %d\n", remaining, a.config.Account.ObserveOnlyRuns)
+	}
 
 	fmt.Println("\n--- Stealth Configuration ---")
 	fmt.Printf("Bézier Curves: %v\n", a.config.Stealth.Bezier.Enabled)
@@ -394,6 +903,10 @@ func (a *Automation) runDryRun() {
 		a.config.Stealth.Timing.TypingMinDelayMs,
 		a.config.Stealth.Timing.TypingMaxDelayMs)
 	fmt.Printf("Typo Probability: %.2f\n", a.config.Stealth.Timing.TypoProbability)
+	fmt.Printf("Delay Distribution: %s\n", a.config.Stealth.Timing.Distribution)
+	fmt.Printf("Pre-navigation Pause: %d-%dms\n",
+		a.config.Stealth.Timing.PreNavMinMs,
+		a.config.Stealth.Timing.PreNavMaxMs)
 	fmt.Printf("User Agent Rotation: %v\n", a.config.Stealth.Fingerprint.RotateUserAgent)
 	fmt.Printf("Viewport Randomization: %v\n", a.config.Stealth.Fingerprint.RandomizeViewport)
 
@@ -402,6 +915,12 @@ func (a *Automation) runDryRun() {
 		fmt.Printf("%d. %s\n", i+1, t)
 	}
 
+	if ab := a.config.Connection.ABTest; ab.Enabled {
+		fmt.Printf("\n--- A/B Test %q (%.0f%% A) ---\n", ab.Name, ab.Split*100)
+		fmt.Printf("A. %s\n", ab.VariantA)
+		fmt.Printf("B. %s\n", ab.VariantB)
+	}
+
 	fmt.Println("\n--- Message Templates ---")
 	for i, t := range a.config.Messaging.Templates {
 		fmt.Printf("%d. %s\n", i+1, t)
@@ -411,15 +930,65 @@ func (a *Automation) runDryRun() {
 	fmt.Println("Remove --dry-run flag to start actual automation")
 }
 
-// printSummary prints the automation summary
-func (a *Automation) printSummary() {
+// printSummary prints the automation summary for a finished run
+func (a *Automation) printSummary(result *RunResult) {
 	activity, _ := a.db.GetOrCreateDailyActivity()
 
 	fmt.Println("\n==================================================")
 	fmt.Println("   Automation Summary")
 	fmt.Println("==================================================")
-	fmt.Printf("Connections sent today: %d / %d\n", activity.ConnectionsSent, a.config.Connection.DailyLimit)
-	fmt.Printf("Messages sent today: %d / %d\n", activity.MessagesSent, a.config.Messaging.DailyLimit)
+	fmt.Printf("Run finished: %s after %s\n", result.StopReason, result.Duration.Round(time.Second))
+	if result.Challenge != "" {
+		fmt.Printf("Security challenge: %s\n", result.Challenge)
+	}
+	fmt.Printf("Profiles found: %d\n", result.ProfilesFound)
+	fmt.Printf("Connections this run: %d sent, %d failed\n", result.ConnectionsSent, result.ConnectionsFailed)
+	fmt.Printf("Messages this run: %d sent, %d failed\n", result.MessagesSent, result.MessagesFailed)
+	fmt.Printf("Newly accepted: %d\n", result.AcceptedDetected)
+	if result.InvitationsAccepted > 0 {
+		fmt.Printf("Incoming invitations accepted: %d\n", result.InvitationsAccepted)
+	}
+	if result.ActionsAborted > 0 {
+		fmt.Printf("Aborted at shutdown: %d (not counted as sent)\n", result.ActionsAborted)
+	}
+	if result.ClockJumps > 0 {
+		fmt.Printf("Clock jumps (sleep/resume): %d\n", result.ClockJumps)
+	}
+	if result.Throttled > 0 {
+		fmt.Printf("Throttle pauses: %d\n", result.Throttled)
+	}
+	if result.Bursts > 0 && a.plan != nil {
+		fmt.Printf("Activity bursts today: %d of %d run (planned %s)\n", a.plan.Completed, result.Bursts, formatBurstStarts(a.plan.Starts))
+	}
+	if result.ObserveOnly {
+		fmt.Printf("Observe-only: %d more run(s) before invitations and messages start\n", a.observeRemaining-1)
+	}
+	fmt.Printf("Connections sent today: %d / %d\n", activity.ConnectionsSent, a.config.Connection.EffectiveDailyLimit(time.Now()))
+	if limit := a.config.Connection.WeeklyLimit; limit > 0 {
+		if sent, err := a.db.GetWeeklyConnectionCount(); err == nil {
+			fmt.Printf("Connections sent in the last 7 days: %d / %d\n", sent, limit)
+		}
+	}
+	if limit := a.config.Connection.MonthlyLimit; limit > 0 {
+		if sent, err := a.db.GetMonthlyConnectionCount(); err == nil {
+			fmt.Printf("Connections sent in the last 30 days: %d / %d\n", sent, limit)
+		}
+	}
+	fmt.Printf("Messages sent today: %d / %d\n", activity.MessagesSent, a.config.Messaging.EffectiveDailyLimit(time.Now()))
+	if blocked, next, _ := a.connectionManager.WeeklyLimitStatus(); blocked {
+		fmt.Printf("Weekly invitation limit: next invitation expected after %s\n", next.Format("Mon Jan 2 15:04"))
+	}
+
+	if result.ConnectionsFailed > 0 {
+		fmt.Println("\nConnection failures this run:")
+		for _, reason := range messaging.FailureReasons {
+			if count := result.FailureReasons[reason]; count > 0 {
+				fmt.Printf("  %-18s %d\n", reason, count)
+			}
+		}
+	}
+
+	fmt.Printf("Active hours: connections %s, messages %s\n", a.config.ConnectionHours(), a.config.MessagingHours())
 
 	if a.config.IsBusinessHours() {
 		fmt.Println("\nNext run: Whenever you start the automation again")
@@ -430,29 +999,314 @@ func (a *Automation) printSummary() {
 	}
 }
 
-// maskEmail masks email for logging
-func maskEmail(email string) string {
-	if len(email) < 5 {
-		return "***"
+// printABReport prints the acceptance rate of each A/B test variant and
+// whether the difference between them is significant
+func printABReport(db database.Store) error {
+	stats, err := db.GetVariantStats()
+	if err != nil {
+		return err
 	}
-	return email[:3] + "***" + email[len(email)-4:]
+
+	fmt.Println("\n==================================================")
+	fmt.Println("   Connection Note A/B Tests")
+	fmt.Println("==================================================")
+	if len(stats) == 0 {
+		fmt.Println("No connections have been tagged with an A/B test variant yet")
+		return nil
+	}
+
+	for _, c := range messaging.CompareTests(stats) {
+		fmt.Printf("\nTest: %s\n", c.Test)
+		fmt.Printf("  A: %d sent, %d accepted (%.1f%%)\n", c.A.Sent, c.A.Accepted, c.RateA*100)
+		fmt.Printf("  B: %d sent, %d accepted (%.1f%%)\n", c.B.Sent, c.B.Accepted, c.RateB*100)
+		switch {
+		case c.A.Sent == 0 || c.B.Sent == 0:
+			fmt.Println("  Result: not enough data, both variants need sent invitations")
+		case c.Significant:
+			winner := messaging.VariantA
+			if c.RateB > c.RateA {
+				winner = messaging.VariantB
+			}
+			fmt.Printf("  Result: %s is better, significant at 95%% (z = %.2f)\n", winner, c.Z)
+		default:
+			fmt.Printf("  Result: no significant difference yet (z = %.2f)\n", c.Z)
+		}
+	}
+	return nil
+}
+
+// printActivityReport prints connections, messages and acceptances per day
+// for the last days days, or writes them as CSV when csvPath is set
+func printActivityReport(db database.Store, days int, csvPath string) error {
+	series, err := db.GetActivityTimeSeries(days)
+	if err != nil {
+		return err
+	}
+
+	if csvPath != "" {
+		f, err := os.Create(csvPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w := csv.NewWriter(f)
+		w.Write([]string{"date", "connections_sent", "messages_sent", "accepted"})
+		for _, day := range series {
+			w.Write([]string{day.Date, strconv.Itoa(day.ConnectionsSent), strconv.Itoa(day.MessagesSent), strconv.Itoa(day.Accepted)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Wrote %d days of activity to %s\n", len(series), csvPath)
+		return nil
+	}
+
+	fmt.Println("\n==================================================")
+	fmt.Printf("   Activity, last %d days\n", days)
+	fmt.Println("==================================================")
+	fmt.Printf("%-12s %12s %10s %10s\n", "Date", "Connections", "Messages", "Accepted")
+	var connections, messages, accepted int
+	for _, day := range series {
+		fmt.Printf("%-12s %12d %10d %10d\n", day.Date, day.ConnectionsSent, day.MessagesSent, day.Accepted)
+		connections += day.ConnectionsSent
+		messages += day.MessagesSent
+		accepted += day.Accepted
+	}
+	fmt.Printf("%-12s %12d %10d %10d\n", "Total", connections, messages, accepted)
+	return printPageReport(db)
+}
+
+// printPageReport prints how invitations convert by the search results page
+// the profile was found on, over all connections sent
+func printPageReport(db database.Store) error {
+	pages, err := db.GetAcceptanceByPage()
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return nil
+	}
+
+	fmt.Println("\nAcceptance by search page")
+	fmt.Printf("%-12s %12s %10s %10s\n", "Page", "Sent", "Accepted", "Rate")
+	for _, p := range pages {
+		page := strconv.Itoa(p.PageNumber)
+		if p.PageNumber == 0 {
+			page = "unknown" // suggestions and rows saved before pages were tracked
+		}
+		rate := 0.0
+		if p.Sent > 0 {
+			rate = float64(p.Accepted) / float64(p.Sent) * 100
+		}
+		fmt.Printf("%-12s %12d %10d %9.1f%%\n", page, p.Sent, p.Accepted, rate)
+	}
+	return nil
+}
+
+// Message export formats
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// exportedMessageJSON is one message in a JSON export
+type exportedMessageJSON struct {
+	MessageID         string    `json:"message_id"`
+	ConnectionID      string    `json:"connection_id"`
+	Name              string    `json:"name"`
+	ProfileURL        string    `json:"profile_url"`
+	ConnectionMissing bool      `json:"connection_missing,omitempty"`
+	Content           string    `json:"content"`
+	Status            string    `json:"status"`
+	TemplateID        string    `json:"template_id"`
+	SentAt            time.Time `json:"sent_at"`
+}
+
+// resolveExportFormat returns format, or the format matching the extension
+// of path when format is empty
+func resolveExportFormat(path, format string) (string, error) {
+	if format == "" {
+		format = ExportCSV
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = ExportJSON
+		}
+	}
+	if format != ExportCSV && format != ExportJSON {
+		return "", fmt.Errorf("export format must be csv or json, got %q", format)
+	}
+	return format, nil
 }
 
-// devices package stub for viewport
-var devices = struct {
-	Device func(title string, width, height int) Device
-}{
-	Device: func(title string, width, height int) Device {
-		return Device{Title: title, Screen: Screen{Width: width, Height: height}}
-	},
+// exportedConnectionJSON is one connection in a JSON export
+type exportedConnectionJSON struct {
+	ConnectionID   string     `json:"connection_id"`
+	Name           string     `json:"name"`
+	ProfileURL     string     `json:"profile_url"`
+	JobTitle       string     `json:"job_title"`
+	Company        string     `json:"company"`
+	SnapshotSource string     `json:"snapshot_source"`
+	CriteriaID     string     `json:"criteria_id"`
+	PageNumber     int        `json:"page_number"`
+	Position       int        `json:"position"`
+	Note           string     `json:"note"`
+	Variant        string     `json:"variant,omitempty"`
+	Status         string     `json:"status"`
+	SentAt         time.Time  `json:"sent_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
 }
 
-type Device struct {
-	Title  string
-	Screen Screen
+// exportConnectionHistory writes every connection request as an audit
+// record: who was contacted, when, through which criteria, with which note,
+// and their title and company at the time
+func exportConnectionHistory(db database.Store, path, format string) error {
+	format, err := resolveExportFormat(path, format)
+	if err != nil {
+		return err
+	}
+
+	connections, err := db.ExportConnections()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == ExportJSON {
+		out := make([]exportedConnectionJSON, len(connections))
+		for i, c := range connections {
+			out[i] = exportedConnectionJSON{
+				ConnectionID:   c.ID,
+				Name:           strings.TrimSpace(c.FirstName + " " + c.LastName),
+				ProfileURL:     c.ProfileURL,
+				JobTitle:       c.JobTitle,
+				Company:        c.Company,
+				SnapshotSource: c.SnapshotSource,
+				CriteriaID:     c.SearchCriteriaID,
+				PageNumber:     c.PageNumber,
+				Position:       c.Position,
+				Note:           c.NoteSent,
+				Variant:        c.Variant,
+				Status:         c.Status,
+				SentAt:         c.CreatedAt,
+				AcceptedAt:     c.AcceptedAt,
+			}
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	} else {
+		w := csv.NewWriter(f)
+		w.Write([]string{"connection_id", "name", "profile_url", "job_title", "company", "snapshot_source", "criteria_id", "page_number", "position", "note", "variant", "status", "sent_at", "accepted_at"})
+		for _, c := range connections {
+			accepted := ""
+			if c.AcceptedAt != nil {
+				accepted = c.AcceptedAt.Format(time.RFC3339)
+			}
+			w.Write([]string{
+				c.ID,
+				strings.TrimSpace(c.FirstName + " " + c.LastName),
+				c.ProfileURL,
+				c.JobTitle,
+				c.Company,
+				c.SnapshotSource,
+				c.SearchCriteriaID,
+				strconv.Itoa(c.PageNumber),
+				strconv.Itoa(c.Position),
+				c.NoteSent,
+				c.Variant,
+				c.Status,
+				c.CreatedAt.Format(time.RFC3339),
+				accepted,
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ Exported %d connections to %s\n", len(connections), path)
+	return nil
 }
 
-type Screen struct {
-	Width  int
-	Height int
+// exportMessageHistory writes every sent message with its connection to
+// path as CSV or JSON. format defaults to the extension of path
+func exportMessageHistory(db database.Store, path, format string) error {
+	format, err := resolveExportFormat(path, format)
+	if err != nil {
+		return err
+	}
+
+	messages, err := db.ExportMessages()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == ExportJSON {
+		out := make([]exportedMessageJSON, len(messages))
+		for i, m := range messages {
+			out[i] = exportedMessageJSON{
+				MessageID:         m.ID,
+				ConnectionID:      m.ConnectionID,
+				Name:              strings.TrimSpace(m.FirstName + " " + m.LastName),
+				ProfileURL:        m.ProfileURL,
+				ConnectionMissing: m.ConnectionMissing,
+				Content:           m.Content,
+				Status:            m.Status,
+				TemplateID:        m.TemplateID,
+				SentAt:            m.SentAt,
+			}
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	} else {
+		// The csv writer quotes fields with commas, quotes and line breaks
+		w := csv.NewWriter(f)
+		w.Write([]string{"message_id", "connection_id", "name", "profile_url", "connection_missing", "content", "status", "template_id", "sent_at"})
+		for _, m := range messages {
+			w.Write([]string{
+				m.ID,
+				m.ConnectionID,
+				strings.TrimSpace(m.FirstName + " " + m.LastName),
+				m.ProfileURL,
+				strconv.FormatBool(m.ConnectionMissing),
+				m.Content,
+				m.Status,
+				m.TemplateID,
+				m.SentAt.Format(time.RFC3339),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ Exported %d messages to %s\n", len(messages), path)
+	return nil
+}
+
+// maskEmail masks email for logging
+func maskEmail(email string) string {
+	if len(email) < 5 {
+		return "***"
+	}
+	return email[:3] + "***" + email[len(email)-4:]
 }
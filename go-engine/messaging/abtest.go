@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	"linkedin-automation/database"
+)
+
+// A/B test variant labels
+const (
+	VariantA = "A"
+	VariantB = "B"
+)
+
+// significanceZ is the two-sided 95% critical value of the standard normal
+const significanceZ = 1.96
+
+// AssignVariant deterministically assigns a profile to variant A or B. The
+// same profile always lands in the same variant for a given test name, and
+// about split (0-1) of all profiles land in A
+func AssignVariant(testName, profileURL string, split float64) string {
+	h := fnv.New64a()
+	h.Write([]byte(testName))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.ToLower(strings.TrimRight(profileURL, "/"))))
+	bucket := float64(h.Sum64()%10000) / 10000
+	if bucket < split {
+		return VariantA
+	}
+	return VariantB
+}
+
+// VariantTag is the value stored in the connections.variant column
+func VariantTag(testName, variant string) string {
+	return testName + ":" + variant
+}
+
+// SplitVariantTag reverses VariantTag
+func SplitVariantTag(tag string) (testName, variant string) {
+	i := strings.LastIndex(tag, ":")
+	if i < 0 {
+		return "", tag
+	}
+	return tag[:i], tag[i+1:]
+}
+
+// ABComparison compares acceptance between the two variants of a test
+type ABComparison struct {
+	Test        string
+	A, B        database.VariantStats
+	RateA       float64
+	RateB       float64
+	Z           float64 // two-proportion z statistic, B relative to A
+	Significant bool    // |Z| exceeds the 95% critical value
+}
+
+// CompareVariants runs a two-proportion z-test on acceptance rates
+func CompareVariants(test string, a, b database.VariantStats) ABComparison {
+	c := ABComparison{Test: test, A: a, B: b}
+	if a.Sent == 0 || b.Sent == 0 {
+		return c
+	}
+
+	c.RateA = float64(a.Accepted) / float64(a.Sent)
+	c.RateB = float64(b.Accepted) / float64(b.Sent)
+
+	pooled := float64(a.Accepted+b.Accepted) / float64(a.Sent+b.Sent)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(a.Sent) + 1/float64(b.Sent)))
+	if se == 0 {
+		return c
+	}
+	c.Z = (c.RateB - c.RateA) / se
+	c.Significant = math.Abs(c.Z) > significanceZ
+	return c
+}
+
+// CompareTests groups per-variant stats by test name and compares the two
+// variants of each test, in test name order
+func CompareTests(stats []database.VariantStats) []ABComparison {
+	byTest := make(map[string]map[string]database.VariantStats)
+	var names []string
+	for _, vs := range stats {
+		test, variant := SplitVariantTag(vs.Variant)
+		if byTest[test] == nil {
+			byTest[test] = make(map[string]database.VariantStats)
+			names = append(names, test)
+		}
+		byTest[test][variant] = vs
+	}
+	sort.Strings(names)
+
+	comparisons := make([]ABComparison, 0, len(names))
+	for _, test := range names {
+		comparisons = append(comparisons, CompareVariants(test, byTest[test][VariantA], byTest[test][VariantB]))
+	}
+	return comparisons
+}
@@ -2,58 +2,198 @@ package messaging
 
 import (
 	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
 	"github.com/go-rod/rod/lib/proto"
 
 	"linkedin-automation/config"
 	"linkedin-automation/database"
 	"linkedin-automation/logger"
+	"linkedin-automation/search"
+	"linkedin-automation/selectors"
 	"linkedin-automation/stealth"
+	"linkedin-automation/utils"
 )
 
 // ConnectionManager handles sending connection requests
 type ConnectionManager struct {
 	config     config.ConnectionConfig
-	db         *database.DB
+	db         database.Store
 	logger     *logger.Logger
 	timing     *stealth.TimingController
 	typing     *stealth.TypingSimulator
 	bezier     *stealth.BezierMouse
 	mouse      *stealth.MouseHoverController
+	scroll     *stealth.ScrollController
+	pacer      *stealth.NavigationPacer
+	cursor     *stealth.Cursor
+	selectors  *selectors.Registry
+	site       config.LinkedInConfig
 	templates  []string
+	confirm    ConfirmFunc // nil sends without asking
+	retry      utils.RetryConfig
+	suggestion []string   // occupation terms a suggested profile must match; empty accepts all
+	emojiLimit int        // emoji a note may keep; -1 keeps all
+	variation  *Variation // {{greeting}} and {{closing}} pools; nil renders them empty
+	rng        *rand.Rand // picks among the least used templates
+
+	suggestionCriteria string // criteria ID recorded with suggestion invitations
 }
 
 // NewConnectionManager creates a new ConnectionManager
 func NewConnectionManager(
 	cfg config.ConnectionConfig,
-	db *database.DB,
+	db database.Store,
 	log *logger.Logger,
 	stealthCfg config.StealthConfig,
+	pacer *stealth.NavigationPacer,
+	cursor *stealth.Cursor,
+	registry *selectors.Registry,
+	site config.LinkedInConfig,
 ) *ConnectionManager {
+	// Validated when the config is loaded
+	emojiLimit, _ := utils.ParseEmojiPolicy(cfg.EmojiPolicy)
+
 	return &ConnectionManager{
-		config:    cfg,
-		db:        db,
-		logger:    log.WithComponent("connection"),
-		timing:    stealth.NewTimingController(stealthCfg.Timing),
-		typing:    stealth.NewTypingSimulator(stealthCfg.Timing),
-		bezier:    stealth.NewBezierMouse(stealthCfg.Bezier),
-		mouse:     stealth.NewMouseHoverController(stealthCfg.Mouse),
-		templates: cfg.Templates,
+		config:     cfg,
+		db:         db,
+		logger:     log.WithComponent("connection"),
+		timing:     stealth.NewTimingController(stealthCfg.Timing),
+		typing:     stealth.NewTypingSimulator(stealthCfg.Timing),
+		bezier:     stealth.NewBezierMouse(stealthCfg.Bezier),
+		mouse:      stealth.NewMouseHoverController(stealthCfg.Mouse),
+		scroll:     stealth.NewScrollController(stealthCfg.Scrolling),
+		pacer:      pacer,
+		cursor:     cursor,
+		selectors:  registry,
+		site:       site,
+		templates:  cfg.Templates,
+		rng:        utils.NewRand("templates"),
+		retry:      utils.NavigationRetryConfig(),
+		emojiLimit: emojiLimit,
 	}
 }
 
+// Decision is the answer to a connection request preview
+type Decision int
+
+// Preview decisions
+const (
+	DecisionSend Decision = iota // send the request
+	DecisionSkip                 // skip this profile and mark it processed
+	DecisionQuit                 // stop sending connection requests
+)
+
+// ConfirmFunc previews a fully prepared request, including the exact note,
+// and decides whether it is sent
+type ConfirmFunc func(req *ConnectionRequest) Decision
+
+// SetConfirm installs a preview step that runs before each Connect click
+func (cm *ConnectionManager) SetConfirm(confirm ConfirmFunc) {
+	cm.confirm = confirm
+}
+
+// SetRetryBudget makes navigation retries draw from the run-wide budget
+func (cm *ConnectionManager) SetRetryBudget(budget *utils.RetryBudget) {
+	cm.retry.Budget = budget
+}
+
+// SetVariation installs the greeting and closing pools for notes
+func (cm *ConnectionManager) SetVariation(v *Variation) {
+	cm.variation = v
+}
+
 // ConnectionRequest represents a connection request
 type ConnectionRequest struct {
-	ProfileURL  string
-	FirstName   string
-	LastName    string
-	JobTitle    string
-	Company     string
-	Note        string
-	TemplateIdx int
+	ProfileURL   string
+	FirstName    string
+	LastName     string
+	JobTitle     string
+	Company      string
+	Note         string
+	PageNumber   int    // search results page the profile came from
+	Position     int    // position of the profile on that page
+	OriginalURL  string // requested URL when the profile redirected elsewhere
+	MutualCount  int    // mutual connections shown on the profile
+	MutualName   string // one mutual connection by name, if LinkedIn lists any
+	CriteriaID   string // search criteria the profile was found with
+	Location     string // profile location, used to infer the note language
+	Language     string // language of the note's template group; empty for the regular templates
+	SharedSchool string // school LinkedIn says we both studied at
+	SharedGroup  string // LinkedIn group we are both members of
+}
+
+// Where the title and company recorded with an invitation were read
+const (
+	SnapshotProfile        = "profile"         // the live profile, at send time
+	SnapshotSearchCard     = "search_card"     // the search result, when the profile showed neither
+	SnapshotSuggestionCard = "suggestion_card" // the "People you may know" card
+)
+
+// FailureReason classifies why a connection request was not sent
+type FailureReason string
+
+// Connection failure reasons
+const (
+	ReasonButtonNotFound   FailureReason = "button-not-found"
+	ReasonAlreadyConnected FailureReason = "already-connected"
+	ReasonPending          FailureReason = "pending"
+	ReasonWeeklyLimit      FailureReason = "weekly-limit"
+	ReasonDailyLimit       FailureReason = "daily-limit" // no slot left under connection.daily_limit
+	ReasonEmailRequired    FailureReason = "email-required"
+	ReasonUnavailable      FailureReason = "unavailable"
+	ReasonSendUnconfirmed  FailureReason = "send-unconfirmed"
+	ReasonTemplatesCapped  FailureReason = "templates-capped"
+	ReasonLowQuality       FailureReason = "low-quality"
+	ReasonInactive         FailureReason = "inactive"
+	ReasonActivityHidden   FailureReason = "activity-hidden"
+	ReasonRedirected       FailureReason = "redirected"
+	ReasonDuplicate        FailureReason = "duplicate"
+	ReasonUserSkipped      FailureReason = "user-skipped"
+	ReasonUserQuit         FailureReason = "user-quit" // not a failure; ends the connect phase
+	ReasonAborted          FailureReason = "aborted"   // cut off at shutdown; not known to be sent
+	ReasonError            FailureReason = "error"
+)
+
+// Policies for when every note template has hit its daily cap
+const (
+	WhenCappedNoNote = "no_note"
+	WhenCappedStop   = "stop"
+)
+
+// Policies for a profile URL that redirects to a different profile
+const (
+	RedirectReconcile = "reconcile"
+	RedirectSkip      = "skip"
+	RedirectIgnore    = "ignore"
+)
+
+// FailureReasons lists every reason in reporting order
+var FailureReasons = []FailureReason{
+	ReasonButtonNotFound,
+	ReasonAlreadyConnected,
+	ReasonPending,
+	ReasonWeeklyLimit,
+	ReasonDailyLimit,
+	ReasonEmailRequired,
+	ReasonUnavailable,
+	ReasonSendUnconfirmed,
+	ReasonTemplatesCapped,
+	ReasonLowQuality,
+	ReasonInactive,
+	ReasonActivityHidden,
+	ReasonRedirected,
+	ReasonDuplicate,
+	ReasonUserSkipped,
+	ReasonAborted,
+	ReasonError,
 }
 
 // ConnectionResult represents the result of a connection request
@@ -62,44 +202,168 @@ type ConnectionResult struct {
 	ProfileURL   string
 	ErrorMessage string
 	NeedsCaptcha bool
+	Reason       FailureReason // set when Success is false
+	ConnectionID string        // database id of the sent invitation
+	OpenProfile  bool          // the profile takes messages from non-connections
+	DryRun       bool          // walked up to the Send click but not sent
+
+	// WeeklyLimitReached is set when LinkedIn showed the weekly invitation
+	// limit notice; no invitation can go out until the window frees up
+	WeeklyLimitReached bool
+}
+
+// failed builds an unsuccessful result with a typed reason
+func failed(profileURL string, reason FailureReason, message string) *ConnectionResult {
+	return &ConnectionResult{
+		Success:      false,
+		ProfileURL:   profileURL,
+		ErrorMessage: message,
+		Reason:       reason,
+
+		WeeklyLimitReached: reason == ReasonWeeklyLimit,
+	}
 }
 
-// SendConnectionRequest sends a connection request to a profile
-func (cm *ConnectionManager) SendConnectionRequest(page *rod.Page, req *ConnectionRequest) (*ConnectionResult, error) {
+// SendConnectionRequest sends a connection request to a profile. With
+// dryRun it goes through the invitation modal, note included, and closes it
+// instead of clicking Send; nothing about the request is recorded. Profile
+// bookkeeping on the way, such as skipped profiles, still goes to the
+// store, which a live dry run makes read-only
+func (cm *ConnectionManager) SendConnectionRequest(page *rod.Page, req *ConnectionRequest, dryRun bool) (_ *ConnectionResult, err error) {
+	defer utils.RecoverAsError(&err)
 	cm.logger.Info("sending connection request", "profile", req.ProfileURL)
 
 	// Navigate to profile
-	err := page.Navigate(req.ProfileURL)
+	time.Sleep(cm.timing.GetPreNavigationDelay())
+	err = utils.RetryWithBackoff(cm.retry, func() error {
+		cm.pacer.Wait()
+		err := page.Navigate(req.ProfileURL)
+		cm.logger.Trace(logger.TraceNavigate, req.ProfileURL, err)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to navigate: %w", err)
 	}
 
 	time.Sleep(cm.timing.GetPageLoadDelay())
-	err = page.WaitLoad()
+	err = utils.WaitLoadBounded(page, cm.site.PageLoadTimeout, cm.logger)
 	if err != nil {
 		cm.logger.LogError("page load", err, nil)
 	}
 
 	// Wait for profile to load
 	time.Sleep(cm.timing.GetThinkTime())
+	if variant, detected := cm.selectors.DetectIfUnknown(page); detected {
+		cm.logger.Info("detected LinkedIn UI variant", "variant", variant)
+	}
+
+	// Renamed vanity slugs and old /pub/ URLs land on a different profile URL
+	if cm.config.ProfileRedirects != RedirectIgnore {
+		if result := cm.handleRedirect(page, req); result != nil {
+			return result, nil
+		}
+	}
+
+	// Bail out early on profiles we cannot or need not invite, before
+	// anything is clicked
+	if reason, ok := cm.detectProfileState(page); ok {
+		cm.logger.Info("skipping profile", "profile", req.ProfileURL, "reason", reason)
+		cm.recordProfileState(req.ProfileURL, reason)
+		return failed(req.ProfileURL, reason, "profile is "+string(reason)), nil
+	}
+
+	// Filter out profiles that look new or inactive
+	if cm.config.MinQuality > 0 && !cm.passesQualityGate(page, req.ProfileURL) {
+		cm.db.MarkProfileProcessed(req.ProfileURL)
+		return failed(req.ProfileURL, ReasonLowQuality, "profile below min_profile_quality"), nil
+	}
+
+	// Filter by how recently the member was active
+	if cm.config.ActivityRecency.Enabled() {
+		if reason, message, ok := cm.checkActivityRecency(page, req.ProfileURL); !ok {
+			cm.db.MarkProfileProcessed(req.ProfileURL)
+			return failed(req.ProfileURL, reason, message), nil
+		}
+	}
 
-	// Extract profile data if not provided
+	// Snapshot title and company from the live profile for the audit
+	// record; search card values are kept only where the profile shows none
+	firstName, lastName, jobTitle, company := cm.extractProfileData(page)
 	if req.FirstName == "" {
-		req.FirstName, req.LastName, req.JobTitle, req.Company = cm.extractProfileData(page)
+		req.FirstName, req.LastName = firstName, lastName
+	}
+	snapshot := SnapshotSearchCard
+	if jobTitle != "" || company != "" {
+		snapshot = SnapshotProfile
+		if jobTitle != "" {
+			req.JobTitle = jobTitle
+		}
+		if company != "" {
+			req.Company = company
+		}
+	} else {
+		cm.logger.Warn("could not read title or company from profile, keeping search card values", "profile", req.ProfileURL)
+	}
+	cm.readMutualConnections(page, req)
+	cm.readSharedAffiliations(page, req)
+	if cm.config.NoteLanguages.Enabled() && req.Location == "" {
+		req.Location = cm.readProfileLocation(page)
+	}
+
+	// Check if we need to add a note. A running A/B test takes precedence
+	// over the regular template rotation
+	templateIdx := -1
+	variant := ""
+	if cm.config.ABTest.Enabled && req.Note == "" {
+		variant = AssignVariant(cm.config.ABTest.Name, req.ProfileURL, cm.config.ABTest.Split)
+		req.Note = cm.generateVariantNote(req, variant)
+		cm.logger.Debug("assigned A/B variant", "profile", req.ProfileURL, "test", cm.config.ABTest.Name, "variant", variant)
+		if req.Note == "" {
+			// An empty note is not a fair sample of the variant
+			cm.logger.Warn("A/B variant note rendered empty, sending without a note", "profile", req.ProfileURL, "variant", variant)
+			variant = ""
+		}
+	} else if cm.hasNoteTemplates() && req.Note == "" {
+		req.Note, templateIdx = cm.generateNote(req)
+		if templateIdx < 0 {
+			cm.logger.Info("all note templates at daily cap", "policy", cm.config.WhenCapped)
+			if cm.config.WhenCapped == WhenCappedStop {
+				return failed(req.ProfileURL, ReasonTemplatesCapped, "all note templates reached their daily cap"), nil
+			}
+		}
+	}
+
+	// Multi-line notes must still fit the limit once newlines are normalized
+	req.Note = utils.NormalizeNoteNewlines(req.Note)
+	if err := utils.ValidateNoteLength(req.Note, cm.config.MaxNoteLength); err != nil {
+		return failed(req.ProfileURL, ReasonError, err.Error()), nil
+	}
+
+	// Let the operator review the request before anything is clicked
+	if cm.confirm != nil {
+		switch cm.confirm(req) {
+		case DecisionSkip:
+			cm.db.MarkProfileProcessed(req.ProfileURL)
+			return failed(req.ProfileURL, ReasonUserSkipped, "skipped at preview"), nil
+		case DecisionQuit:
+			return failed(req.ProfileURL, ReasonUserQuit, "quit at preview"), nil
+		}
 	}
 
+	// Open Profiles can be messaged in the same visit; check before the
+	// invitation modal covers the top card
+	openProfile := cm.config.MessageOpenProfiles && cm.isOpenProfile(page)
+
 	// Find Connect button
 	connectButton, err := cm.findConnectButton(page)
+	cm.logger.Trace(logger.TraceSelector, selectors.ConnectButton, err)
 	if err != nil {
-		return &ConnectionResult{
-			Success:      false,
-			ProfileURL:   req.ProfileURL,
-			ErrorMessage: "Connect button not found - may already be connected",
-		}, nil
+		return failed(req.ProfileURL, ReasonButtonNotFound, "Connect button not found - may already be connected"), nil
 	}
 
 	// Click Connect button with realistic behavior
 	err = cm.clickWithRealism(page, connectButton)
+	cm.logger.Trace(logger.TraceClick, selectors.ConnectButton, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to click connect: %w", err)
 	}
@@ -107,24 +371,63 @@ func (cm *ConnectionManager) SendConnectionRequest(page *rod.Page, req *Connecti
 	// Wait for modal
 	time.Sleep(time.Second)
 
-	// Check if we need to add a note
-	if len(cm.templates) > 0 && req.Note == "" {
-		req.Note = cm.generateNote(req)
+	// The modal may ask for the member's email or announce the weekly limit
+	if reason, ok := cm.detectModalBlocker(page); ok {
+		cm.logger.Info("connection blocked", "profile", req.ProfileURL, "reason", reason)
+		return failed(req.ProfileURL, reason, "connection blocked: "+string(reason)), nil
 	}
 
-	// Send with or without note
-	if req.Note != "" {
-		err = cm.sendWithNote(page, req.Note)
-	} else {
-		err = cm.sendWithoutNote(page)
+	// Branch on what the invitation modal actually offers
+	shape := cm.inspectModal(page)
+	if shape.AI {
+		cm.handleAIPrompt(page, req.ProfileURL)
+	}
+	if shape.Kind == ModalEmailRequired {
+		cm.logger.Info("connection blocked", "profile", req.ProfileURL, "reason", ReasonEmailRequired)
+		return failed(req.ProfileURL, ReasonEmailRequired, "connection blocked: "+string(ReasonEmailRequired)), nil
+	}
+	if dryRun {
+		return cm.rehearseSend(page, req, shape, openProfile), nil
+	}
+	slot, ok := cm.reserveSlot(req.ProfileURL)
+	if !ok {
+		return failed(req.ProfileURL, ReasonDailyLimit, "no connection slot left today"), nil
+	}
+	switch shape.Kind {
+	case ModalSendOnly:
+		if req.Note != "" {
+			cm.logger.Info("modal offers no note option, sending without note", "profile", req.ProfileURL)
+			req.Note = ""
+		}
+		err = cm.sendWithoutNote(page, false)
+	default:
+		// Note available, or unrecognized markup where the note path
+		// falls back on its own
+		if req.Note != "" {
+			err = cm.sendWithNote(page, req.Note, false)
+		} else {
+			err = cm.sendWithoutNote(page, false)
+		}
 	}
 
 	if err != nil {
-		return &ConnectionResult{
-			Success:      false,
-			ProfileURL:   req.ProfileURL,
-			ErrorMessage: err.Error(),
-		}, nil
+		cm.releaseSlot(slot)
+		// The limit notice can take the place of the modal, leaving no
+		// send button to find
+		if reason, ok := cm.detectModalBlocker(page); ok {
+			return failed(req.ProfileURL, reason, "connection blocked: "+string(reason)), nil
+		}
+		return failed(req.ProfileURL, ReasonError, err.Error()), nil
+	}
+
+	// Confirm the invitation actually went out. An unconfirmed send keeps
+	// its slot, since the invitation may have gone out anyway
+	if reason, ok := cm.detectModalBlocker(page); ok {
+		cm.releaseSlot(slot)
+		return failed(req.ProfileURL, reason, "connection blocked: "+string(reason)), nil
+	}
+	if !cm.sendConfirmed(page) {
+		return failed(req.ProfileURL, ReasonSendUnconfirmed, "invitation modal still open after send"), nil
 	}
 
 	// Record in database
@@ -135,59 +438,584 @@ func (cm *ConnectionManager) SendConnectionRequest(page *rod.Page, req *Connecti
 		LastName:   req.LastName,
 		JobTitle:   req.JobTitle,
 		Company:    req.Company,
+		Location:   req.Location,
 		NoteSent:   req.Note,
 		Status:     "pending",
+		PageNumber: req.PageNumber,
+		Position:   req.Position,
 		CreatedAt:  time.Now(),
+
+		SearchCriteriaID: req.CriteriaID,
+		SnapshotSource:   snapshot,
+	}
+	if variant != "" {
+		conn.Variant = VariantTag(cm.config.ABTest.Name, variant)
+	}
+	if err := cm.db.RecordConnectionSent(conn); err != nil {
+		cm.logger.LogError("record connection", err, map[string]interface{}{"profile": req.ProfileURL})
+	}
+	if req.OriginalURL != "" {
+		cm.db.MarkProfileProcessed(req.OriginalURL)
+	}
+	// Language groups are counted under their own kind, which the daily
+	// caps of the regular templates do not see
+	if templateIdx >= 0 && req.Note != "" {
+		cm.db.IncrementTemplateUsage(noteKind(req), templateIdx)
 	}
-	cm.db.SaveConnection(conn)
-	cm.db.IncrementConnectionCount()
-	cm.db.MarkProfileProcessed(req.ProfileURL)
 
-	cm.logger.Info("connection request sent", "profile", req.ProfileURL)
+	cm.logger.Info("connection request sent", "profile", req.ProfileURL, "open_profile", openProfile)
 
 	return &ConnectionResult{
-		Success:    true,
-		ProfileURL: req.ProfileURL,
+		Success:      true,
+		ProfileURL:   req.ProfileURL,
+		ConnectionID: conn.ID,
+		OpenProfile:  openProfile,
 	}, nil
 }
 
-// findConnectButton finds the Connect button on a profile page
-func (cm *ConnectionManager) findConnectButton(page *rod.Page) (*rod.Element, error) {
-	// Try various selectors
-	selectors := []string{
-		`button[aria-label*="Invite"]`,
-		`button[aria-label*="Connect"]`,
-		`button.pvs-profile-actions__action[aria-label*="connect"]`,
-		`.pv-top-card-v2-ctas button:has-text("Connect")`,
-		`button:has-text("Connect")`,
+// handleRedirect compares the loaded profile URL with the requested one and
+// applies the profile_redirects policy when they differ. Under reconcile the
+// stored record moves to the new URL and req continues with it. It returns
+// a result when the profile should not be processed further
+func (cm *ConnectionManager) handleRedirect(page *rod.Page, req *ConnectionRequest) *ConnectionResult {
+	info, err := page.Info()
+	if err != nil {
+		return nil
+	}
+	finalURL, ok := utils.CanonicalProfileURL(info.URL)
+	if !ok {
+		return nil
+	}
+	if requested, ok := utils.CanonicalProfileURL(req.ProfileURL); ok && strings.EqualFold(requested, finalURL) {
+		return nil
 	}
 
-	for _, selector := range selectors {
-		btn, err := page.Timeout(3 * time.Second).Element(selector)
-		if err == nil && btn != nil {
-			// Verify it's visible
-			visible, _ := btn.Visible()
-			if visible {
-				return btn, nil
+	requested := req.ProfileURL
+	cm.logger.Info("profile URL redirected", "from", requested, "to", finalURL, "policy", cm.config.ProfileRedirects)
+
+	if cm.config.ProfileRedirects == RedirectSkip {
+		cm.db.MarkProfileProcessed(requested)
+		return failed(requested, ReasonRedirected, "profile redirected to "+finalURL)
+	}
+
+	processed, err := cm.db.IsProfileProcessed(finalURL)
+	if err != nil {
+		cm.logger.LogError("check redirected profile", err, map[string]interface{}{"profile": finalURL})
+	}
+	if err := cm.db.ReconcileProfileURL(requested, finalURL); err != nil {
+		cm.logger.LogError("reconcile profile URL", err, map[string]interface{}{"from": requested, "to": finalURL})
+	}
+	if processed {
+		cm.db.MarkProfileProcessed(requested)
+		return failed(requested, ReasonDuplicate, "profile already processed as "+finalURL)
+	}
+
+	req.OriginalURL = requested
+	req.ProfileURL = finalURL
+	return nil
+}
+
+// readMutualConnections fills in the request's mutual connection fields from
+// the open profile and stores them with the profile details. Profiles that
+// show no mutual connections are left alone
+func (cm *ConnectionManager) readMutualConnections(page *rod.Page, req *ConnectionRequest) {
+	mutual, err := search.ExtractMutualConnections(page)
+	if err != nil {
+		cm.logger.LogError("extract mutual connections", err, map[string]interface{}{"profile": req.ProfileURL})
+		return
+	}
+	if mutual.Count == 0 {
+		return
+	}
+
+	req.MutualCount = mutual.Count
+	if len(mutual.Names) > 0 {
+		req.MutualName = mutual.Names[0]
+	}
+	cm.logger.Debug("mutual connections", "profile", req.ProfileURL, "count", mutual.Count, "names", strings.Join(mutual.Names, ", "))
+
+	err = cm.db.SaveProfileDetails(&database.ProfileDetails{
+		ProfileURL:  req.ProfileURL,
+		Headline:    req.JobTitle,
+		MutualCount: mutual.Count,
+		MutualNames: mutual.Names,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		cm.logger.LogError("save profile details", err, map[string]interface{}{"profile": req.ProfileURL})
+	}
+}
+
+// readSharedAffiliations fills in the school and group the open profile
+// shares with us, for the {{sharedSchool}} and {{sharedGroup}} variables
+func (cm *ConnectionManager) readSharedAffiliations(page *rod.Page, req *ConnectionRequest) {
+	shared, err := search.ExtractSharedAffiliations(page)
+	if err != nil {
+		cm.logger.LogError("extract shared affiliations", err, map[string]interface{}{"profile": req.ProfileURL})
+		return
+	}
+	req.SharedSchool, req.SharedGroup = shared.School, shared.Group
+	if shared.School != "" || shared.Group != "" {
+		cm.logger.Debug("shared affiliations", "profile", req.ProfileURL, "school", shared.School, "group", shared.Group)
+	}
+}
+
+// passesQualityGate reports whether the open profile meets
+// min_profile_quality. Private profiles follow the private_profiles policy
+func (cm *ConnectionManager) passesQualityGate(page *rod.Page, profileURL string) bool {
+	quality, err := search.AssessProfileQuality(page)
+	if err != nil {
+		cm.logger.LogError("assess profile quality", err, map[string]interface{}{"profile": profileURL})
+		return true
+	}
+
+	cm.logger.Debug("profile quality", "profile", profileURL, "score", quality.Score,
+		"connections", quality.Connections, "positions", quality.Positions,
+		"photo", quality.HasPhoto, "private", quality.Private)
+
+	if quality.Private {
+		return cm.config.PrivateProfiles != "skip"
+	}
+	if quality.Score < cm.config.MinQuality {
+		cm.logger.Info("filtered low-quality profile", "profile", profileURL, "score", quality.Score)
+		return false
+	}
+	return true
+}
+
+// Activity recency modes
+const (
+	ActivityRequireActive = "require_active" // keep members active within the window
+	ActivitySkipInactive  = "skip_inactive"  // drop members whose last activity is older than the window
+)
+
+// checkActivityRecency applies connection.activity_recency to the open
+// profile. require_active wants a dated entry within the window, so
+// members who never posted fail it; skip_inactive only drops members whose
+// latest entry is older than the window. Profiles whose activity section
+// cannot be read follow when_hidden. It returns the reason and message for
+// a filtered profile
+func (cm *ConnectionManager) checkActivityRecency(page *rod.Page, profileURL string) (FailureReason, string, bool) {
+	recency := cm.config.ActivityRecency
+	window := time.Duration(recency.Days) * 24 * time.Hour
+
+	activity, err := search.ReadProfileActivity(page)
+	if err != nil {
+		cm.logger.LogError("read profile activity", err, map[string]interface{}{"profile": profileURL})
+		activity = &search.ProfileActivity{}
+	}
+	cm.logger.Debug("profile activity", "profile", profileURL, "visible", activity.Visible,
+		"posted", activity.Posted, "age", activity.Age.Round(time.Hour))
+
+	if !activity.Visible {
+		if recency.WhenHidden == "skip" {
+			cm.logger.Info("filtered profile with hidden activity", "profile", profileURL)
+			return ReasonActivityHidden, "activity not visible", false
+		}
+		return "", "", true
+	}
+
+	switch {
+	case activity.Posted && activity.Age > window:
+		cm.logger.Info("filtered inactive profile", "profile", profileURL, "last_activity_days", int(activity.Age.Hours()/24))
+		return ReasonInactive, fmt.Sprintf("last active %d days ago, outside %d days", int(activity.Age.Hours()/24), recency.Days), false
+	case !activity.Posted && recency.Mode == ActivityRequireActive:
+		cm.logger.Info("filtered profile with no activity", "profile", profileURL)
+		return ReasonInactive, "no activity shown", false
+	}
+	return "", "", true
+}
+
+// detectProfileState reports profiles that cannot be invited: unavailable
+// profiles, existing 1st-degree connections and pending invitations
+func (cm *ConnectionManager) detectProfileState(page *rod.Page) (FailureReason, bool) {
+	html, err := page.HTML()
+	if err != nil {
+		return "", false
+	}
+	lower := strings.ToLower(html)
+
+	if strings.Contains(lower, "this page doesn’t exist") ||
+		strings.Contains(lower, "this page doesn't exist") ||
+		strings.Contains(lower, "profile is not available") {
+		return ReasonUnavailable, true
+	}
+
+	if has, _, _ := page.Has(`button[aria-label*="Pending"]`); has {
+		return ReasonPending, true
+	}
+	if reason, ok := classifyTopCardActions(cm.topCardActions(page)); ok {
+		return reason, true
+	}
+
+	if degree, err := page.Timeout(time.Second).Element(`.dist-value`); err == nil {
+		if text, _ := degree.Text(); strings.Contains(text, "1st") {
+			return ReasonAlreadyConnected, true
+		}
+	}
+
+	return "", false
+}
+
+// topCardActions returns the labels of the profile top card's action
+// buttons, as shown
+func (cm *ConnectionManager) topCardActions(page *rod.Page) []string {
+	for _, selector := range cm.selectors.Get(selectors.TopCardActions) {
+		buttons, err := page.Elements(selector)
+		if err != nil || len(buttons) == 0 {
+			continue
+		}
+		var labels []string
+		for _, btn := range buttons {
+			if text, err := btn.Text(); err == nil && strings.TrimSpace(text) != "" {
+				labels = append(labels, strings.TrimSpace(text))
 			}
 		}
+		return labels
+	}
+	return nil
+}
+
+// classifyTopCardActions reads the invitation state off the top card's
+// action buttons. Pending means an invitation is out. Message without
+// Follow or Connect is what a 1st-degree connection gets, where an Open
+// Profile offers Message next to Follow or Connect. Following without
+// Connect is a member invited before, whom LinkedIn follows on sending
+func classifyTopCardActions(labels []string) (FailureReason, bool) {
+	has := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		has[strings.ToLower(strings.TrimSpace(label))] = true
+	}
+
+	switch {
+	case has["pending"]:
+		return ReasonPending, true
+	case has["connect"]:
+		return "", false
+	case has["message"] && !has["follow"]:
+		return ReasonAlreadyConnected, true
+	case has["following"]:
+		return ReasonPending, true
+	}
+	return "", false
+}
+
+// recordProfileState brings the connection record of a profile found
+// pending or connected on LinkedIn in line, and keeps the profile from
+// being queued again
+func (cm *ConnectionManager) recordProfileState(profileURL string, reason FailureReason) {
+	status := ""
+	switch reason {
+	case ReasonPending:
+		status = "pending"
+	case ReasonAlreadyConnected:
+		status = "accepted"
+	default:
+		return
+	}
+	if err := cm.db.UpdateConnectionStatus(profileURL, status); err != nil {
+		cm.logger.LogError("update connection status", err, map[string]interface{}{"profile": profileURL})
+	}
+	if err := cm.db.MarkProfileProcessed(profileURL); err != nil {
+		cm.logger.LogError("mark profile processed", err, map[string]interface{}{"profile": profileURL})
+	}
+}
+
+// isOpenProfile reports whether the open profile is an Open Profile: it
+// shows the Open Profile badge and offers Message to a non-connection
+func (cm *ConnectionManager) isOpenProfile(page *rod.Page) bool {
+	html, err := page.HTML()
+	if err != nil {
+		return false
+	}
+	lower := strings.ToLower(html)
+	if !strings.Contains(lower, "open profile") && !strings.Contains(lower, "open-profile") {
+		return false
+	}
+
+	for _, selector := range cm.selectors.Get(selectors.MessageButton) {
+		btn, err := page.Timeout(time.Second).Element(selector)
+		if err != nil || btn == nil {
+			continue
+		}
+		if visible, _ := btn.Visible(); visible {
+			return true
+		}
+	}
+	return false
+}
+
+// detectModalBlocker reports modal states that prevent sending: LinkedIn
+// asking for the member's email, or the weekly invitation limit notice. A
+// weekly limit notice is recorded so later runs can wait for the window
+func (cm *ConnectionManager) detectModalBlocker(page *rod.Page) (FailureReason, bool) {
+	if weeklyLimitShown(page) {
+		cm.recordWeeklyLimit()
+		return ReasonWeeklyLimit, true
+	}
+
+	if has, _, _ := page.Has(`.send-invite input[type="email"], input[name="email"]`); has {
+		return ReasonEmailRequired, true
+	}
+
+	return "", false
+}
+
+// Invitation modal shapes
+const (
+	ModalNoteAvailable = "note-available" // "Add a note" or the note field is offered
+	ModalSendOnly      = "send-only"      // only a send button, no way to add a note
+	ModalEmailRequired = "email-required" // LinkedIn wants the member's email first
+	ModalUnknown       = "unknown"
+)
+
+// ModalShape describes the invitation modal as found on the page
+type ModalShape struct {
+	Kind    string
+	Buttons []string // button labels in the modal, for logging
+	AI      bool     // the modal offers AI-written note suggestions
+}
+
+// AI note prompt handling
+const (
+	AIPromptDismiss = "dismiss" // close the suggestion panel, then write our note
+	AIPromptIgnore  = "ignore"  // leave the panel open and write our note
+)
+
+// modalButtonsScript lists the buttons of the open invitation modal and
+// whether it holds a note field, an email input or AI note suggestions
+const modalButtonsScript = `() => {
+	const modal = document.querySelector('.send-invite, [role="dialog"], .artdeco-modal');
+	if (!modal) return { found: false, buttons: [], noteField: false, email: false, ai: false };
+	const buttons = [...modal.querySelectorAll('button')]
+		.map(b => (b.getAttribute('aria-label') || b.innerText || '').trim())
+		.filter(Boolean);
+	return {
+		found: true,
+		buttons: buttons,
+		noteField: !!modal.querySelector('textarea'),
+		email: !!modal.querySelector('input[type="email"], input[name="email"]'),
+		ai: /(write|personali[sz]e|draft|rewrite) (a note )?with ai|ai[- ]suggested|ai[- ]generated/i.test(modal.innerText),
+	};
+}`
+
+// dismissAIPromptScript clicks the button that declines the AI suggestion
+// and keeps the note manual. The modal's own Dismiss button is left alone,
+// since it closes the whole invitation
+const dismissAIPromptScript = `() => {
+	const modal = document.querySelector('.send-invite, [role="dialog"], .artdeco-modal');
+	if (!modal) return '';
+	const decline = [...modal.querySelectorAll('button')].find(b =>
+		/no thanks|write (it )?(myself|my own)|without ai|skip ai/i.test(b.getAttribute('aria-label') || b.innerText || ''));
+	if (!decline) return '';
+	decline.click();
+	return (decline.getAttribute('aria-label') || decline.innerText || '').trim();
+}`
+
+// manualNoteFieldScript marks the first writable note textarea that is not
+// part of an AI suggestion panel, so typing never lands in the AI field
+const manualNoteFieldScript = `() => {
+	const modal = document.querySelector('.send-invite, [role="dialog"], .artdeco-modal');
+	if (!modal) return false;
+	const aiPattern = /(^|[-_ ])ai([-_ ]|$)|generative|assist/i;
+	const inAIPanel = el => {
+		for (let n = el; n && n !== modal; n = n.parentElement) {
+			const marks = [n.getAttribute('class'), n.getAttribute('aria-label'), n.getAttribute('data-test-id'), n.getAttribute('name'), n.getAttribute('id')].join(' ');
+			if (aiPattern.test(marks)) return true;
+		}
+		return false;
+	};
+	const fields = [...modal.querySelectorAll('textarea')].filter(t => !t.readOnly && !t.disabled && !inAIPanel(t));
+	modal.querySelectorAll('[data-manual-note]').forEach(t => t.removeAttribute('data-manual-note'));
+	if (!fields.length) return false;
+	fields[0].setAttribute('data-manual-note', '1');
+	return true;
+}`
+
+// inspectModal enumerates the buttons in the invitation modal and classifies
+// it, so the send path follows what is actually offered
+func (cm *ConnectionManager) inspectModal(page *rod.Page) ModalShape {
+	res, err := page.Eval(modalButtonsScript)
+	if err != nil {
+		cm.logger.LogError("inspect invitation modal", err, nil)
+		return ModalShape{Kind: ModalUnknown}
+	}
+
+	var buttons []string
+	for _, b := range res.Value.Get("buttons").Arr() {
+		buttons = append(buttons, b.Str())
+	}
+	ai := res.Value.Get("ai").Bool()
+	shape := ModalShape{
+		Kind: classifyModal(res.Value.Get("found").Bool(), buttons,
+			res.Value.Get("noteField").Bool(), res.Value.Get("email").Bool(), ai),
+		Buttons: buttons,
+		AI:      ai,
+	}
+	cm.logger.Info("invitation modal", "shape", shape.Kind, "ai", shape.AI, "buttons", strings.Join(buttons, " | "))
+	return shape
+}
+
+// handleAIPrompt deals with the AI note suggestion variant of the modal.
+// Under the dismiss policy the suggestion is declined so it cannot fill or
+// replace the note; either way the note is typed into the manual field
+func (cm *ConnectionManager) handleAIPrompt(page *rod.Page, profileURL string) {
+	cm.logger.Info("invitation modal offers AI note suggestions", "profile", profileURL, "policy", cm.config.AINotePrompt)
+	if cm.config.AINotePrompt != AIPromptDismiss {
+		return
+	}
+	res, err := page.Eval(dismissAIPromptScript)
+	if err != nil {
+		cm.logger.Debug("could not dismiss AI note prompt", "error", err)
+		return
+	}
+	if label := res.Value.Str(); label != "" {
+		cm.logger.Info("dismissed AI note prompt", "button", label)
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// findNoteField returns the manual note textarea, passing over any field
+// that belongs to an AI suggestion panel, and falls back to the registry
+// selectors when the modal markup is not recognized
+func (cm *ConnectionManager) findNoteField(page *rod.Page, timeout time.Duration) (*rod.Element, error) {
+	if res, err := page.Eval(manualNoteFieldScript); err == nil && res.Value.Bool() {
+		if field, err := page.Timeout(timeout).Element(`[data-manual-note="1"]`); err == nil {
+			return field.CancelTimeout(), nil
+		}
+	}
+	return findFirst(page, cm.selectors.Get(selectors.NoteField), timeout)
+}
+
+// classifyModal decides the modal shape from its buttons and inputs. An AI
+// note prompt means a note can be written even before a field shows
+func classifyModal(found bool, buttons []string, noteField, email, ai bool) string {
+	if !found {
+		return ModalUnknown
+	}
+	if email {
+		return ModalEmailRequired
+	}
+	if noteField || ai {
+		return ModalNoteAvailable
+	}
+
+	canSend := false
+	for _, label := range buttons {
+		lower := strings.ToLower(label)
+		if strings.Contains(lower, "add a note") {
+			return ModalNoteAvailable
+		}
+		if strings.HasPrefix(lower, "send") {
+			canSend = true
+		}
+	}
+	if canSend {
+		return ModalSendOnly
+	}
+	return ModalUnknown
+}
+
+// sendConfirmed checks that the invitation modal closed after sending
+func (cm *ConnectionManager) sendConfirmed(page *rod.Page) bool {
+	for _, selector := range cm.selectors.Get(selectors.SendInvitation) {
+		btn, err := page.Timeout(500 * time.Millisecond).Element(selector)
+		if err != nil || btn == nil {
+			continue
+		}
+		if visible, _ := btn.Visible(); visible {
+			return false
+		}
+	}
+	return true
+}
+
+// Where on the profile the Connect button was found
+const (
+	ConnectAtTopCard  = "top_card"
+	ConnectInMoreMenu = "more_menu"
+	ConnectAtSticky   = "sticky_header"
+)
+
+// topCardScript returns the document offset just below the intro card,
+// where the sticky action bar starts to show
+const topCardScript = `() => {
+	const card = document.querySelector('.pv-top-card, .pv-top-card-v2-ctas, .pvs-profile-actions, main section');
+	const bottom = card ? card.getBoundingClientRect().bottom + window.scrollY : window.innerHeight;
+	return [Math.round(bottom), Math.round(window.scrollY)];
+}`
+
+// findConnectButton finds the Connect button on a profile page. It tries
+// the top card buttons and the More menu, then scrolls past the intro card
+// to reveal the sticky action bar, where some layouts only offer Connect
+func (cm *ConnectionManager) findConnectButton(page *rod.Page) (*rod.Element, error) {
+	// Try the selectors for the detected UI variant
+	if btn := findVisible(page, cm.selectors.Get(selectors.ConnectButton), 3*time.Second); btn != nil {
+		cm.logger.Debug("connect button found", "location", ConnectAtTopCard)
+		return btn, nil
 	}
 
 	// Check for "More" dropdown which might contain Connect
-	moreBtn, err := page.Timeout(2 * time.Second).Element(`button[aria-label="More actions"]`)
+	moreBtn, err := findFirst(page, cm.selectors.Get(selectors.MoreActions), 2*time.Second)
 	if err == nil && moreBtn != nil {
 		moreBtn.Click(proto.InputMouseButtonLeft, 1)
 		time.Sleep(500 * time.Millisecond)
 
-		connectInMenu, err := page.Timeout(2 * time.Second).Element(`div[data-control-name="connect"]`)
+		connectInMenu, err := findFirst(page, cm.selectors.Get(selectors.ConnectInMenu), 2*time.Second)
 		if err == nil && connectInMenu != nil {
+			cm.logger.Debug("connect button found", "location", ConnectInMoreMenu)
 			return connectInMenu, nil
 		}
+
+		// Close the menu before scrolling
+		page.Keyboard.Type(input.Escape)
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	// Scroll below the intro card so the sticky action bar appears
+	if btn := cm.findStickyConnectButton(page); btn != nil {
+		cm.logger.Debug("connect button found", "location", ConnectAtSticky)
+		return btn, nil
 	}
 
 	return nil, fmt.Errorf("connect button not found")
 }
 
+// findStickyConnectButton scrolls past the intro card and looks for Connect
+// in the sticky header
+func (cm *ConnectionManager) findStickyConnectButton(page *rod.Page) *rod.Element {
+	res, err := page.Eval(topCardScript)
+	if err != nil {
+		return nil
+	}
+	target := res.Value.Get("0").Int() + 200
+	current := res.Value.Get("1").Int()
+
+	for _, step := range cm.scroll.GenerateScrollSequence(target, current) {
+		if step.DeltaY != 0 {
+			page.Eval(`(d) => window.scrollBy(0, d)`, step.DeltaY)
+		}
+		time.Sleep(step.Duration)
+	}
+	// The sticky bar animates in once the card is out of view
+	time.Sleep(cm.scroll.GetRandomScrollPause())
+
+	return findVisible(page, cm.selectors.Get(selectors.StickyConnect), 3*time.Second)
+}
+
+// findVisible returns the first visible element matching one of candidates
+func findVisible(page *rod.Page, candidates []string, timeout time.Duration) *rod.Element {
+	for _, selector := range candidates {
+		btn, err := page.Timeout(timeout).Element(selector)
+		if err == nil && btn != nil {
+			if visible, _ := btn.Visible(); visible {
+				return btn.CancelTimeout()
+			}
+		}
+	}
+	return nil
+}
+
 // extractProfileData extracts profile information from the current page
 func (cm *ConnectionManager) extractProfileData(page *rod.Page) (firstName, lastName, jobTitle, company string) {
 	// Try to get name
@@ -220,85 +1048,316 @@ func (cm *ConnectionManager) extractProfileData(page *rod.Page) (firstName, last
 	return
 }
 
-// generateNote generates a personalized connection note
-func (cm *ConnectionManager) generateNote(req *ConnectionRequest) string {
-	if len(cm.templates) == 0 {
+// readProfileLocation returns the location shown under the open profile's
+// headline, or "" when it cannot be read
+func (cm *ConnectionManager) readProfileLocation(page *rod.Page) string {
+	el, err := page.Timeout(2 * time.Second).Element(`.pv-text-details__left-panel .text-body-small.inline.t-black--light.break-words`)
+	if err != nil {
 		return ""
 	}
+	location, _ := el.Text()
+	return strings.TrimSpace(location)
+}
+
+// hasNoteTemplates reports whether notes can be written from the regular
+// templates or a language group
+func (cm *ConnectionManager) hasNoteTemplates() bool {
+	return len(cm.templates) > 0 || cm.config.NoteLanguages.Enabled()
+}
+
+// noteTemplates returns the templates req's note is written from and sets
+// req.Language to the language group chosen. The inferred language's group
+// comes first, then the default language's; the regular templates are the
+// last resort and leave req.Language empty
+func (cm *ConnectionManager) noteTemplates(req *ConnectionRequest) []string {
+	languages := cm.config.NoteLanguages
+	req.Language = ""
+	if !languages.Enabled() {
+		return cm.templates
+	}
+
+	lang, source := utils.InferLanguage(req.Location, req.JobTitle, languages.Locations)
+	if templates, ok := languages.Templates[lang]; ok {
+		cm.logger.Debug("note language inferred", "profile", req.ProfileURL, "language", lang, "source", source)
+		req.Language = lang
+		return templates
+	}
+	if lang != "" {
+		cm.logger.Debug("no templates for inferred language, using the default", "profile", req.ProfileURL, "language", lang, "default", languages.Default)
+	}
+	if templates, ok := languages.Templates[languages.Default]; ok {
+		req.Language = languages.Default
+		return templates
+	}
+	return cm.templates
+}
 
-	// Select template (rotate through templates)
-	template := cm.templates[req.TemplateIdx%len(cm.templates)]
+// generateNote generates a personalized connection note and returns the
+// index of the template used, or -1 when no template is available. When a
+// template renders empty, up to max_empty_note_retries further templates are
+// tried; if all of them are empty too, the note is empty and the request is
+// sent without one. Notes outside the note_length band move on to the next
+// template; when no template fits, the first note is padded or trimmed.
+// With note_languages set, the templates are the recipient's language group
+func (cm *ConnectionManager) generateNote(req *ConnectionRequest) (string, int) {
+	templates := cm.noteTemplates(req)
+	if len(templates) == 0 {
+		return "", -1
+	}
+
+	band := cm.config.NoteLength
+	emptyRetries := cm.config.EmptyNoteRetries
+	fallbackBody, fallbackIdx := "", -1
+	tried := make(map[int]bool)
+
+	// Start from the least used template and rotate, skipping capped ones
+	start := cm.firstTemplate(templates, req)
+	idx := -1
+	for i := 0; i < len(templates); i++ {
+		next := cm.selectTemplate(templates, req.Language == "", start)
+		if next < 0 {
+			return "", -1
+		}
+		if tried[next] {
+			break
+		}
+		idx = next
+		tried[idx] = true
+		start = idx + 1
+
+		body := cm.renderBody(templates[idx], req)
+		if body == "" {
+			cm.logger.Warn("note template rendered empty", "template", idx, "profile", req.ProfileURL)
+			if emptyRetries == 0 {
+				break
+			}
+			emptyRetries--
+			continue
+		}
+		note := utils.SignNote(body, cm.config.Signature, cm.config.MaxNoteLength)
+		if utils.NoteInBand(note, band.Min, band.Max) {
+			return note, idx
+		}
 
-	// Substitute variables
+		cm.logger.Info("note outside length band", "template", idx, "length", utf8.RuneCountInString(note), "min", band.Min, "max", band.Max)
+		if fallbackIdx < 0 {
+			fallbackBody, fallbackIdx = body, idx
+		}
+	}
+
+	if fallbackIdx >= 0 {
+		note, ok := utils.FitNoteLength(fallbackBody, cm.config.Signature, band.Min, band.Max, band.Padding)
+		if !ok {
+			cm.logger.Warn("no template meets the note length band, sending it as is", "template", fallbackIdx,
+				"length", utf8.RuneCountInString(note), "min", band.Min, "max", band.Max)
+		}
+		return note, fallbackIdx
+	}
+
+	cm.logger.Info("no template produced a note, sending without one", "profile", req.ProfileURL)
+	return "", idx
+}
+
+// generateVariantNote renders the A/B test template for the given variant
+func (cm *ConnectionManager) generateVariantNote(req *ConnectionRequest, variant string) string {
+	template := cm.config.ABTest.VariantA
+	if variant == VariantB {
+		template = cm.config.ABTest.VariantB
+	}
+	return cm.renderNote(template, req)
+}
+
+// renderNote renders a note template and signs it, trimming the body so
+// the note fits max_note_length with the signature intact. It returns an
+// empty note when renderBody does
+func (cm *ConnectionManager) renderNote(template string, req *ConnectionRequest) string {
+	body := cm.renderBody(template, req)
+	if body == "" {
+		return ""
+	}
+	return utils.SignNote(body, cm.config.Signature, cm.config.MaxNoteLength)
+}
+
+// renderBody substitutes the profile's variables into a note template and
+// applies the emoji policy. It returns an empty body when the template's
+// profile variables are all missing, since what is left is a bare greeting
+// like "Hi". The greeting and closing are picked after that check, and the
+// length limits apply to the note as rendered with them
+func (cm *ConnectionManager) renderBody(template string, req *ConnectionRequest) string {
 	vars := map[string]string{
-		"firstName": req.FirstName,
-		"lastName":  req.LastName,
-		"jobTitle":  req.JobTitle,
-		"company":   req.Company,
+		"firstName":    req.FirstName,
+		"lastName":     req.LastName,
+		"jobTitle":     req.JobTitle,
+		"company":      req.Company,
+		"mutualCount":  "",
+		"mutualName":   req.MutualName,
+		"sharedSchool": req.SharedSchool,
+		"sharedGroup":  req.SharedGroup,
 	}
+	if req.MutualCount > 0 {
+		vars["mutualCount"] = strconv.Itoa(req.MutualCount)
+	}
+	if allVariablesMissing(template, vars) {
+		return ""
+	}
+	cm.variation.Apply(vars)
 
-	note := stealth.SubstituteTemplate(template, vars)
+	note := utils.RenderTemplate(template, vars)
+	note = utils.ApplyEmojiPolicy(note, cm.emojiLimit)
+	return utils.NormalizeNoteNewlines(note)
+}
 
-	// Enforce character limit
-	if len(note) > cm.config.MaxNoteLength {
-		note = note[:cm.config.MaxNoteLength-3] + "..."
+// allVariablesMissing reports whether template references variables and
+// none of them has a value. A placeholder with a fallback always renders
+// something, so it counts as present
+func allVariablesMissing(template string, vars map[string]string) bool {
+	referenced := false
+	for _, v := range utils.TemplateVariables(template) {
+		value, known := vars[v.Name]
+		if !known {
+			continue
+		}
+		if strings.TrimSpace(value) != "" || v.HasFallback {
+			return false
+		}
+		referenced = true
 	}
+	return referenced
+}
 
-	return note
+// firstTemplate picks the template a note starts from: one of the least
+// used of its kind across runs, so the first profile of a run does not
+// always get the first template
+func (cm *ConnectionManager) firstTemplate(templates []string, req *ConnectionRequest) int {
+	stats, err := cm.db.GetTemplateStats(noteKind(req))
+	if err != nil {
+		cm.logger.LogError("load template stats", err, nil)
+	}
+	return pickBalanced(len(templates), stats, cm.rng)
 }
 
-// sendWithNote sends a connection request with a personalized note
-func (cm *ConnectionManager) sendWithNote(page *rod.Page, note string) error {
+// noteKind is the template usage kind of a request's note templates. Each
+// language group is counted apart from the regular templates
+func noteKind(req *ConnectionRequest) string {
+	if req.Language == "" {
+		return TemplateKindConnection
+	}
+	return TemplateKindConnection + "_" + req.Language
+}
+
+// selectTemplate returns the first of templates at or after start (wrapping)
+// that is still under its daily cap, or -1 if all are capped. Caps apply
+// only when capped is set, since they are parallel to the regular templates
+func (cm *ConnectionManager) selectTemplate(templates []string, capped bool, start int) int {
+	n := len(templates)
+	if !capped {
+		return start % n
+	}
+
+	usage, err := cm.db.GetTemplateUsage(TemplateKindConnection)
+	if err != nil {
+		cm.logger.LogError("load template usage", err, nil)
+	}
+
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if idx < len(cm.config.TemplateCaps) {
+			if limit := cm.config.TemplateCaps[idx]; limit > 0 && usage[idx] >= limit {
+				continue
+			}
+		}
+		return idx
+	}
+	return -1
+}
+
+// rehearseSend is the dry run end of SendConnectionRequest: the modal is
+// filled in as for a real request, down to finding the Send button, then
+// closed. The result reports what would have been sent
+func (cm *ConnectionManager) rehearseSend(page *rod.Page, req *ConnectionRequest, shape ModalShape, openProfile bool) *ConnectionResult {
+	var err error
+	if req.Note != "" && shape.Kind != ModalSendOnly {
+		err = cm.sendWithNote(page, req.Note, true)
+	} else {
+		err = cm.sendWithoutNote(page, true)
+	}
+	page.Keyboard.Type(input.Escape)
+	if err != nil {
+		return failed(req.ProfileURL, ReasonError, err.Error())
+	}
+
+	cm.logger.Info("dry run: connection request not sent", "profile", req.ProfileURL, "note", req.Note, "open_profile", openProfile)
+	return &ConnectionResult{
+		Success:     true,
+		ProfileURL:  req.ProfileURL,
+		OpenProfile: openProfile,
+		DryRun:      true,
+	}
+}
+
+// sendWithNote sends a connection request with a personalized note. With
+// dryRun it stops once the Send button is found
+func (cm *ConnectionManager) sendWithNote(page *rod.Page, note string, dryRun bool) error {
 	// Click "Add a note" button
-	addNoteBtn, err := page.Timeout(5 * time.Second).Element(`button[aria-label="Add a note"]`)
+	addNoteBtn, err := findFirst(page, cm.selectors.Get(selectors.AddNoteButton), 5*time.Second)
 	if err != nil {
 		// Try alternate approach - just find the note field
-		noteField, err := page.Timeout(3 * time.Second).Element(`textarea[name="message"]`)
+		noteField, err := cm.findNoteField(page, 3*time.Second)
 		if err != nil {
 			// No note option available, send without note
-			return cm.sendWithoutNote(page)
+			return cm.sendWithoutNote(page, dryRun)
 		}
-		return cm.typeAndSend(page, noteField, note)
+		return cm.typeAndSend(page, noteField, note, dryRun)
 	}
 
 	addNoteBtn.Click(proto.InputMouseButtonLeft, 1)
 	time.Sleep(500 * time.Millisecond)
 
 	// Find note textarea
-	noteField, err := page.Timeout(3 * time.Second).Element(`textarea[name="message"], textarea#custom-message`)
+	noteField, err := cm.findNoteField(page, 3*time.Second)
 	if err != nil {
 		return fmt.Errorf("note field not found: %w", err)
 	}
 
-	return cm.typeAndSend(page, noteField, note)
+	return cm.typeAndSend(page, noteField, note, dryRun)
 }
 
-// typeAndSend types the note and sends the request
-func (cm *ConnectionManager) typeAndSend(page *rod.Page, noteField *rod.Element, note string) error {
-	// Type note with realistic behavior
-	sequence := cm.typing.GenerateTypingSequence(note)
+// typeAndSend types the note and sends the request, or with dryRun stops
+// short of the click
+func (cm *ConnectionManager) typeAndSend(page *rod.Page, noteField *rod.Element, note string, dryRun bool) error {
+	// Keystrokes sent to an unfocused field are lost and the note goes
+	// out empty, so make sure it has focus, then pause before typing
+	if err := cm.focusNoteField(noteField); err != nil {
+		return err
+	}
+	time.Sleep(cm.timing.GetFocusDelay())
 
-	for _, char := range sequence {
-		if char.IsBurstPause {
-			time.Sleep(char.Delay)
-			continue
-		}
+	if err := cm.typeNote(page, noteField, note); err != nil {
+		return err
+	}
 
-		if char.IsBackspace {
-			noteField.MustInput("\b")
-			time.Sleep(char.Delay)
-			continue
+	// Confirm every character landed before sending
+	expected := utf8.RuneCountInString(note)
+	if typed, ok := cm.typedLength(page, noteField); ok && typed != expected {
+		cm.logger.Warn("note field length does not match note", "expected", expected, "typed", typed)
+		if cm.config.RetypeIncomplete {
+			if err := cm.clearNoteField(page, noteField); err != nil {
+				return fmt.Errorf("failed to clear note field: %w", err)
+			}
+			if err := cm.typeNote(page, noteField, note); err != nil {
+				return err
+			}
+			if typed, ok := cm.typedLength(page, noteField); ok && typed != expected {
+				cm.logger.Warn("note still incomplete after retyping", "expected", expected, "typed", typed)
+			}
 		}
-
-		noteField.MustInput(string(char.Char))
-		time.Sleep(char.Delay)
 	}
 
 	// Think time before sending
 	time.Sleep(cm.timing.GetThinkTime())
 
 	// Click Send button
-	sendBtn, err := page.Timeout(3 * time.Second).Element(`button[aria-label="Send now"], button[aria-label="Send invitation"]`)
+	sendBtn, err := findFirst(page, cm.selectors.Get(selectors.SendInvitation), 3*time.Second)
 	if err != nil {
 		// Try generic send button
 		sendBtn, err = page.Element(`button.ml1[aria-label*="Send"]`)
@@ -306,15 +1365,120 @@ func (cm *ConnectionManager) typeAndSend(page *rod.Page, noteField *rod.Element,
 			return fmt.Errorf("send button not found: %w", err)
 		}
 	}
+	if dryRun {
+		return nil
+	}
 
-	sendBtn.Click(proto.InputMouseButtonLeft, 1)
+	err = sendBtn.Click(proto.InputMouseButtonLeft, 1)
+	cm.logger.Trace(logger.TraceClick, selectors.SendInvitation, err)
+	if err != nil {
+		return fmt.Errorf("failed to click send: %w", err)
+	}
 	time.Sleep(time.Second)
 
 	return nil
 }
 
-// sendWithoutNote sends a connection request without a note
-func (cm *ConnectionManager) sendWithoutNote(page *rod.Page) error {
+// noteFocusRetryDelay is the wait before clicking the note field again
+const noteFocusRetryDelay = 400 * time.Millisecond
+
+// focusNoteField clicks the note field until it is document.activeElement,
+// retrying up to note_focus_retries times while the modal settles
+func (cm *ConnectionManager) focusNoteField(noteField *rod.Element) error {
+	for attempt := 0; attempt <= cm.config.NoteFocusRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(noteFocusRetryDelay)
+		}
+		if err := noteField.Click(proto.InputMouseButtonLeft, 1); err != nil {
+			cm.logger.Debug("note field click failed", "attempt", attempt+1, "error", err)
+			continue
+		}
+		res, err := noteField.Eval(`() => this === document.activeElement`)
+		if err == nil && res.Value.Bool() {
+			if attempt > 0 {
+				cm.logger.Info("note field focus re-established", "attempts", attempt+1)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("note field did not take focus after %d attempts", cm.config.NoteFocusRetries+1)
+}
+
+// counterPattern matches LinkedIn's "typed/limit" note counter
+var counterPattern = regexp.MustCompile(`(\d+)\s*/\s*\d+`)
+
+// typedLength reports how many characters the note field holds, preferring
+// LinkedIn's live character counter and falling back to the textarea value
+func (cm *ConnectionManager) typedLength(page *rod.Page, noteField *rod.Element) (int, bool) {
+	if counter, err := findFirst(page, cm.selectors.Get(selectors.NoteCounter), time.Second); err == nil {
+		if text, err := counter.Text(); err == nil {
+			if m := counterPattern.FindStringSubmatch(text); m != nil {
+				if n, err := strconv.Atoi(m[1]); err == nil {
+					return n, true
+				}
+			}
+		}
+	}
+
+	value, err := noteField.Property("value")
+	if err != nil {
+		return 0, false
+	}
+	return utf8.RuneCountInString(value.String()), true
+}
+
+// clearNoteField selects and deletes whatever is in the note field
+func (cm *ConnectionManager) clearNoteField(page *rod.Page, noteField *rod.Element) error {
+	if err := noteField.SelectAllText(); err != nil {
+		return err
+	}
+	if err := page.Keyboard.Type(input.Backspace); err != nil {
+		return err
+	}
+	time.Sleep(cm.timing.GetActionDelay())
+	return nil
+}
+
+// typeNote types the note into the field with realistic behavior
+func (cm *ConnectionManager) typeNote(page *rod.Page, noteField *rod.Element, note string) error {
+	sequence := cm.typing.GenerateTypingSequence(note)
+
+	for _, char := range sequence {
+		if char.IsBurstPause {
+			time.Sleep(char.Delay)
+			continue
+		}
+
+		if char.IsBackspace {
+			if err := stealth.PressBackspace(page); err != nil {
+				return fmt.Errorf("failed to type backspace: %w", err)
+			}
+			time.Sleep(char.Delay)
+			continue
+		}
+
+		// A bare Enter may submit the invitation early; Shift+Enter inserts
+		// a line break without submitting
+		if char.Char == '\n' {
+			if err := page.KeyActions().Press(input.ShiftLeft).Type(input.Enter).Do(); err != nil {
+				return fmt.Errorf("failed to insert line break: %w", err)
+			}
+			time.Sleep(char.Delay)
+			continue
+		}
+
+		if err := noteField.Input(string(char.Char)); err != nil {
+			return fmt.Errorf("failed to type character: %w", err)
+		}
+		time.Sleep(char.Delay)
+	}
+
+	return nil
+}
+
+// sendWithoutNote sends a connection request without a note, or with
+// dryRun only finds its Send button
+func (cm *ConnectionManager) sendWithoutNote(page *rod.Page, dryRun bool) error {
 	// Click Send button directly
 	selectors := []string{
 		`button[aria-label="Send now"]`,
@@ -326,7 +1490,11 @@ func (cm *ConnectionManager) sendWithoutNote(page *rod.Page) error {
 	for _, selector := range selectors {
 		sendBtn, err := page.Timeout(2 * time.Second).Element(selector)
 		if err == nil && sendBtn != nil {
-			sendBtn.Click(proto.InputMouseButtonLeft, 1)
+			if dryRun {
+				return nil
+			}
+			err = sendBtn.Click(proto.InputMouseButtonLeft, 1)
+			cm.logger.Trace(logger.TraceClick, selector, err)
 			time.Sleep(time.Second)
 			return nil
 		}
@@ -335,7 +1503,8 @@ func (cm *ConnectionManager) sendWithoutNote(page *rod.Page) error {
 	return fmt.Errorf("send button not found")
 }
 
-// clickWithRealism clicks an element with natural mouse movement
+// clickWithRealism clicks an element with natural mouse movement, starting
+// from wherever the shared cursor last was
 func (cm *ConnectionManager) clickWithRealism(page *rod.Page, element *rod.Element) error {
 	// Get element position
 	box, err := element.Shape()
@@ -344,30 +1513,39 @@ func (cm *ConnectionManager) clickWithRealism(page *rod.Page, element *rod.Eleme
 	}
 
 	quads := box.Quads
-	if len(quads) == 0 || len(quads[0]) < 4 {
+	if len(quads) == 0 || len(quads[0]) < 8 {
 		return element.Click(proto.InputMouseButtonLeft, 1)
 	}
 
-	// Calculate center of element
-	centerX := (quads[0][0] + quads[0][2]) / 2
-	centerY := (quads[0][1] + quads[0][5]) / 2
+	// Pick a point inside the element, not its exact center
+	centerX, centerY := cm.bezier.RandomPointInQuad(quads[0])
+
+	document, width, height := utils.PageDocument(page)
+	cm.cursor.Begin(document, width, height)
 
 	// Pre-click hover actions
-	hoverActions := cm.mouse.GeneratePreClickSequence(centerX, centerY, 1920, 1080)
+	hoverActions := cm.mouse.GeneratePreClickSequence(centerX, centerY, int(width), int(height))
 	for _, action := range hoverActions {
-		page.Mouse.MustMoveTo(action.X, action.Y)
+		if err := page.Mouse.MoveTo(proto.Point{X: action.X, Y: action.Y}); err != nil {
+			return fmt.Errorf("failed to move mouse: %w", err)
+		}
+		cm.cursor.MovedTo(action.X, action.Y)
 		time.Sleep(action.Duration)
 	}
 
-	// Generate Bezier path to target
-	path := cm.bezier.GeneratePath(0, 0, centerX, centerY)
+	// Generate Bezier path to target from where the pointer is
+	startX, startY := cm.cursor.CurrentPosition()
+	path := cm.bezier.GeneratePath(startX, startY, centerX, centerY)
 	durations := cm.bezier.GetMovementDurations(len(path), 300*time.Millisecond)
 
 	for i, point := range path {
 		if i > 0 && i-1 < len(durations) {
 			time.Sleep(durations[i-1])
 		}
-		page.Mouse.MustMoveTo(point.X, point.Y)
+		if err := page.Mouse.MoveTo(proto.Point{X: point.X, Y: point.Y}); err != nil {
+			return fmt.Errorf("failed to move mouse: %w", err)
+		}
+		cm.cursor.MovedTo(point.X, point.Y)
 	}
 
 	// Small hover delay
@@ -376,13 +1554,109 @@ func (cm *ConnectionManager) clickWithRealism(page *rod.Page, element *rod.Eleme
 	return page.Mouse.Click(proto.InputMouseButtonLeft, 1)
 }
 
-// CanSendMoreToday checks if we can send more connection requests today
-func (cm *ConnectionManager) CanSendMoreToday() (bool, int, error) {
+// findFirst returns the first element matching any of the selectors
+func findFirst(page *rod.Page, candidates []string, timeout time.Duration) (*rod.Element, error) {
+	for _, selector := range candidates {
+		el, err := page.Timeout(timeout).Element(selector)
+		if err == nil && el != nil {
+			// Detach from the lookup timeout so later actions on the
+			// element are not cut short by it
+			return el.CancelTimeout(), nil
+		}
+	}
+	return nil, fmt.Errorf("no element matched %d selectors", len(candidates))
+}
+
+// reserveSlot takes one of today's connection slots before an invitation
+// goes out, returning the date it was counted on for releaseSlot. It fails
+// closed: a database error refuses the slot
+func (cm *ConnectionManager) reserveSlot(profileURL string) (string, bool) {
+	allowance, err := cm.ConnectionAllowance()
+	if err != nil {
+		cm.logger.LogError("reserve connection slot", err, map[string]interface{}{"profile": profileURL})
+		return "", false
+	}
+	// Today's counter may reach the ceiling left by the tightest limit
+	date, ok, err := cm.db.ReserveConnectionSlot(allowance.SentToday + allowance.Remaining)
+	if err != nil {
+		cm.logger.LogError("reserve connection slot", err, map[string]interface{}{"profile": profileURL})
+		return "", false
+	}
+	if !ok {
+		cm.logger.Info("connection limit reached", "profile", profileURL, "limit", allowance.Binding)
+	}
+	return date, ok
+}
+
+// releaseSlot gives back a slot reserved on date whose invitation did not
+// go out
+func (cm *ConnectionManager) releaseSlot(date string) {
+	if err := cm.db.ReleaseConnectionSlot(date); err != nil {
+		cm.logger.LogError("release connection slot", err, map[string]interface{}{"date": date})
+	}
+}
+
+// Connection limits that can bind today's sending
+const (
+	BindingDaily   = "daily_limit"
+	BindingWeekly  = "weekly_limit"
+	BindingMonthly = "monthly_limit"
+)
+
+// ConnectionAllowance is how many invitations can still go out today
+// under the daily, weekly and monthly limits together
+type ConnectionAllowance struct {
+	Remaining int    // left under the tightest limit, never negative
+	Binding   string // the limit Remaining comes from
+	SentToday int
+}
+
+// ConnectionAllowance works out today's remaining invitations. The daily
+// limit counts today's reserved slots; the weekly and monthly limits count
+// sent invitations over rolling 7 and 30 days
+func (cm *ConnectionManager) ConnectionAllowance() (*ConnectionAllowance, error) {
 	activity, err := cm.db.GetOrCreateDailyActivity()
 	if err != nil {
-		return false, 0, err
+		return nil, err
 	}
 
-	remaining := cm.config.DailyLimit - activity.ConnectionsSent
-	return remaining > 0, remaining, nil
+	// The ceiling moves by up to daily_limit_jitter_percent from day to day
+	a := &ConnectionAllowance{
+		Remaining: cm.config.EffectiveDailyLimit(time.Now()) - activity.ConnectionsSent,
+		Binding:   BindingDaily,
+		SentToday: activity.ConnectionsSent,
+	}
+	for _, limit := range []struct {
+		binding string
+		limit   int
+		count   func() (int, error)
+	}{
+		{BindingWeekly, cm.config.WeeklyLimit, cm.db.GetWeeklyConnectionCount},
+		{BindingMonthly, cm.config.MonthlyLimit, cm.db.GetMonthlyConnectionCount},
+	} {
+		if limit.limit <= 0 {
+			continue
+		}
+		sent, err := limit.count()
+		if err != nil {
+			return nil, err
+		}
+		if left := limit.limit - sent; left < a.Remaining {
+			a.Remaining, a.Binding = left, limit.binding
+		}
+	}
+	if a.Remaining < 0 {
+		a.Remaining = 0
+	}
+	return a, nil
+}
+
+// CanSendMoreToday checks if we can send more connection requests today,
+// under whichever of the daily, weekly and monthly limits is tightest
+func (cm *ConnectionManager) CanSendMoreToday() (bool, int, error) {
+	a, err := cm.ConnectionAllowance()
+	if err != nil {
+		return false, 0, err
+	}
+	return a.Remaining > 0, a.Remaining, nil
 }
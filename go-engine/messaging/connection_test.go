@@ -0,0 +1,194 @@
+package messaging
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"linkedin-automation/config"
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/utils"
+)
+
+var buttonPattern = regexp.MustCompile(`(?s)<button[^>]*>(.*?)</button>`)
+
+// buttonLabels returns the text of each button in a captured top card,
+// trimmed the way topCardActions reads it
+func buttonLabels(card string) []string {
+	var labels []string
+	for _, m := range buttonPattern.FindAllStringSubmatch(card, -1) {
+		if text := strings.TrimSpace(innerText(m[1])); text != "" {
+			labels = append(labels, strings.Join(strings.Fields(text), " "))
+		}
+	}
+	return labels
+}
+
+// Top cards as LinkedIn renders them, trimmed to the action buttons
+const (
+	cardConnect = `<div class="pvs-profile-actions">
+		<button aria-label="Invite Jane Doe to connect" class="artdeco-button artdeco-button--primary"><span class="artdeco-button__text">Connect</span></button>
+		<button aria-label="Follow Jane Doe" class="artdeco-button artdeco-button--secondary"><span class="artdeco-button__text">Follow</span></button>
+		<button aria-label="More actions" class="artdeco-dropdown__trigger"><span class="artdeco-button__text">More</span></button>
+	</div>`
+	cardPending = `<div class="pvs-profile-actions">
+		<button aria-label="Pending, click to withdraw invitation sent to Jane Doe" class="artdeco-button artdeco-button--secondary"><span class="artdeco-button__text">
+			Pending
+		</span></button>
+		<button aria-label="More actions" class="artdeco-dropdown__trigger"><span class="artdeco-button__text">More</span></button>
+	</div>`
+	cardConnected = `<div class="pv-top-card-v2-ctas">
+		<button aria-label="Message Jane Doe" class="artdeco-button artdeco-button--primary"><span class="artdeco-button__text">Message</span></button>
+		<button aria-label="More actions" class="artdeco-dropdown__trigger"><span class="artdeco-button__text">More</span></button>
+	</div>`
+	cardOpenProfile = `<div class="pvs-profile-actions">
+		<button aria-label="Message Jane Doe" class="artdeco-button artdeco-button--primary"><span class="artdeco-button__text">Message</span></button>
+		<button aria-label="Follow Jane Doe" class="artdeco-button artdeco-button--secondary"><span class="artdeco-button__text">Follow</span></button>
+		<button aria-label="More actions" class="artdeco-dropdown__trigger"><span class="artdeco-button__text">More</span></button>
+	</div>`
+	cardFollowing = `<div class="pvs-profile-actions">
+		<button aria-label="Following Jane Doe" class="artdeco-button artdeco-button--secondary"><svg aria-hidden="true"></svg><span class="artdeco-button__text">Following</span></button>
+		<button aria-label="More actions" class="artdeco-dropdown__trigger"><span class="artdeco-button__text">More</span></button>
+	</div>`
+	cardCreator = `<div class="pvs-profile-actions">
+		<button aria-label="Follow Jane Doe" class="artdeco-button artdeco-button--primary"><span class="artdeco-button__text">Follow</span></button>
+		<button aria-label="More actions" class="artdeco-dropdown__trigger"><span class="artdeco-button__text">More</span></button>
+	</div>`
+)
+
+func TestClassifyTopCardActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		card   string
+		reason FailureReason
+		ok     bool
+	}{
+		{"connect offered", cardConnect, "", false},
+		{"invitation pending", cardPending, ReasonPending, true},
+		{"1st degree", cardConnected, ReasonAlreadyConnected, true},
+		{"open profile", cardOpenProfile, "", false},
+		{"followed on an earlier invitation", cardFollowing, ReasonPending, true},
+		{"connect behind More", cardCreator, "", false},
+		{"no top card", ``, "", false},
+	}
+	for _, tt := range tests {
+		reason, ok := classifyTopCardActions(buttonLabels(tt.card))
+		if reason != tt.reason || ok != tt.ok {
+			t.Errorf("%s: classifyTopCardActions(%q) = %q, %v, want %q, %v",
+				tt.name, buttonLabels(tt.card), reason, ok, tt.reason, tt.ok)
+		}
+	}
+}
+
+// newTestStore opens a fresh database and a logger for managers under test
+func newTestStore(t *testing.T) (*database.DB, *logger.Logger) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	log, err := logger.New("error", "text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, log
+}
+
+// newTestConnectionManager returns a ConnectionManager over a fresh
+// database that can pick and render notes without a browser
+func newTestConnectionManager(t *testing.T, cfg config.ConnectionConfig) *ConnectionManager {
+	t.Helper()
+	db, log := newTestStore(t)
+	emojiLimit, err := utils.ParseEmojiPolicy(cfg.EmojiPolicy)
+	if err != nil {
+		emojiLimit = -1
+	}
+	return &ConnectionManager{
+		config:     cfg,
+		db:         db,
+		logger:     log,
+		templates:  cfg.Templates,
+		emojiLimit: emojiLimit,
+		rng:        utils.NewRand("templates"),
+	}
+}
+
+func TestRenderNoteMultiLine(t *testing.T) {
+	template := "Hi {{firstName}},\r\n\r\n\r\nYour work as a {{jobTitle}}   \r\ncaught my eye. Would be great to connect!"
+	req := &ConnectionRequest{FirstName: "Zoë", JobTitle: "data engineer"}
+	want := "Hi Zoë,\n\nYour work as a data engineer\ncaught my eye. Would be great to connect!"
+
+	cm := newTestConnectionManager(t, config.ConnectionConfig{MaxNoteLength: 300})
+	note := cm.renderNote(template, req)
+	if note != want {
+		t.Fatalf("renderNote = %q, want %q", note, want)
+	}
+	// every line break counts as one character
+	n := utf8.RuneCountInString(want)
+	if err := utils.ValidateNoteLength(note, n); err != nil {
+		t.Errorf("ValidateNoteLength at %d characters: %v", n, err)
+	}
+	if err := utils.ValidateNoteLength(note, n-1); err == nil {
+		t.Errorf("ValidateNoteLength at %d characters accepted a %d character note", n-1, n)
+	}
+
+	// too long for the limit, the note is cut at a line break or space
+	cm = newTestConnectionManager(t, config.ConnectionConfig{MaxNoteLength: 40})
+	note = cm.renderNote(template, req)
+	if got := utf8.RuneCountInString(note); got > 40 {
+		t.Errorf("renderNote with max 40 = %q, %d characters", note, got)
+	}
+	if note != "Hi Zoë,\n\nYour work as a data engineer…" {
+		t.Errorf("renderNote with max 40 = %q", note)
+	}
+}
+
+func TestAllVariablesMissing(t *testing.T) {
+	vars := map[string]string{"firstName": "", "company": "Acme", "jobTitle": " "}
+	tests := []struct {
+		template string
+		want     bool
+	}{
+		{"Hi {{firstName}}", true},
+		{"{{greeting}} {{firstName}}", true},
+		{"Hi {{firstName}}, {{jobTitle}} here", true},
+		{"Hi {{firstName}} at {{company}}", false},
+		{"Hi {{firstName|there}}", false},
+		{"Hi there", false},
+	}
+	for _, tt := range tests {
+		if got := allVariablesMissing(tt.template, vars); got != tt.want {
+			t.Errorf("allVariablesMissing(%q) = %v, want %v", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateNoteWithoutFirstName(t *testing.T) {
+	req := &ConnectionRequest{ProfileURL: "https://www.linkedin.com/in/jane/"}
+
+	// A bare greeting is not sent: the request goes without a note
+	cm := newTestConnectionManager(t, config.ConnectionConfig{MaxNoteLength: 300, Templates: []string{"Hi {{firstName}}"}})
+	cm.variation = NewVariation([]string{"Hey"}, nil)
+	if body := cm.renderBody("{{greeting}} {{firstName}}", req); body != "" {
+		t.Errorf("renderBody without a first name = %q, want empty", body)
+	}
+	if note, _ := cm.generateNote(req); note != "" {
+		t.Errorf("generateNote with one template = %q, want no note", note)
+	}
+
+	// Templates that render empty are passed over for one that does not
+	templates := []string{"Hi {{firstName}}", "{{greeting}} {{firstName}}!", "Hello {{firstName|there}}, I enjoyed your post."}
+	cm = newTestConnectionManager(t, config.ConnectionConfig{MaxNoteLength: 300, Templates: templates, EmptyNoteRetries: 2})
+	cm.variation = NewVariation([]string{"Hey"}, nil)
+	note, idx := cm.generateNote(req)
+	if note != "Hello there, I enjoyed your post." || idx != 2 {
+		t.Errorf("generateNote = %q from template %d, want the fallback template's note", note, idx)
+	}
+}
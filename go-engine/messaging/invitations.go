@@ -0,0 +1,242 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/utils"
+)
+
+// SnapshotInvitationCard marks connections accepted from the received
+// invitations page; their title is the one shown on the invitation card
+const SnapshotInvitationCard = "invitation_card"
+
+// Incoming invitation filters
+const (
+	InvitationFilterAll      = "all"       // accept every invitation
+	InvitationFilterWithNote = "with_note" // accept invitations that came with a note
+	InvitationFilterMatching = "matching"  // accept when the occupation matches the suggestion filter
+)
+
+// invitationCardsScript lists the received invitations with the inviter's
+// profile link, name, occupation, note and whether the card offers Accept
+const invitationCardsScript = `() => {
+	const cards = document.querySelectorAll('li.invitation-card, .invitation-card, [data-view-name="pending-invitation"]');
+	return [...cards].map((card, i) => {
+		card.setAttribute('data-invitation-index', String(i));
+		const link = card.querySelector('a[href*="/in/"]');
+		const name = card.querySelector('.invitation-card__title, .artdeco-entity-lockup__title, strong');
+		const occupation = card.querySelector('.invitation-card__subtitle, .artdeco-entity-lockup__subtitle');
+		const note = card.querySelector('.invitation-card__custom-message, [class*="custom-message"]');
+		const accept = [...card.querySelectorAll('button')].find(b =>
+			/accept/i.test(b.getAttribute('aria-label') || b.innerText || ''));
+		return {
+			index: i,
+			url: link ? link.href : '',
+			name: name ? name.innerText.trim() : '',
+			occupation: occupation ? occupation.innerText.trim() : '',
+			note: note ? note.innerText.trim() : '',
+			accept: !!accept,
+		};
+	});
+}`
+
+// Invitation is one received connection invitation
+type Invitation struct {
+	ProfileURL string
+	FirstName  string
+	LastName   string
+	Occupation string
+	Note       string // message sent with the invitation, empty when none
+	index      int    // data-invitation-index of the card
+}
+
+// InvitationsResult summarizes one pass over the received invitations
+type InvitationsResult struct {
+	Found    int // invitations offering Accept
+	Accepted []Invitation
+	Skipped  int // invitations left alone by the filter
+}
+
+// CanAcceptMoreToday reports whether more incoming invitations may be
+// accepted today under connection.accept_incoming.daily_limit, and how many
+func (cm *ConnectionManager) CanAcceptMoreToday() (bool, int, error) {
+	activity, err := cm.db.GetOrCreateDailyActivity()
+	if err != nil {
+		return false, 0, err
+	}
+
+	remaining := cm.config.AcceptIncoming.DailyLimit - activity.InvitationsAccepted
+	return remaining > 0, remaining, nil
+}
+
+// acceptsInvitation applies connection.accept_incoming.filter. The
+// matching filter reuses the occupation terms of the suggestion filter
+func (cm *ConnectionManager) acceptsInvitation(inv Invitation) bool {
+	switch cm.config.AcceptIncoming.Filter {
+	case InvitationFilterWithNote:
+		return inv.Note != ""
+	case InvitationFilterMatching:
+		return cm.matchesSuggestionFilter(inv.Occupation)
+	}
+	return true
+}
+
+// AcceptIncomingInvitations opens the received invitations page and
+// accepts up to max invitations that pass the filter, within the daily
+// limit. Accepted invitations are recorded as accepted connections. An
+// empty invitations page is not an error
+func (cm *ConnectionManager) AcceptIncomingInvitations(page *rod.Page, max int) (_ *InvitationsResult, err error) {
+	defer utils.RecoverAsError(&err)
+
+	invitationsURL := cm.site.URL("/mynetwork/invitation-manager/")
+	time.Sleep(cm.timing.GetPreNavigationDelay())
+	err = utils.RetryWithBackoff(cm.retry, func() error {
+		cm.pacer.Wait()
+		err := page.Navigate(invitationsURL)
+		cm.logger.Trace(logger.TraceNavigate, invitationsURL, err)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate: %w", err)
+	}
+	time.Sleep(cm.timing.GetPageLoadDelay())
+	if err := utils.WaitLoadBounded(page, cm.site.PageLoadTimeout, cm.logger); err != nil {
+		cm.logger.LogError("page load", err, nil)
+	}
+	time.Sleep(cm.timing.GetThinkTime())
+
+	invitations, err := cm.readInvitations(page)
+	if err != nil {
+		return nil, err
+	}
+	result := &InvitationsResult{Found: len(invitations)}
+	if len(invitations) == 0 {
+		cm.logger.Info("no pending invitations")
+		return result, nil
+	}
+	cm.logger.Info("received invitations", "count", len(invitations), "filter", cm.config.AcceptIncoming.Filter)
+
+	for _, inv := range invitations {
+		if len(result.Accepted) >= max || page.GetContext().Err() != nil {
+			break
+		}
+		if canAccept, _, _ := cm.CanAcceptMoreToday(); !canAccept {
+			cm.logger.Info("daily accepted invitation limit reached")
+			break
+		}
+		if !cm.acceptsInvitation(inv) {
+			cm.logger.Debug("invitation filtered out", "profile", inv.ProfileURL, "occupation", inv.Occupation)
+			result.Skipped++
+			continue
+		}
+
+		if err := cm.acceptInvitation(page, inv); err != nil {
+			cm.logger.LogError("accept invitation", err, map[string]interface{}{"profile": inv.ProfileURL})
+			continue
+		}
+		result.Accepted = append(result.Accepted, inv)
+		time.Sleep(cm.timing.GetActionDelay())
+	}
+
+	return result, nil
+}
+
+// readInvitations reads the received invitation cards that offer Accept
+func (cm *ConnectionManager) readInvitations(page *rod.Page) ([]Invitation, error) {
+	res, err := page.Eval(invitationCardsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read invitations: %w", err)
+	}
+
+	var invitations []Invitation
+	seen := make(map[string]bool)
+	for _, card := range res.Value.Arr() {
+		if !card.Get("accept").Bool() {
+			continue
+		}
+		profileURL, ok := utils.CanonicalProfileURL(card.Get("url").Str())
+		if !ok || seen[profileURL] {
+			continue
+		}
+		seen[profileURL] = true
+
+		inv := Invitation{
+			ProfileURL: profileURL,
+			Occupation: card.Get("occupation").Str(),
+			Note:       card.Get("note").Str(),
+			index:      card.Get("index").Int(),
+		}
+		parts := strings.Fields(card.Get("name").Str())
+		if len(parts) >= 1 {
+			inv.FirstName = parts[0]
+		}
+		if len(parts) >= 2 {
+			inv.LastName = strings.Join(parts[1:], " ")
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, nil
+}
+
+// acceptInvitation clicks the Accept button of one card and records the
+// connection once the button is gone
+func (cm *ConnectionManager) acceptInvitation(page *rod.Page, inv Invitation) error {
+	selector := fmt.Sprintf(`[data-invitation-index="%d"] button`, inv.index)
+	buttons, err := page.Timeout(3 * time.Second).Elements(selector)
+	if err != nil {
+		return fmt.Errorf("invitation card no longer on the page")
+	}
+
+	var accept *rod.Element
+	for _, btn := range buttons {
+		label, _ := btn.Attribute("aria-label")
+		text, _ := btn.Text()
+		lower := strings.ToLower(text)
+		if label != nil {
+			lower += " " + strings.ToLower(*label)
+		}
+		if strings.Contains(lower, "accept") {
+			accept = btn.CancelTimeout()
+			break
+		}
+	}
+	if accept == nil {
+		return fmt.Errorf("no Accept button on invitation card")
+	}
+
+	accept.ScrollIntoView()
+	time.Sleep(cm.timing.GetThinkTime())
+	err = cm.clickWithRealism(page, accept)
+	cm.logger.Trace(logger.TraceClick, selector, err)
+	if err != nil {
+		return err
+	}
+	time.Sleep(time.Second)
+
+	// The card is replaced by a confirmation or removed once accepted
+	stillOpen, _, _ := page.Has(fmt.Sprintf(`[data-invitation-index="%d"] button[aria-label*="Accept"]`, inv.index))
+	if stillOpen {
+		return fmt.Errorf("invitation still offers Accept after the click")
+	}
+
+	conn := &database.Connection{
+		ID:             fmt.Sprintf("conn_%d", time.Now().UnixNano()),
+		ProfileURL:     inv.ProfileURL,
+		FirstName:      inv.FirstName,
+		LastName:       inv.LastName,
+		JobTitle:       inv.Occupation,
+		CreatedAt:      time.Now(),
+		SnapshotSource: SnapshotInvitationCard,
+	}
+	if err := cm.db.RecordInvitationAccepted(conn); err != nil {
+		cm.logger.LogError("record accepted invitation", err, map[string]interface{}{"profile": inv.ProfileURL})
+	}
+	cm.logger.Info("incoming invitation accepted", "profile", inv.ProfileURL, "with_note", inv.Note != "")
+	return nil
+}
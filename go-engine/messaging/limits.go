@@ -0,0 +1,120 @@
+package messaging
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/database"
+)
+
+// LimitWeekly is the limit hit kind for LinkedIn's weekly invitation limit
+const LimitWeekly = "weekly"
+
+// LimitThrottle is the limit hit kind for throttle responses (429, 999 or
+// an exhausted rate-limit header) seen on LinkedIn's own requests
+const LimitThrottle = "throttle"
+
+// weeklyLimitNotices are the wordings LinkedIn has used for the weekly
+// invitation limit modal and banner, lower-cased with plain apostrophes
+var weeklyLimitNotices = []string{
+	"weekly invitation limit",           // "You've reached the weekly invitation limit"
+	"weekly limit for invitations",      // "You've reached the weekly limit for invitations"
+	"invitation limit for the week",     // "You've hit your invitation limit for the week"
+	"out of invitations for the week",   // "You're out of invitations for the week"
+	"reached the limit for invitations", // "You've reached the limit for invitations this week"
+}
+
+// weeklyLimitScript returns the text of the open dialogs, alerts, toasts
+// and banners, and whether LinkedIn's limit alert modal is among them
+const weeklyLimitScript = `() => {
+	const notices = document.querySelectorAll('[role="dialog"], [role="alertdialog"], [role="alert"], .artdeco-modal, .artdeco-toast-item, .artdeco-inline-feedback, .ip-fuse-limit-alert, [class*="limit-alert"]');
+	return {
+		alert: !!document.querySelector('.ip-fuse-limit-alert'),
+		text: [...notices].map(el => el.innerText || el.textContent || '').join('\n'),
+	};
+}`
+
+// IsWeeklyLimitNotice reports whether text, from a modal or banner, is
+// LinkedIn's weekly invitation limit notice in any of its known wordings
+func IsWeeklyLimitNotice(text string) bool {
+	text = strings.NewReplacer("\u2019", "'", "\u00a0", " ").Replace(strings.ToLower(text))
+	text = strings.Join(strings.Fields(text), " ")
+	for _, notice := range weeklyLimitNotices {
+		if strings.Contains(text, notice) {
+			return true
+		}
+	}
+	return false
+}
+
+// weeklyLimitShown reports whether the page shows the weekly invitation
+// limit modal or banner
+func weeklyLimitShown(page *rod.Page) bool {
+	res, err := page.Timeout(5 * time.Second).Eval(weeklyLimitScript)
+	if err != nil {
+		return false
+	}
+	return res.Value.Get("alert").Bool() || IsWeeklyLimitNotice(res.Value.Get("text").Str())
+}
+
+// weeklyWindow is the rolling window LinkedIn's weekly limit counts over
+const weeklyWindow = 7 * 24 * time.Hour
+
+// NextInvitationTime estimates when the rolling weekly window frees up room
+// again after hit. LinkedIn refused invitations with hit.SentInWindow sent
+// in the window, so that count is taken as the limit: sending resumes once
+// the invitations in the window drop below it. sent holds the send times in
+// the current window, oldest first
+func NextInvitationTime(hit *database.LimitHit, sent []time.Time) time.Time {
+	if hit.SentInWindow <= 0 {
+		// Nothing recorded to age out; wait a full window from the hit
+		return hit.DetectedAt.Add(weeklyWindow)
+	}
+	if len(sent) < hit.SentInWindow {
+		return time.Time{}
+	}
+	return sent[len(sent)-hit.SentInWindow].Add(weeklyWindow)
+}
+
+// recordWeeklyLimit stores that the weekly limit was hit, along with how
+// many invitations were sent in the window at the time
+func (cm *ConnectionManager) recordWeeklyLimit() {
+	now := time.Now()
+	sent, err := cm.db.GetConnectionsSentSince(now.Add(-weeklyWindow))
+	if err != nil {
+		cm.logger.LogError("count connections in weekly window", err, nil)
+	}
+	err = cm.db.RecordLimitHit(&database.LimitHit{
+		Kind:         LimitWeekly,
+		SentInWindow: len(sent),
+		DetectedAt:   now,
+	})
+	if err != nil {
+		cm.logger.LogError("record weekly limit", err, nil)
+		return
+	}
+	cm.logger.Warn("weekly invitation limit hit", "sent_in_window", len(sent))
+}
+
+// WeeklyLimitStatus reports whether the last weekly limit hit still blocks
+// invitations and, if so, the estimated time sending can resume
+func (cm *ConnectionManager) WeeklyLimitStatus() (bool, time.Time, error) {
+	hit, err := cm.db.GetLastLimitHit(LimitWeekly)
+	if err != nil || hit == nil {
+		return false, time.Time{}, err
+	}
+
+	now := time.Now()
+	sent, err := cm.db.GetConnectionsSentSince(now.Add(-weeklyWindow))
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	next := NextInvitationTime(hit, sent)
+	if next.After(now) {
+		return true, next, nil
+	}
+	return false, time.Time{}, nil
+}
@@ -0,0 +1,60 @@
+package messaging
+
+import (
+	"html"
+	"regexp"
+	"testing"
+	"time"
+
+	"linkedin-automation/database"
+)
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// innerText approximates what weeklyLimitScript reads off a notice
+func innerText(fragment string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(fragment, " "))
+}
+
+func TestIsWeeklyLimitNotice(t *testing.T) {
+	tests := []struct {
+		name, html string
+		want       bool
+	}{
+		{"modal", `<div role="dialog" class="artdeco-modal"><h2 id="ip-fuse-limit-alert__header">You&#8217;ve reached the weekly invitation limit</h2><p>Please try again next week.</p></div>`, true},
+		{"limit for invitations", `<div role="alertdialog"><h2>You&rsquo;ve reached the weekly limit for invitations</h2></div>`, true},
+		{"toast", `<div class="artdeco-toast-item"><p>You've hit your invitation&nbsp;limit for the week.</p></div>`, true},
+		{"out of invitations", `<div role="alert"><span>You're out of</span>
+			<span>invitations for the week</span></div>`, true},
+		{"this week", `<section class="ip-fuse-limit-alert"><h2>YOU'VE REACHED THE LIMIT FOR INVITATIONS THIS WEEK</h2></section>`, true},
+		{"note modal", `<div role="dialog"><h2>Add a note to your invitation?</h2><button>Send without a note</button></div>`, false},
+		{"email modal", `<div role="dialog"><label>To verify this member knows you, please enter their email</label></div>`, false},
+		{"weekly digest", `<div role="alert">Your weekly invitation summary is ready</div>`, false},
+	}
+	for _, tt := range tests {
+		if got := IsWeeklyLimitNotice(innerText(tt.html)); got != tt.want {
+			t.Errorf("%s: IsWeeklyLimitNotice = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextInvitationTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	sent := []time.Time{now.Add(-6 * 24 * time.Hour), now.Add(-3 * 24 * time.Hour), now.Add(-time.Hour)}
+
+	tests := []struct {
+		name string
+		hit  database.LimitHit
+		sent []time.Time
+		want time.Time
+	}{
+		{"nothing recorded waits a window", database.LimitHit{DetectedAt: now}, sent, now.Add(weeklyWindow)},
+		{"oldest of the limit ages out", database.LimitHit{SentInWindow: 2, DetectedAt: now}, sent, sent[1].Add(weeklyWindow)},
+		{"already under the limit", database.LimitHit{SentInWindow: 5, DetectedAt: now}, sent, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := NextInvitationTime(&tt.hit, tt.sent); !got.Equal(tt.want) {
+			t.Errorf("%s: NextInvitationTime = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
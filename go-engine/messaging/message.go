@@ -2,34 +2,68 @@ package messaging
 
 import (
 	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
 	"github.com/go-rod/rod/lib/proto"
 
 	"linkedin-automation/config"
 	"linkedin-automation/database"
 	"linkedin-automation/logger"
+	"linkedin-automation/selectors"
 	"linkedin-automation/stealth"
+	"linkedin-automation/utils"
 )
 
+// Link preview policies
+const (
+	LinkPreviewKeep   = "keep"
+	LinkPreviewRemove = "remove"
+	LinkPreviewIgnore = "ignore"
+)
+
+// linkPreviewTimeout bounds how long we wait for LinkedIn to render a preview
+const linkPreviewTimeout = 5 * time.Second
+
+// maxMessageLength is the longest message LinkedIn's composer accepts
+const maxMessageLength = 8000
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
 // MessageManager handles sending follow-up messages
 type MessageManager struct {
 	config    config.MessagingConfig
-	db        *database.DB
+	db        database.Store
 	logger    *logger.Logger
 	timing    *stealth.TimingController
 	typing    *stealth.TypingSimulator
+	scroll    *stealth.ScrollController
+	bezier    *stealth.BezierMouse
+	pacer     *stealth.NavigationPacer
+	cursor    *stealth.Cursor
+	selectors *selectors.Registry
+	site      config.LinkedInConfig
 	templates []string
+	retry     utils.RetryConfig
+	variation *Variation // {{greeting}} and {{closing}} pools; nil renders them empty
+	rng       *rand.Rand // picks among the least used templates
 }
 
 // NewMessageManager creates a new MessageManager
 func NewMessageManager(
 	cfg config.MessagingConfig,
-	db *database.DB,
+	db database.Store,
 	log *logger.Logger,
 	stealthCfg config.StealthConfig,
+	pacer *stealth.NavigationPacer,
+	cursor *stealth.Cursor,
+	registry *selectors.Registry,
+	site config.LinkedInConfig,
 ) *MessageManager {
 	return &MessageManager{
 		config:    cfg,
@@ -37,10 +71,28 @@ func NewMessageManager(
 		logger:    log.WithComponent("messaging"),
 		timing:    stealth.NewTimingController(stealthCfg.Timing),
 		typing:    stealth.NewTypingSimulator(stealthCfg.Timing),
+		scroll:    stealth.NewScrollController(stealthCfg.Scrolling),
+		bezier:    stealth.NewBezierMouse(stealthCfg.Bezier),
+		pacer:     pacer,
+		cursor:    cursor,
+		selectors: registry,
+		site:      site,
 		templates: cfg.Templates,
+		retry:     utils.NavigationRetryConfig(),
+		rng:       utils.NewRand("templates"),
 	}
 }
 
+// SetRetryBudget makes navigation retries draw from the run-wide budget
+func (mm *MessageManager) SetRetryBudget(budget *utils.RetryBudget) {
+	mm.retry.Budget = budget
+}
+
+// SetVariation installs the greeting and closing pools for messages
+func (mm *MessageManager) SetVariation(v *Variation) {
+	mm.variation = v
+}
+
 // MessageRequest represents a message to send
 type MessageRequest struct {
 	ConnectionID string
@@ -50,7 +102,14 @@ type MessageRequest struct {
 	JobTitle     string
 	Company      string
 	Message      string
-	TemplateIdx  int
+	OpenProfile  bool          // message an Open Profile non-connection whose profile is already open
+	AcceptDelay  time.Duration // invitation to detected acceptance; negative when unknown
+}
+
+// FollowUp is an accepted connection waiting for its follow-up message
+type FollowUp struct {
+	database.Connection
+	AcceptDelay time.Duration // negative when the acceptance time is unknown
 }
 
 // MessageResult represents the result of sending a message
@@ -58,23 +117,43 @@ type MessageResult struct {
 	Success      bool
 	ConnectionID string
 	ErrorMessage string
+	DryRun       bool // typed and checked but not sent
 }
 
-// SendMessage sends a follow-up message to an accepted connection
-func (mm *MessageManager) SendMessage(page *rod.Page, req *MessageRequest) (*MessageResult, error) {
+// SendMessage sends a follow-up message to an accepted connection. With
+// dryRun it types the message and returns before the Send click, with
+// nothing recorded
+func (mm *MessageManager) SendMessage(page *rod.Page, req *MessageRequest, dryRun bool) (_ *MessageResult, err error) {
+	defer utils.RecoverAsError(&err)
 	mm.logger.Info("sending message", "connection", req.ConnectionID, "profile", req.ProfileURL)
 
-	// Navigate to profile
-	err := page.Navigate(req.ProfileURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to navigate: %w", err)
-	}
+	// Navigate to profile, unless it is already open
+	if !req.OpenProfile {
+		time.Sleep(mm.timing.GetPreNavigationDelay())
+		err = utils.RetryWithBackoff(mm.retry, func() error {
+			mm.pacer.Wait()
+			err := page.Navigate(req.ProfileURL)
+			mm.logger.Trace(logger.TraceNavigate, req.ProfileURL, err)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to navigate: %w", err)
+		}
 
-	time.Sleep(mm.timing.GetPageLoadDelay())
-	page.WaitLoad()
+		time.Sleep(mm.timing.GetPageLoadDelay())
+		if err := utils.WaitLoadBounded(page, mm.site.PageLoadTimeout, mm.logger); err != nil {
+			mm.logger.LogError("page load", err, nil)
+		}
+	} else {
+		time.Sleep(mm.timing.GetThinkTime())
+	}
+	if variant, detected := mm.selectors.DetectIfUnknown(page); detected {
+		mm.logger.Info("detected LinkedIn UI variant", "variant", variant)
+	}
 
 	// Find and click Message button
 	messageBtn, err := mm.findMessageButton(page)
+	mm.logger.Trace(logger.TraceSelector, selectors.MessageButton, err)
 	if err != nil {
 		return &MessageResult{
 			Success:      false,
@@ -83,22 +162,25 @@ func (mm *MessageManager) SendMessage(page *rod.Page, req *MessageRequest) (*Mes
 		}, nil
 	}
 
-	messageBtn.Click(proto.InputMouseButtonLeft, 1)
+	err = mm.clickWithRealism(page, messageBtn)
+	mm.logger.Trace(logger.TraceClick, selectors.MessageButton, err)
 	time.Sleep(time.Second)
 
-	// Wait for messaging pane to open
-	messageInput, err := page.Timeout(5 * time.Second).Element(`div.msg-form__contenteditable, textarea.msg-form__textarea`)
+	// Wait for messaging pane to open and load its history
+	mm.loadThreadHistory(page)
+	messageInput, err := mm.waitForComposer(page)
 	if err != nil {
 		return &MessageResult{
 			Success:      false,
 			ConnectionID: req.ConnectionID,
-			ErrorMessage: "Message input not found",
+			ErrorMessage: fmt.Sprintf("Message input not ready: %v", err),
 		}, nil
 	}
 
 	// Generate message if not provided
-	if req.Message == "" && len(mm.templates) > 0 {
-		req.Message = mm.generateMessage(req)
+	templateID := ""
+	if req.Message == "" {
+		req.Message, templateID = mm.generateMessage(req)
 	}
 
 	if req.Message == "" {
@@ -119,11 +201,21 @@ func (mm *MessageManager) SendMessage(page *rod.Page, req *MessageRequest) (*Mes
 		}, nil
 	}
 
+	// A pasted URL makes LinkedIn render a preview card that reshapes the form
+	if urlPattern.MatchString(req.Message) {
+		mm.handleLinkPreview(page)
+	}
+
 	// Think time before sending
 	time.Sleep(mm.timing.GetThinkTime())
 
+	if dryRun {
+		return mm.rehearseSend(page, messageInput, req), nil
+	}
+
 	// Click send
 	err = mm.clickSend(page)
+	mm.logger.Trace(logger.TraceClick, selectors.MessageSend, err)
 	if err != nil {
 		return &MessageResult{
 			Success:      false,
@@ -137,11 +229,16 @@ func (mm *MessageManager) SendMessage(page *rod.Page, req *MessageRequest) (*Mes
 		ID:           fmt.Sprintf("msg_%d", time.Now().UnixNano()),
 		ConnectionID: req.ConnectionID,
 		Content:      req.Message,
+		TemplateID:   templateID,
 		Status:       "sent",
 		SentAt:       time.Now(),
 	}
-	mm.db.SaveMessage(msg)
-	mm.db.IncrementMessageCount()
+	if err := mm.db.RecordMessageSent(msg); err != nil {
+		mm.logger.LogError("record message", err, map[string]interface{}{"connection": req.ConnectionID})
+	}
+	if kind, idx, ok := parseTemplateID(templateID); ok {
+		mm.db.IncrementTemplateUsage(kind, idx)
+	}
 
 	mm.logger.Info("message sent", "connection", req.ConnectionID)
 
@@ -153,14 +250,7 @@ func (mm *MessageManager) SendMessage(page *rod.Page, req *MessageRequest) (*Mes
 
 // findMessageButton finds the Message button on a profile page
 func (mm *MessageManager) findMessageButton(page *rod.Page) (*rod.Element, error) {
-	selectors := []string{
-		`button[aria-label*="Message"]`,
-		`a[href*="/messaging/"]`,
-		`button.message-anywhere-button`,
-		`button:has-text("Message")`,
-	}
-
-	for _, selector := range selectors {
+	for _, selector := range mm.selectors.Get(selectors.MessageButton) {
 		btn, err := page.Timeout(3 * time.Second).Element(selector)
 		if err == nil && btn != nil {
 			visible, _ := btn.Visible()
@@ -173,14 +263,31 @@ func (mm *MessageManager) findMessageButton(page *rod.Page) (*rod.Element, error
 	return nil, fmt.Errorf("message button not found")
 }
 
-// generateMessage generates a personalized message from template
-func (mm *MessageManager) generateMessage(req *MessageRequest) string {
-	if len(mm.templates) == 0 {
-		return ""
+// generateMessage generates a personalized message from template and
+// returns it with the template's ID, e.g. "follow_up:2". Open Profile
+// messages use their own templates, since the member has not connected yet.
+// Follow-ups use the latency bucket matching how fast the invitation was
+// accepted, and rotate through the regular templates when none matches.
+// {{greeting}} and {{closing}} get a fresh pick per message, and the result
+// is kept within LinkedIn's message length
+func (mm *MessageManager) generateMessage(req *MessageRequest) (string, string) {
+	templates, kind := mm.templates, TemplateKindFollowUp
+	if req.OpenProfile {
+		templates, kind = mm.config.OpenProfileTemplates, TemplateKindOpenProfile
+	} else if i, ok := SelectLatencyBucket(mm.config.LatencyTemplates, req.AcceptDelay); ok {
+		templates, kind = mm.config.LatencyTemplates[i].Templates, latencyKind(mm.config.LatencyTemplates[i])
+	}
+	if len(templates) == 0 {
+		return "", ""
 	}
 
-	// Select template
-	template := mm.templates[req.TemplateIdx%len(mm.templates)]
+	// Start from one of the least used templates of the kind
+	stats, err := mm.db.GetTemplateStats(kind)
+	if err != nil {
+		mm.logger.LogError("load template stats", err, nil)
+	}
+	idx := pickBalanced(len(templates), stats, mm.rng)
+	template := templates[idx]
 
 	// Substitute variables
 	vars := map[string]string{
@@ -189,14 +296,55 @@ func (mm *MessageManager) generateMessage(req *MessageRequest) string {
 		"jobTitle":  req.JobTitle,
 		"company":   req.Company,
 	}
+	mm.variation.Apply(vars)
+
+	message := utils.TrimNote(utils.RenderTemplate(template, vars), maxMessageLength)
+	return message, fmt.Sprintf("%s:%d", kind, idx)
+}
+
+// parseTemplateID splits a template ID made by generateMessage into its
+// kind and index. ok is false for messages not made from a template
+func parseTemplateID(id string) (string, int, bool) {
+	kind, num, found := strings.Cut(id, ":")
+	if !found {
+		return "", 0, false
+	}
+	idx, err := strconv.Atoi(num)
+	return kind, idx, err == nil
+}
 
-	return stealth.SubstituteTemplate(template, vars)
+// SelectLatencyBucket returns the index of the first bucket whose max_days
+// covers delay. ok is false when delay is unknown or no bucket covers it
+func SelectLatencyBucket(buckets []config.LatencyTemplates, delay time.Duration) (int, bool) {
+	if delay < 0 {
+		return 0, false
+	}
+	for i, b := range buckets {
+		if b.MaxDays == 0 || delay <= time.Duration(b.MaxDays)*24*time.Hour {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// latencyKind names a latency bucket in template IDs, e.g. "accepted_7d"
+func latencyKind(b config.LatencyTemplates) string {
+	if b.MaxDays == 0 {
+		return "accepted_any"
+	}
+	return fmt.Sprintf("accepted_%dd", b.MaxDays)
 }
 
 // typeMessage types a message with realistic behavior
 func (mm *MessageManager) typeMessage(element *rod.Element, message string) error {
 	sequence := mm.typing.GenerateTypingSequence(message)
 
+	// Focus the composer and pause before the first keystroke
+	if err := element.Focus(); err != nil {
+		return fmt.Errorf("failed to focus message input: %w", err)
+	}
+	time.Sleep(mm.timing.GetFocusDelay())
+
 	for _, char := range sequence {
 		if char.IsBurstPause {
 			time.Sleep(char.Delay)
@@ -204,36 +352,176 @@ func (mm *MessageManager) typeMessage(element *rod.Element, message string) erro
 		}
 
 		if char.IsBackspace {
-			element.MustInput("\b")
+			if err := stealth.PressBackspace(element.Page()); err != nil {
+				return fmt.Errorf("failed to type backspace: %w", err)
+			}
 			time.Sleep(char.Delay)
 			continue
 		}
 
-		element.MustInput(string(char.Char))
+		if err := element.Input(string(char.Char)); err != nil {
+			return fmt.Errorf("failed to type character: %w", err)
+		}
 		time.Sleep(char.Delay)
 	}
 
 	return nil
 }
 
-// clickSend clicks the send button
-func (mm *MessageManager) clickSend(page *rod.Page) error {
-	selectors := []string{
-		`button[type="submit"].msg-form__send-button`,
-		`button.msg-form__send-button`,
-		`button[aria-label="Send"]`,
+// loadThreadHistory scrolls an existing thread to the bottom so LinkedIn
+// loads the most recent messages. It stops once the pane stops growing or
+// after the configured number of passes
+func (mm *MessageManager) loadThreadHistory(page *rod.Page) {
+	thread, err := findFirst(page, mm.selectors.Get(selectors.MessageThread), 3*time.Second)
+	if err != nil {
+		return // new conversation, nothing to load
 	}
 
-	for _, selector := range selectors {
+	lastHeight := -1
+	for attempt := 0; attempt < mm.config.ThreadScrollAttempts; attempt++ {
+		res, err := thread.Eval(`() => [this.scrollHeight, this.scrollHeight - this.scrollTop - this.clientHeight]`)
+		if err != nil {
+			mm.logger.Debug("could not measure message thread", "error", err)
+			return
+		}
+		height := res.Value.Get("0").Int()
+		remaining := res.Value.Get("1").Int()
+		if remaining <= 0 && height == lastHeight {
+			return
+		}
+		lastHeight = height
+
+		for _, step := range mm.scroll.GenerateScrollSequence(remaining, 0) {
+			if step.DeltaY != 0 {
+				thread.Eval(`(d) => this.scrollBy(0, d)`, step.DeltaY)
+			}
+			time.Sleep(step.Duration)
+		}
+
+		// Give lazy-loaded messages a moment to render
+		time.Sleep(mm.scroll.GetRandomScrollPause())
+	}
+}
+
+// waitForComposer waits, bounded by composer_wait_seconds, for the reply
+// box to exist and be interactable
+func (mm *MessageManager) waitForComposer(page *rod.Page) (*rod.Element, error) {
+	timeout := time.Duration(mm.config.ComposerWaitSeconds) * time.Second
+	messageInput, err := findFirst(page, mm.selectors.Get(selectors.MessageInput), timeout)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := messageInput.Timeout(timeout).WaitInteractable(); err != nil {
+		return nil, fmt.Errorf("composer not interactable: %w", err)
+	}
+	return messageInput, nil
+}
+
+// handleLinkPreview waits for the link-preview card to load and then keeps
+// or dismisses it according to the configured policy
+func (mm *MessageManager) handleLinkPreview(page *rod.Page) {
+	policy := mm.config.LinkPreview
+	if policy == LinkPreviewIgnore {
+		return
+	}
+
+	preview, err := page.Timeout(linkPreviewTimeout).Element(`.msg-form__link-preview, .msg-link-preview, .msg-form__attachment-preview`)
+	if err != nil {
+		mm.logger.Info("no link preview appeared")
+		return
+	}
+
+	// Let the preview finish rendering so the form stops shifting
+	preview.Timeout(linkPreviewTimeout).WaitStable(300 * time.Millisecond)
+
+	if policy != LinkPreviewRemove {
+		mm.logger.Info("keeping link preview")
+		return
+	}
+
+	dismiss, err := preview.Timeout(2 * time.Second).Element(`button[aria-label*="Remove"], button[aria-label*="Dismiss"], button.msg-link-preview__close`)
+	if err != nil {
+		mm.logger.Warn("link preview dismiss button not found")
+		return
+	}
+	if err := dismiss.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		mm.logger.LogError("dismiss link preview", err, nil)
+		return
+	}
+	mm.logger.Info("removed link preview")
+	time.Sleep(500 * time.Millisecond)
+}
+
+// clickSend clicks the send button
+func (mm *MessageManager) clickSend(page *rod.Page) error {
+	for _, selector := range mm.selectors.Get(selectors.MessageSend) {
 		sendBtn, err := page.Timeout(2 * time.Second).Element(selector)
 		if err == nil && sendBtn != nil {
-			return sendBtn.Click(proto.InputMouseButtonLeft, 1)
+			return mm.clickWithRealism(page, sendBtn.CancelTimeout())
 		}
 	}
 
 	return fmt.Errorf("send button not found")
 }
 
+// rehearseSend ends a dry run message: the send button is looked up but not
+// clicked, the message that would have gone out is logged and the composer
+// is cleared, since LinkedIn keeps what was typed there as a draft
+func (mm *MessageManager) rehearseSend(page *rod.Page, composer *rod.Element, req *MessageRequest) *MessageResult {
+	_, err := findFirst(page, mm.selectors.Get(selectors.MessageSend), 2*time.Second)
+	mm.logger.Trace(logger.TraceSelector, selectors.MessageSend, err)
+	if err != nil {
+		return &MessageResult{
+			Success:      false,
+			ConnectionID: req.ConnectionID,
+			ErrorMessage: "Send button not found",
+		}
+	}
+	mm.logger.Info("dry run: message not sent", "connection", req.ConnectionID, "message", req.Message)
+
+	if err := composer.Focus(); err == nil {
+		err = page.KeyActions().Press(input.ControlLeft).Type(input.KeyA).Do()
+		if err == nil {
+			err = page.Keyboard.Type(input.Backspace)
+		}
+		if err != nil {
+			mm.logger.Warn("could not clear the dry run message, a draft may remain", "connection", req.ConnectionID, "error", err)
+		}
+	}
+	return &MessageResult{
+		Success:      true,
+		ConnectionID: req.ConnectionID,
+		DryRun:       true,
+	}
+}
+
+// clickWithRealism moves the pointer to the element on a Bézier path from
+// wherever the shared cursor last was, then clicks it
+func (mm *MessageManager) clickWithRealism(page *rod.Page, element *rod.Element) error {
+	box, err := element.Shape()
+	if err != nil || len(box.Quads) == 0 || len(box.Quads[0]) < 8 {
+		return element.Click(proto.InputMouseButtonLeft, 1)
+	}
+	centerX, centerY := mm.bezier.RandomPointInQuad(box.Quads[0])
+
+	document, width, height := utils.PageDocument(page)
+	startX, startY := mm.cursor.Begin(document, width, height)
+	path := mm.bezier.GeneratePath(startX, startY, centerX, centerY)
+	durations := mm.bezier.GetMovementDurations(len(path), 300*time.Millisecond)
+	for i, point := range path {
+		if i > 0 && i-1 < len(durations) {
+			time.Sleep(durations[i-1])
+		}
+		if err := page.Mouse.MoveTo(proto.Point{X: point.X, Y: point.Y}); err != nil {
+			return fmt.Errorf("failed to move mouse: %w", err)
+		}
+		mm.cursor.MovedTo(point.X, point.Y)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	return page.Mouse.Click(proto.InputMouseButtonLeft, 1)
+}
+
 // DetectAcceptedConnections checks for newly accepted connections
 func (mm *MessageManager) DetectAcceptedConnections(page *rod.Page) ([]database.Connection, error) {
 	// Get pending connections from database
@@ -247,13 +535,19 @@ func (mm *MessageManager) DetectAcceptedConnections(page *rod.Page) ([]database.
 	}
 
 	// Navigate to My Network
-	err = page.Navigate("https://www.linkedin.com/mynetwork/invite-connect/connections/")
+	connectionsURL := mm.site.URL("/mynetwork/invite-connect/connections/")
+	time.Sleep(mm.timing.GetPreNavigationDelay())
+	mm.pacer.Wait()
+	err = page.Navigate(connectionsURL)
+	mm.logger.Trace(logger.TraceNavigate, connectionsURL, err)
 	if err != nil {
 		return nil, err
 	}
 
 	time.Sleep(mm.timing.GetPageLoadDelay())
-	page.WaitLoad()
+	if err := utils.WaitLoadBounded(page, mm.site.PageLoadTimeout, mm.logger); err != nil {
+		mm.logger.LogError("page load", err, nil)
+	}
 
 	// Get connection list
 	html, err := page.HTML()
@@ -289,21 +583,30 @@ func extractProfileID(profileURL string) string {
 	return id
 }
 
-// GetConnectionsNeedingFollowUp returns accepted connections without follow-up messages
-func (mm *MessageManager) GetConnectionsNeedingFollowUp() ([]database.Connection, error) {
+// GetConnectionsNeedingFollowUp returns accepted connections without
+// follow-up messages, with how long each took to accept. The acceptance time
+// is when it was detected, so the delay can run long by up to a detection run.
+// Accepted incoming invitations have no delay of their own
+func (mm *MessageManager) GetConnectionsNeedingFollowUp() ([]FollowUp, error) {
 	accepted, err := mm.db.GetAcceptedConnections()
 	if err != nil {
 		return nil, err
 	}
 
-	var needFollowUp []database.Connection
+	var needFollowUp []FollowUp
 	for _, conn := range accepted {
 		hasSent, err := mm.db.HasSentFollowUp(conn.ID)
 		if err != nil {
 			continue
 		}
 		if !hasSent {
-			needFollowUp = append(needFollowUp, conn)
+			delay := time.Duration(-1)
+			if conn.AcceptedAt != nil && !conn.CreatedAt.IsZero() && !conn.Incoming {
+				if delay = conn.AcceptedAt.Sub(conn.CreatedAt); delay < 0 {
+					delay = 0
+				}
+			}
+			needFollowUp = append(needFollowUp, FollowUp{Connection: conn, AcceptDelay: delay})
 		}
 	}
 
@@ -317,6 +620,7 @@ func (mm *MessageManager) CanSendMoreMessagesToday() (bool, int, error) {
 		return false, 0, err
 	}
 
-	remaining := mm.config.DailyLimit - activity.MessagesSent
+	// The ceiling moves by up to daily_limit_jitter_percent from day to day
+	remaining := mm.config.EffectiveDailyLimit(time.Now()) - activity.MessagesSent
 	return remaining > 0, remaining, nil
 }
@@ -0,0 +1,78 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	"linkedin-automation/config"
+	"linkedin-automation/utils"
+)
+
+func TestSelectLatencyBucket(t *testing.T) {
+	day := 24 * time.Hour
+	buckets := []config.LatencyTemplates{
+		{MaxDays: 1, Templates: []string{"same day"}},
+		{MaxDays: 7, Templates: []string{"this week"}},
+		{MaxDays: 30, Templates: []string{"this month"}},
+	}
+	withCatchAll := append(append([]config.LatencyTemplates{}, buckets...), config.LatencyTemplates{Templates: []string{"any"}})
+
+	tests := []struct {
+		name    string
+		buckets []config.LatencyTemplates
+		delay   time.Duration
+		want    int
+		wantOK  bool
+	}{
+		{"same day", buckets, 3 * time.Hour, 0, true},
+		{"one day exactly", buckets, day, 0, true},
+		{"mid latency", buckets, 4 * day, 1, true},
+		{"long latency", buckets, 20 * day, 2, true},
+		{"beyond every bucket", buckets, 45 * day, 0, false},
+		{"beyond every bucket, catch-all", withCatchAll, 45 * day, 3, true},
+		{"unknown latency", buckets, -1, 0, false},
+		{"no buckets", nil, 4 * day, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := SelectLatencyBucket(tt.buckets, tt.delay)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: SelectLatencyBucket = %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// A gap between buckets: nothing covers latencies past the first one
+	gap := []config.LatencyTemplates{{MaxDays: 2, Templates: []string{"fast"}}}
+	if _, ok := SelectLatencyBucket(gap, 5*day); ok {
+		t.Error("SelectLatencyBucket matched a latency past the last bucket")
+	}
+}
+
+func TestGenerateMessageByLatency(t *testing.T) {
+	db, log := newTestStore(t)
+	cfg := config.MessagingConfig{
+		Templates: []string{"Thanks for connecting, {{firstName}}!"},
+		LatencyTemplates: []config.LatencyTemplates{
+			{MaxDays: 1, Templates: []string{"Quick accept, {{firstName}}!"}},
+			{MaxDays: 7, Templates: []string{"Glad we connected this week, {{firstName}}."}},
+		},
+	}
+	mm := &MessageManager{config: cfg, db: db, logger: log, templates: cfg.Templates, rng: utils.NewRand("templates")}
+
+	tests := []struct {
+		delay  time.Duration
+		wantID string
+		want   string
+	}{
+		{2 * time.Hour, "accepted_1d:0", "Quick accept, Ana!"},
+		{3 * 24 * time.Hour, "accepted_7d:0", "Glad we connected this week, Ana."},
+		// no bucket covers it: the regular templates rotate
+		{20 * 24 * time.Hour, TemplateKindFollowUp + ":0", "Thanks for connecting, Ana!"},
+		{-1, TemplateKindFollowUp + ":0", "Thanks for connecting, Ana!"},
+	}
+	for _, tt := range tests {
+		msg, id := mm.generateMessage(&MessageRequest{FirstName: "Ana", AcceptDelay: tt.delay})
+		if msg != tt.want || id != tt.wantID {
+			t.Errorf("delay %v: generateMessage = %q (%s), want %q (%s)", tt.delay, msg, id, tt.want, tt.wantID)
+		}
+	}
+}
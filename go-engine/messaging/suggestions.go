@@ -0,0 +1,290 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/utils"
+)
+
+// Connection sources
+const (
+	SourceSearch = "search" // profiles found by search, invited from their profile page
+	SourcePYMK   = "pymk"   // "People you may know" cards on My Network
+	SourceBoth   = "both"
+)
+
+// suggestionScrolls is how many times the My Network page is scrolled to
+// load more suggestion cards before they are read
+const suggestionScrolls = 3
+
+// suggestionCardsScript lists the "People you may know" cards with their
+// profile link, name, occupation and whether the card offers Connect
+const suggestionCardsScript = `() => {
+	const cards = document.querySelectorAll('.discover-entity-type-card, .discover-fluid-entity-list--item, li.discover-entity-card');
+	return [...cards].map((card, i) => {
+		card.setAttribute('data-pymk-index', String(i));
+		const link = card.querySelector('a[href*="/in/"]');
+		const name = card.querySelector('.discover-person-card__name, .artdeco-entity-lockup__title, [class*="member-name"]');
+		const occupation = card.querySelector('.discover-person-card__occupation, .artdeco-entity-lockup__subtitle, [class*="occupation"]');
+		const connect = [...card.querySelectorAll('button')].find(b =>
+			/connect|invite/i.test(b.getAttribute('aria-label') || b.innerText || ''));
+		return {
+			index: i,
+			url: link ? link.href : '',
+			name: name ? name.innerText.trim() : '',
+			occupation: occupation ? occupation.innerText.trim() : '',
+			connect: !!connect,
+		};
+	});
+}`
+
+// Suggestion is one "People you may know" card
+type Suggestion struct {
+	ProfileURL string
+	FirstName  string
+	LastName   string
+	Occupation string
+	index      int // data-pymk-index of the card
+}
+
+// SuggestionsResult summarizes one pass over the suggestion grid
+type SuggestionsResult struct {
+	Found   int // cards with a Connect button
+	Results []*ConnectionResult
+	Quit    bool // the operator quit at a preview
+}
+
+// SetSuggestionFilter restricts suggested profiles to those whose
+// occupation contains one of terms (case-insensitive). criteriaID is
+// recorded with the invitations sent through the filter
+func (cm *ConnectionManager) SetSuggestionFilter(criteriaID string, terms []string) {
+	cm.suggestionCriteria = criteriaID
+	cm.suggestion = nil
+	for _, term := range terms {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			cm.suggestion = append(cm.suggestion, term)
+		}
+	}
+}
+
+// matchesSuggestionFilter reports whether occupation passes the filter
+func (cm *ConnectionManager) matchesSuggestionFilter(occupation string) bool {
+	if len(cm.suggestion) == 0 {
+		return true
+	}
+	lower := strings.ToLower(occupation)
+	for _, term := range cm.suggestion {
+		if strings.Contains(lower, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectFromSuggestions opens My Network and sends note-less invitations
+// through the inline Connect buttons of the "People you may know" grid.
+// Cards are filtered by occupation and processed profiles are skipped; the
+// daily limit is checked before each click. Sent invitations are recorded
+// like any other connection request
+func (cm *ConnectionManager) ConnectFromSuggestions(page *rod.Page) (_ *SuggestionsResult, err error) {
+	defer utils.RecoverAsError(&err)
+
+	networkURL := cm.site.URL("/mynetwork/")
+	time.Sleep(cm.timing.GetPreNavigationDelay())
+	err = utils.RetryWithBackoff(cm.retry, func() error {
+		cm.pacer.Wait()
+		err := page.Navigate(networkURL)
+		cm.logger.Trace(logger.TraceNavigate, networkURL, err)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate: %w", err)
+	}
+	time.Sleep(cm.timing.GetPageLoadDelay())
+	if err := utils.WaitLoadBounded(page, cm.site.PageLoadTimeout, cm.logger); err != nil {
+		cm.logger.LogError("page load", err, nil)
+	}
+
+	for i := 0; i < suggestionScrolls; i++ {
+		page.Eval(`() => window.scrollBy(0, window.innerHeight * 0.8)`)
+		time.Sleep(cm.timing.GetThinkTime())
+	}
+
+	suggestions, err := cm.readSuggestions(page)
+	if err != nil {
+		return nil, err
+	}
+	result := &SuggestionsResult{Found: len(suggestions)}
+	cm.logger.Info("people you may know", "cards", len(suggestions), "filter", strings.Join(cm.suggestion, ", "))
+
+	for _, s := range suggestions {
+		if cm.retry.Budget.Depleted() || page.GetContext().Err() != nil {
+			break
+		}
+		if canSend, _, _ := cm.CanSendMoreToday(); !canSend {
+			cm.logger.Info("daily connection limit reached, leaving suggestions")
+			break
+		}
+
+		if !cm.matchesSuggestionFilter(s.Occupation) {
+			cm.logger.Debug("suggestion filtered out", "profile", s.ProfileURL, "occupation", s.Occupation)
+			continue
+		}
+		if processed, _ := cm.db.IsProfileProcessed(s.ProfileURL); processed {
+			continue
+		}
+
+		req := &ConnectionRequest{
+			ProfileURL: s.ProfileURL,
+			FirstName:  s.FirstName,
+			LastName:   s.LastName,
+			JobTitle:   s.Occupation,
+		}
+		if cm.confirm != nil {
+			switch cm.confirm(req) {
+			case DecisionSkip:
+				cm.db.MarkProfileProcessed(s.ProfileURL)
+				result.Results = append(result.Results, failed(s.ProfileURL, ReasonUserSkipped, "skipped at preview"))
+				continue
+			case DecisionQuit:
+				result.Quit = true
+				return result, nil
+			}
+		}
+
+		res := cm.connectSuggestion(page, s)
+		result.Results = append(result.Results, res)
+		if res.WeeklyLimitReached {
+			break
+		}
+		time.Sleep(cm.timing.GetActionDelay())
+	}
+
+	return result, nil
+}
+
+// readSuggestions reads the suggestion cards that offer Connect
+func (cm *ConnectionManager) readSuggestions(page *rod.Page) ([]Suggestion, error) {
+	res, err := page.Eval(suggestionCardsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suggestions: %w", err)
+	}
+
+	var suggestions []Suggestion
+	seen := make(map[string]bool)
+	for _, card := range res.Value.Arr() {
+		if !card.Get("connect").Bool() {
+			continue
+		}
+		profileURL, ok := utils.CanonicalProfileURL(card.Get("url").Str())
+		if !ok || seen[profileURL] {
+			continue
+		}
+		seen[profileURL] = true
+
+		s := Suggestion{
+			ProfileURL: profileURL,
+			Occupation: card.Get("occupation").Str(),
+			index:      card.Get("index").Int(),
+		}
+		parts := strings.Fields(card.Get("name").Str())
+		if len(parts) >= 1 {
+			s.FirstName = parts[0]
+		}
+		if len(parts) >= 2 {
+			s.LastName = strings.Join(parts[1:], " ")
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, nil
+}
+
+// connectSuggestion clicks the inline Connect button of one card and
+// records the invitation once the button turns into Pending
+func (cm *ConnectionManager) connectSuggestion(page *rod.Page, s Suggestion) *ConnectionResult {
+	selector := fmt.Sprintf(`[data-pymk-index="%d"] button`, s.index)
+	buttons, err := page.Timeout(3 * time.Second).Elements(selector)
+	if err != nil {
+		return failed(s.ProfileURL, ReasonButtonNotFound, "suggestion card no longer on the page")
+	}
+
+	var connect *rod.Element
+	for _, btn := range buttons {
+		label, _ := btn.Attribute("aria-label")
+		text, _ := btn.Text()
+		lower := strings.ToLower(text)
+		if label != nil {
+			lower += " " + strings.ToLower(*label)
+		}
+		if strings.Contains(lower, "connect") || strings.Contains(lower, "invite") {
+			connect = btn.CancelTimeout()
+			break
+		}
+	}
+	if connect == nil {
+		return failed(s.ProfileURL, ReasonButtonNotFound, "Connect button not found on suggestion card")
+	}
+
+	slot, ok := cm.reserveSlot(s.ProfileURL)
+	if !ok {
+		return failed(s.ProfileURL, ReasonDailyLimit, "no connection slot left today")
+	}
+	connect.ScrollIntoView()
+	time.Sleep(cm.timing.GetThinkTime())
+	err = cm.clickWithRealism(page, connect)
+	cm.logger.Trace(logger.TraceClick, selector, err)
+	if err != nil {
+		cm.releaseSlot(slot)
+		return failed(s.ProfileURL, ReasonError, err.Error())
+	}
+	time.Sleep(time.Second)
+
+	// Some accounts still get the invitation modal from the grid
+	if reason, ok := cm.detectModalBlocker(page); ok {
+		cm.releaseSlot(slot)
+		return failed(s.ProfileURL, reason, "connection blocked: "+string(reason))
+	}
+	if shape := cm.inspectModal(page); shape.Kind != ModalUnknown {
+		if shape.Kind == ModalEmailRequired {
+			cm.releaseSlot(slot)
+			return failed(s.ProfileURL, ReasonEmailRequired, "connection blocked: "+string(ReasonEmailRequired))
+		}
+		if err := cm.sendWithoutNote(page, false); err != nil {
+			cm.releaseSlot(slot)
+			if reason, ok := cm.detectModalBlocker(page); ok {
+				return failed(s.ProfileURL, reason, "connection blocked: "+string(reason))
+			}
+			return failed(s.ProfileURL, ReasonError, err.Error())
+		}
+	}
+
+	pending, _, _ := page.Has(fmt.Sprintf(`[data-pymk-index="%d"] button[aria-label*="Pending"], [data-pymk-index="%d"] button:disabled`, s.index, s.index))
+	if !pending {
+		return failed(s.ProfileURL, ReasonSendUnconfirmed, "suggestion card did not switch to Pending")
+	}
+
+	conn := &database.Connection{
+		ID:         fmt.Sprintf("conn_%d", time.Now().UnixNano()),
+		ProfileURL: s.ProfileURL,
+		FirstName:  s.FirstName,
+		LastName:   s.LastName,
+		JobTitle:   s.Occupation,
+		Status:     "pending",
+		CreatedAt:  time.Now(),
+
+		SearchCriteriaID: cm.suggestionCriteria,
+		SnapshotSource:   SnapshotSuggestionCard,
+	}
+	if err := cm.db.RecordConnectionSent(conn); err != nil {
+		cm.logger.LogError("record connection", err, map[string]interface{}{"profile": s.ProfileURL})
+	}
+	cm.logger.Info("connection request sent from suggestions", "profile", s.ProfileURL)
+
+	return &ConnectionResult{Success: true, ProfileURL: s.ProfileURL}
+}
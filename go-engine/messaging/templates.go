@@ -2,8 +2,19 @@ package messaging
 
 import (
 	"math/rand"
+	"sort"
 	"strings"
 	"time"
+
+	"linkedin-automation/database"
+	"linkedin-automation/utils"
+)
+
+// Template kinds tracked in template usage
+const (
+	TemplateKindConnection  = "connection"
+	TemplateKindFollowUp    = "follow_up"
+	TemplateKindOpenProfile = "open_profile"
 )
 
 // TemplateManager handles message template operations
@@ -11,6 +22,8 @@ type TemplateManager struct {
 	connectionTemplates []string
 	followUpTemplates   []string
 	rng                 *rand.Rand
+	db                  database.Store                           // uses from earlier runs; nil balances within the run
+	picks               map[string]map[int]database.TemplateStat // SelectBalanced picks this run, by kind
 }
 
 // NewTemplateManager creates a new TemplateManager
@@ -18,17 +31,98 @@ func NewTemplateManager(connectionTemplates, followUpTemplates []string) *Templa
 	return &TemplateManager{
 		connectionTemplates: connectionTemplates,
 		followUpTemplates:   followUpTemplates,
-		rng:                 rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:                 utils.NewRand("templates"),
+		picks:               make(map[string]map[int]database.TemplateStat),
+	}
+}
+
+// SetStore makes SelectBalanced count the template uses recorded in db,
+// so the balance holds across runs
+func (tm *TemplateManager) SetStore(db database.Store) {
+	tm.db = db
+}
+
+// SelectBalanced picks a template of kind, TemplateKindConnection or
+// TemplateKindFollowUp, and returns its index and text, or -1 and "" when
+// there is none. The pick favors the least used templates, counting the
+// store's uses and the picks made so far, so the distribution stays even
+// while the order varies
+func (tm *TemplateManager) SelectBalanced(kind string) (int, string) {
+	var templates []string
+	switch kind {
+	case TemplateKindConnection:
+		templates = tm.connectionTemplates
+	case TemplateKindFollowUp:
+		templates = tm.followUpTemplates
+	}
+	if len(templates) == 0 {
+		return -1, ""
+	}
+
+	stats := make(map[int]database.TemplateStat)
+	if tm.db != nil {
+		if recorded, err := tm.db.GetTemplateStats(kind); err == nil {
+			stats = recorded
+		}
+	}
+	for idx, pick := range tm.picks[kind] {
+		stat := stats[idx]
+		stat.Uses += pick.Uses
+		if pick.LastUsedAt.After(stat.LastUsedAt) {
+			stat.LastUsedAt = pick.LastUsedAt
+		}
+		stats[idx] = stat
+	}
+
+	idx := pickBalanced(len(templates), stats, tm.rng)
+	if tm.picks[kind] == nil {
+		tm.picks[kind] = make(map[int]database.TemplateStat)
+	}
+	pick := tm.picks[kind][idx]
+	pick.Uses++
+	pick.LastUsedAt = time.Now()
+	tm.picks[kind][idx] = pick
+	return idx, templates[idx]
+}
+
+// pickBalanced returns the index of one of n templates given their use
+// stats. Only the least used templates are candidates, which keeps the
+// counts even; the pick is drawn from the half of them unused the longest,
+// or from all never used, so the order differs from run to run instead of
+// always starting at 0
+func pickBalanced(n int, stats map[int]database.TemplateStat, rng *rand.Rand) int {
+	least := -1
+	var candidates []int
+	for i := 0; i < n; i++ {
+		uses := stats[i].Uses
+		switch {
+		case least < 0 || uses < least:
+			least, candidates = uses, []int{i}
+		case uses == least:
+			candidates = append(candidates, i)
+		}
 	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return stats[candidates[a]].LastUsedAt.Before(stats[candidates[b]].LastUsedAt)
+	})
+	pool := (len(candidates) + 1) / 2
+	for pool < len(candidates) && stats[candidates[pool]].LastUsedAt.IsZero() {
+		pool++
+	}
+	return candidates[rng.Intn(pool)]
 }
 
 // TemplateVariables holds variables for template substitution
 type TemplateVariables struct {
-	FirstName string
-	LastName  string
-	JobTitle  string
-	Company   string
-	Location  string
+	FirstName    string
+	LastName     string
+	JobTitle     string
+	Company      string
+	Location     string
+	MutualCount  string
+	MutualName   string
+	SharedSchool string
+	SharedGroup  string
 }
 
 // GetRandomConnectionTemplate returns a random connection request template
@@ -49,24 +143,17 @@ func (tm *TemplateManager) GetRandomFollowUpTemplate() string {
 
 // Render renders a template with the given variables
 func (tm *TemplateManager) Render(template string, vars TemplateVariables) string {
-	result := template
-
-	replacements := map[string]string{
-		"{{firstName}}": vars.FirstName,
-		"{{lastName}}":  vars.LastName,
-		"{{jobTitle}}":  vars.JobTitle,
-		"{{company}}":   vars.Company,
-		"{{location}}":  vars.Location,
-	}
-
-	for placeholder, value := range replacements {
-		if value != "" {
-			result = strings.ReplaceAll(result, placeholder, value)
-		} else {
-			// Handle empty values gracefully
-			result = strings.ReplaceAll(result, placeholder, "")
-		}
-	}
+	result := utils.RenderTemplate(template, map[string]string{
+		"firstName":    vars.FirstName,
+		"lastName":     vars.LastName,
+		"jobTitle":     vars.JobTitle,
+		"company":      vars.Company,
+		"location":     vars.Location,
+		"mutualCount":  vars.MutualCount,
+		"mutualName":   vars.MutualName,
+		"sharedSchool": vars.SharedSchool,
+		"sharedGroup":  vars.SharedGroup,
+	})
 
 	// Clean up any double spaces from empty variables
 	for strings.Contains(result, "  ") {
@@ -85,12 +172,7 @@ func (tm *TemplateManager) RenderConnectionNote(vars TemplateVariables, maxLengt
 
 	result := tm.Render(template, vars)
 
-	// Enforce character limit
-	if maxLength > 0 && len(result) > maxLength {
-		result = result[:maxLength-3] + "..."
-	}
-
-	return result
+	return utils.TrimNote(result, maxLength)
 }
 
 // RenderFollowUpMessage generates a personalized follow-up message
@@ -132,40 +214,3 @@ func (tm *TemplateManager) GetConnectionTemplateCount() int {
 func (tm *TemplateManager) GetFollowUpTemplateCount() int {
 	return len(tm.followUpTemplates)
 }
-
-// ValidateTemplate checks if a template has valid variable placeholders
-func ValidateTemplate(template string) []string {
-	var errors []string
-
-	validVars := []string{"{{firstName}}", "{{lastName}}", "{{jobTitle}}", "{{company}}", "{{location}}"}
-
-	// Find all placeholders in template
-	for {
-		start := strings.Index(template, "{{")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(template[start:], "}}")
-		if end == -1 {
-			errors = append(errors, "Unclosed placeholder starting at position "+string(rune(start)))
-			break
-		}
-
-		placeholder := template[start : start+end+2]
-		isValid := false
-		for _, valid := range validVars {
-			if placeholder == valid {
-				isValid = true
-				break
-			}
-		}
-
-		if !isValid {
-			errors = append(errors, "Unknown variable: "+placeholder)
-		}
-
-		template = template[start+end+2:]
-	}
-
-	return errors
-}
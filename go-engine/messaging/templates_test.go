@@ -0,0 +1,93 @@
+package messaging
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"linkedin-automation/database"
+)
+
+func TestSelectBalancedKeepsCountsEven(t *testing.T) {
+	templates := []string{"a", "b", "c", "d", "e"}
+	for seed := int64(1); seed <= 20; seed++ {
+		tm := NewTemplateManager(templates, nil)
+		tm.rng = rand.New(rand.NewSource(seed))
+		counts := make([]int, len(templates))
+		for i := 0; i < 53; i++ {
+			idx, text := tm.SelectBalanced(TemplateKindConnection)
+			if text != templates[idx] {
+				t.Fatalf("SelectBalanced returned %d, %q", idx, text)
+			}
+			counts[idx]++
+			least, most := counts[0], counts[0]
+			for _, c := range counts {
+				least, most = min(least, c), max(most, c)
+			}
+			if most-least > 1 {
+				t.Fatalf("seed %d: counts %v after %d picks differ by more than 1", seed, counts, i+1)
+			}
+		}
+	}
+}
+
+func TestSelectBalancedFirstPickVaries(t *testing.T) {
+	first := make(map[int]bool)
+	for seed := int64(1); seed <= 40; seed++ {
+		tm := NewTemplateManager([]string{"a", "b", "c", "d"}, nil)
+		tm.rng = rand.New(rand.NewSource(seed))
+		idx, _ := tm.SelectBalanced(TemplateKindConnection)
+		first[idx] = true
+	}
+	if len(first) < 3 {
+		t.Errorf("first picks over 40 runs were only %v", first)
+	}
+}
+
+func TestSelectBalancedWithoutTemplates(t *testing.T) {
+	tm := NewTemplateManager([]string{"a"}, nil)
+	if idx, text := tm.SelectBalanced(TemplateKindFollowUp); idx != -1 || text != "" {
+		t.Errorf("SelectBalanced with no follow-up templates = %d, %q, want -1, \"\"", idx, text)
+	}
+}
+
+func TestPickBalanced(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		n     int
+		stats map[int]database.TemplateStat
+		want  map[int]bool // indexes the pick may return
+	}{
+		{"never used", 3, nil, map[int]bool{0: true, 1: true, 2: true}},
+		{"least used", 3, map[int]database.TemplateStat{
+			0: {Uses: 3, LastUsedAt: now.Add(-3 * time.Hour)},
+			1: {Uses: 2, LastUsedAt: now.Add(-time.Hour)},
+			2: {Uses: 3, LastUsedAt: now.Add(-5 * time.Hour)},
+		}, map[int]bool{1: true}},
+		{"unused longest", 4, map[int]database.TemplateStat{
+			0: {Uses: 1, LastUsedAt: now.Add(-time.Minute)},
+			1: {Uses: 1, LastUsedAt: now.Add(-4 * time.Hour)},
+			2: {Uses: 1, LastUsedAt: now.Add(-2 * time.Minute)},
+			3: {Uses: 1, LastUsedAt: now.Add(-3 * time.Hour)},
+		}, map[int]bool{1: true, 3: true}},
+		{"new templates first", 4, map[int]database.TemplateStat{
+			0: {Uses: 1, LastUsedAt: now},
+			1: {Uses: 1, LastUsedAt: now},
+		}, map[int]bool{2: true, 3: true}},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		seen := make(map[int]bool)
+		for i := 0; i < 200; i++ {
+			idx := pickBalanced(tt.n, tt.stats, rng)
+			if !tt.want[idx] {
+				t.Fatalf("%s: picked %d, want one of %v", tt.name, idx, tt.want)
+			}
+			seen[idx] = true
+		}
+		if len(seen) != len(tt.want) {
+			t.Errorf("%s: picked only %v of %v", tt.name, seen, tt.want)
+		}
+	}
+}
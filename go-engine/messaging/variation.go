@@ -0,0 +1,42 @@
+package messaging
+
+import (
+	"math/rand"
+
+	"linkedin-automation/utils"
+)
+
+// Variation fills the {{greeting}} and {{closing}} placeholders with a
+// random pick from messaging.greetings and messaging.closings, so repeated
+// renders of one template do not read identically. It is shared by notes
+// and messages
+type Variation struct {
+	greetings []string
+	closings  []string
+	rng       *rand.Rand
+}
+
+// NewVariation creates a Variation over the given pools. An empty pool
+// renders its placeholder empty
+func NewVariation(greetings, closings []string) *Variation {
+	return &Variation{
+		greetings: greetings,
+		closings:  closings,
+		rng:       utils.NewRand("variation"),
+	}
+}
+
+// Apply sets the greeting and closing variables for one render. A nil
+// Variation leaves both empty
+func (v *Variation) Apply(vars map[string]string) {
+	vars["greeting"], vars["closing"] = "", ""
+	if v == nil {
+		return
+	}
+	if len(v.greetings) > 0 {
+		vars["greeting"] = v.greetings[v.rng.Intn(len(v.greetings))]
+	}
+	if len(v.closings) > 0 {
+		vars["closing"] = v.closings[v.rng.Intn(len(v.closings))]
+	}
+}
@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"linkedin-automation/config"
+	"linkedin-automation/utils"
+)
+
+func TestVariationRendersDiffer(t *testing.T) {
+	cm := newTestConnectionManager(t, config.ConnectionConfig{MaxNoteLength: 300})
+	cm.variation = NewVariation(
+		[]string{"Hi", "Hello", "Hey", "Good to meet you"},
+		[]string{"Best", "Cheers", "Thanks", "All the best"},
+	)
+	req := &ConnectionRequest{FirstName: "Ana", Company: "Acme"}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		seen[cm.renderNote("{{greeting}} {{firstName}}, great work at {{company}}. {{closing}}", req)] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("50 renders gave %d distinct notes: %v", len(seen), seen)
+	}
+
+	// a nil Variation renders both placeholders empty
+	vars := map[string]string{}
+	(*Variation)(nil).Apply(vars)
+	if vars["greeting"] != "" || vars["closing"] != "" {
+		t.Errorf("nil Variation set %q and %q", vars["greeting"], vars["closing"])
+	}
+}
+
+func TestVariationKeepsLengthLimits(t *testing.T) {
+	long := strings.Repeat("Wishing you a wonderful week ahead ", 10)
+
+	cm := newTestConnectionManager(t, config.ConnectionConfig{MaxNoteLength: 100, Signature: "– Sam"})
+	cm.variation = NewVariation([]string{long}, []string{long})
+	req := &ConnectionRequest{FirstName: "Ana", Company: "Acme"}
+	for i := 0; i < 10; i++ {
+		note := cm.renderNote("{{greeting}} {{firstName}}, great work at {{company}}. {{closing}}", req)
+		if n := utf8.RuneCountInString(note); n > 100 {
+			t.Fatalf("note with a long greeting and closing is %d characters, max 100", n)
+		}
+		if !strings.HasSuffix(note, "\n– Sam") {
+			t.Fatalf("note %q lost its signature", note)
+		}
+	}
+
+	db, log := newTestStore(t)
+	mm := &MessageManager{
+		config:    config.MessagingConfig{Templates: []string{"{{greeting}} {{firstName}}, thanks for connecting. {{closing}}"}},
+		db:        db,
+		logger:    log,
+		templates: []string{"{{greeting}} {{firstName}}, thanks for connecting. {{closing}}"},
+		variation: NewVariation([]string{"Hi"}, []string{strings.Repeat(long, 30)}),
+		rng:       utils.NewRand("templates"),
+	}
+	msg, _ := mm.generateMessage(&MessageRequest{FirstName: "Ana"})
+	if n := utf8.RuneCountInString(msg); n > maxMessageLength {
+		t.Errorf("message with a long closing is %d characters, max %d", n, maxMessageLength)
+	}
+	if !strings.HasPrefix(msg, "Hi Ana, thanks for connecting.") {
+		t.Errorf("message starts %q", msg[:40])
+	}
+}
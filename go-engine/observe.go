@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-rod/rod"
+)
+
+// observeOnlyRemaining returns how many observe-only runs the account has
+// left, this one included, or 0 once account.observe_only_runs is used up
+func (a *Automation) observeOnlyRemaining() int {
+	limit := a.config.Account.ObserveOnlyRuns
+	if limit == 0 {
+		return 0
+	}
+	runs, err := a.db.CountAuthenticatedSessions()
+	if err != nil {
+		// Without the run history, sending nothing is the safe choice
+		a.logger.LogError("count authenticated runs", err, nil)
+		return 1
+	}
+	if runs >= limit {
+		return 0
+	}
+	return limit - runs
+}
+
+// observing reports whether this run is in the observe-only period
+func (a *Automation) observing() bool {
+	return a.observeRemaining > 0
+}
+
+// runObserveStart announces an observe-only run and browses the feed
+// before the workflow steps
+func (a *Automation) runObserveStart(result *RunResult) {
+	result.ObserveOnly = true
+	a.logger.Info("observe-only run, no invitations or messages", "remaining", a.observeRemaining)
+	fmt.Printf("\nObserve-only run (%d of account.observe_only_runs left, this one included): invitations and messages are skipped\n", a.observeRemaining)
+
+	fmt.Println("Browsing the feed...")
+	aborted := a.inFlight(func(page *rod.Page) {
+		if err := a.searchModule.BrowseFeed(page); err != nil {
+			a.logger.LogError("browse feed", err, nil)
+			fmt.Printf("⚠ Could not browse the feed: %v\n", err)
+		}
+	})
+	if aborted {
+		a.logger.Warn("feed browse aborted at shutdown")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"linkedin-automation/proxy"
+)
+
+// pickProxy takes this session's proxy from the proxy.urls pool. A proxy
+// named by proxy.url or --proxy is used as is, and an attached browser
+// keeps its own
+func (a *Automation) pickProxy() error {
+	cfg := &a.config.Proxy
+	if cfg.URL != "" || len(cfg.URLs) == 0 || a.config.Browser.AttachURL != "" {
+		return nil
+	}
+
+	last, err := a.db.GetLastProxy()
+	if err != nil {
+		a.logger.LogError("load last proxy", err, nil)
+	}
+	checkURL := cfg.IPCheckURL
+	if checkURL == "" {
+		checkURL = a.config.LinkedIn.URL("/")
+	}
+	a.proxies = proxy.NewPool(cfg.URLs, cfg.Rotation, last, checkURL)
+
+	next, err := a.proxies.Next()
+	if err != nil {
+		return err
+	}
+	cfg.URL = next
+	a.logger.Info("picked proxy from pool", "proxy", cfg.ID(), "rotation", cfg.Rotation)
+	return nil
+}
+
+// rotateProxy replaces the session's proxy after cause, a failure that
+// looks like the proxy's, and relaunches the browser behind the next one.
+// A proxy that still passes its health check is kept, the failure being
+// someone else's. It reports whether there is a new proxy to retry with
+func (a *Automation) rotateProxy(cause error) bool {
+	if a.proxies == nil || !proxy.IsConnectionError(cause) {
+		return false
+	}
+	current := a.config.Proxy.URL
+	if err := a.proxies.Check(current); err == nil {
+		a.logger.Info("proxy passed its health check, not rotating", "proxy", proxy.ID(current), "cause", cause)
+		return false
+	}
+
+	a.proxies.MarkDead(current, cause)
+	a.logger.Warn("proxy marked dead", "proxy", proxy.ID(current), "cause", cause, "live", a.proxies.Live())
+	next, err := a.proxies.Next()
+	if err != nil {
+		a.logger.LogError("rotate proxy", err, nil)
+		return false
+	}
+
+	fmt.Printf("↻ Proxy %s failed, retrying through %s\n", proxy.ID(current), proxy.ID(next))
+	a.closeBrowser()
+	a.config.Proxy.URL = next
+	if err := a.launchBrowser(a.display); err != nil {
+		a.logger.LogError("relaunch browser", err, map[string]interface{}{"proxy": proxy.ID(next)})
+		return false
+	}
+	a.startProxySession()
+	return true
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"linkedin-automation/config"
+	"linkedin-automation/utils"
+)
+
+// Rotation strategies
+const (
+	RoundRobin = "round_robin"
+	Random     = "random"
+)
+
+// ErrExhausted is returned by Next once every proxy has been marked dead
+var ErrExhausted = errors.New("every proxy in the pool is marked dead")
+
+// checkTimeout bounds a health check request
+const checkTimeout = 15 * time.Second
+
+// connectionErrors are the Chromium network errors a failing proxy, or an
+// IP LinkedIn refuses to serve, shows up as
+var connectionErrors = []string{
+	"ERR_PROXY_",
+	"ERR_TUNNEL_CONNECTION_FAILED",
+	"ERR_SOCKS_CONNECTION_",
+	"ERR_CONNECTION_",
+	"ERR_TIMED_OUT",
+	"ERR_EMPTY_RESPONSE",
+}
+
+// IsConnectionError reports whether err looks like the proxy's fault
+// rather than the page's or the account's
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, code := range connectionErrors {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pool hands out the proxies of proxy.urls, one per browser session, and
+// skips those marked dead after failing a health check
+type Pool struct {
+	mu       sync.Mutex
+	urls     []string
+	strategy string
+	rng      *rand.Rand
+	next     int               // round robin position
+	dead     map[string]string // proxy URL to the failure that killed it
+	checkURL string
+}
+
+// NewPool creates a pool over urls. Round robin starts after last, the ID
+// of the proxy the previous session used, so consecutive runs take turns.
+// Health checks fetch checkURL through the proxy
+func NewPool(urls []string, strategy, last, checkURL string) *Pool {
+	p := &Pool{
+		urls:     urls,
+		strategy: strategy,
+		rng:      utils.NewRand("proxy"),
+		dead:     make(map[string]string),
+		checkURL: checkURL,
+	}
+	for i, u := range urls {
+		if last != "" && ID(u) == last {
+			p.next = i + 1
+			break
+		}
+	}
+	return p
+}
+
+// ID identifies a proxy URL without its credentials, as proxy sessions
+// record it
+func ID(proxyURL string) string {
+	return config.ProxyConfig{URL: proxyURL}.ID()
+}
+
+// Next returns the proxy for the next session
+func (p *Pool) Next() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var live []string
+	for _, u := range p.urls {
+		if _, dead := p.dead[u]; !dead {
+			live = append(live, u)
+		}
+	}
+	if len(live) == 0 {
+		return "", ErrExhausted
+	}
+
+	if p.strategy == Random {
+		return live[p.rng.Intn(len(live))], nil
+	}
+	for range p.urls {
+		u := p.urls[p.next%len(p.urls)]
+		p.next++
+		if _, dead := p.dead[u]; !dead {
+			return u, nil
+		}
+	}
+	return "", ErrExhausted
+}
+
+// MarkDead takes a proxy out of rotation for the rest of the run
+func (p *Pool) MarkDead(proxyURL string, cause error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dead[proxyURL] = cause.Error()
+}
+
+// Live returns how many proxies are still in rotation
+func (p *Pool) Live() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.urls) - len(p.dead)
+}
+
+// Check fetches the health check URL through a proxy. Go cannot speak
+// SOCKS4, so such proxies fail the check, as they would be judged by the
+// failure that prompted it
+func (p *Pool) Check(proxyURL string) error {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "socks4" {
+		return fmt.Errorf("cannot health check a socks4 proxy")
+	}
+
+	client := &http.Client{
+		Timeout:   checkTimeout,
+		Transport: &http.Transport{Proxy: http.ProxyURL(parsed)},
+	}
+	resp, err := client.Get(p.checkURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/logger"
+	"linkedin-automation/selectors"
+)
+
+// replayStepTimeout bounds how long a replayed lookup or page load waits
+const replayStepTimeout = 5 * time.Second
+
+// tracePlayer replays a recorded trace in the browser. Targets that are
+// selector registry keys resolve to the registry's selectors, any other
+// target is used as a CSS selector
+type tracePlayer struct {
+	page     *rod.Page
+	registry *selectors.Registry
+	fixtures string // directory of saved pages, empty to replay against the live site
+}
+
+// Navigate loads target, or with fixtures the saved page standing in for it
+func (p *tracePlayer) Navigate(target string) error {
+	if p.fixtures != "" {
+		path, err := fixturePath(p.fixtures, target)
+		if err != nil {
+			return err
+		}
+		target = "file://" + path
+	}
+	if err := p.page.Navigate(target); err != nil {
+		return err
+	}
+	return p.page.Timeout(replayStepTimeout).WaitLoad()
+}
+
+// Click clicks the first element target resolves to
+func (p *tracePlayer) Click(target string) error {
+	el, err := p.element(target)
+	if err != nil {
+		return err
+	}
+	return el.Click(proto.InputMouseButtonLeft, 1)
+}
+
+// Lookup finds the first element target resolves to
+func (p *tracePlayer) Lookup(target string) error {
+	_, err := p.element(target)
+	return err
+}
+
+func (p *tracePlayer) element(target string) (*rod.Element, error) {
+	candidates := p.registry.Get(target)
+	if len(candidates) == 0 {
+		candidates = []string{target}
+	}
+	for _, sel := range candidates {
+		if el, err := p.page.Timeout(replayStepTimeout).Element(sel); err == nil {
+			return el, nil
+		}
+	}
+	return nil, fmt.Errorf("no element matches %s", target)
+}
+
+// fixturePath maps a recorded URL to the saved page in dir standing in for
+// it: the URL path with its slashes turned into underscores, plus .html.
+// https://www.linkedin.com/in/jane-doe/ is in_jane-doe.html and the site
+// root is index.html
+func fixturePath(dir, target string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	name := strings.ReplaceAll(strings.Trim(u.Path, "/"), "/", "_")
+	if name == "" {
+		name = "index"
+	}
+	path, err := filepath.Abs(filepath.Join(dir, name+".html"))
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no fixture for %s: %w", target, err)
+	}
+	return path, nil
+}
+
+// replayTrace is the --replay-trace mode: it re-issues the navigations,
+// clicks and selector lookups of a trace recorded with debug.record_trace
+// and reports the first step whose outcome differs. Without fixtures it
+// runs against the live site in the saved session
+func (a *Automation) replayTrace(path, fixtures string) error {
+	events, err := logger.LoadTrace(path)
+	if err != nil {
+		return err
+	}
+
+	var page *rod.Page
+	if fixtures == "" {
+		page, _ = a.restoreSession()
+	}
+	if page == nil {
+		if page, err = a.browser.Page(proto.TargetCreateTarget{URL: "about:blank"}); err != nil {
+			return err
+		}
+	}
+	defer page.Close()
+
+	fmt.Printf("\nReplaying %d trace events from %s\n", len(events), path)
+	player := &tracePlayer{page: page, registry: a.selectors, fixtures: fixtures}
+	if err := logger.ReplayTrace(events, player); err != nil {
+		return err
+	}
+	fmt.Println("✓ Replay matched the recording")
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixturePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "in_jane-doe.html", "search_results_people.html"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("<html></html>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		url, want string
+	}{
+		{"https://www.linkedin.com/", "index.html"},
+		{"https://www.linkedin.com/in/jane-doe/", "in_jane-doe.html"},
+		{"https://www.linkedin.com/search/results/people/?keywords=go", "search_results_people.html"},
+	}
+	for _, tt := range tests {
+		got, err := fixturePath(dir, tt.url)
+		if err != nil {
+			t.Errorf("fixturePath(%q): %v", tt.url, err)
+			continue
+		}
+		if filepath.Base(got) != tt.want {
+			t.Errorf("fixturePath(%q) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	if _, err := fixturePath(dir, "https://www.linkedin.com/feed/"); err == nil {
+		t.Error("fixturePath found a fixture for /feed/, which has none")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// postRunOutputLimit caps how much of the post-run command's output is
+// logged
+const postRunOutputLimit = 2000
+
+// runReport is the JSON written to reporting.report_path and piped to
+// reporting.post_run_command
+type runReport struct {
+	*RunResult
+	Error string `json:",omitempty"` // the error the run ended with
+}
+
+// afterRun writes the run report and runs the post-run command. Neither
+// can end the process: failures are logged and the run carries on to exit
+func (a *Automation) afterRun(result *RunResult, runErr error) {
+	cfg := a.config.Reporting
+	if cfg.ReportPath == "" && cfg.PostRunCommand == "" {
+		return
+	}
+
+	report := runReport{RunResult: result}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		a.logger.LogError("encode run report", err, nil)
+		return
+	}
+
+	if cfg.ReportPath != "" {
+		if err := os.WriteFile(cfg.ReportPath, data, 0o600); err != nil {
+			a.logger.LogError("write run report", err, map[string]interface{}{"path": cfg.ReportPath})
+		} else {
+			a.logger.Info("run report written", "path", cfg.ReportPath)
+		}
+	}
+	if cfg.PostRunCommand != "" {
+		a.runPostRunCommand(data)
+	}
+}
+
+// runPostRunCommand runs reporting.post_run_command through the shell with
+// the report as JSON on stdin and, when reporting.report_path is set, the
+// report path as its last argument. It is killed after
+// reporting.post_run_timeout
+func (a *Automation) runPostRunCommand(report []byte) {
+	cfg := a.config.Reporting
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PostRunTimeout)
+	defer cancel()
+
+	args := []string{"-c", cfg.PostRunCommand, "sh"}
+	if cfg.ReportPath != "" {
+		args[1] += ` "$@"`
+		args = append(args, cfg.ReportPath)
+	}
+	cmd := exec.CommandContext(ctx, "sh", args...)
+	cmd.Stdin = bytes.NewReader(report)
+	cmd.Env = append(os.Environ(), "LINKEDIN_RUN_REPORT="+cfg.ReportPath)
+	// Children that keep the output pipes open must not hold us past the
+	// timeout either
+	cmd.WaitDelay = time.Second
+
+	started := time.Now()
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if len(output) > postRunOutputLimit {
+		output = output[:postRunOutputLimit] + "..."
+	}
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		a.logger.Warn("post-run command timed out", "timeout", cfg.PostRunTimeout, "output", output)
+	case err != nil:
+		a.logger.Warn("post-run command failed", "exit_code", exitCode, "error", err, "output", output)
+	default:
+		a.logger.Info("post-run command finished", "exit_code", exitCode,
+			"duration", time.Since(started).Round(time.Millisecond), "output", output)
+	}
+}
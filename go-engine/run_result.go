@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	"linkedin-automation/messaging"
+)
+
+// Reasons a run ended
+const (
+	StopCompleted     = "completed"
+	StopOutsideHours  = "outside_business_hours"
+	StopChallenge     = "security_challenge"
+	StopInterrupted   = "interrupted"
+	StopRetryBudget   = "retry_budget_exhausted"
+	StopError         = "error"
+	StopBetweenBursts = "between_bursts" // the next activity burst has not started yet
+	StopBurstsDone    = "bursts_done"    // every activity burst of the day has run
+)
+
+// RunResult is the outcome of one Automation.Run. The console summary is
+// printed from it, and programmatic callers can inspect it directly
+type RunResult struct {
+	StartedAt           time.Time
+	FinishedAt          time.Time
+	Duration            time.Duration
+	ProfilesFound       int
+	ConnectionsSent     int
+	ConnectionsFailed   int
+	FailureReasons      map[messaging.FailureReason]int
+	MessagesSent        int
+	MessagesFailed      int
+	ActionsAborted      int  // in-flight actions cut off by the shutdown grace period
+	ClockJumps          int  // wall clock jumps detected, usually sleep/resume
+	Throttled           int  // pauses for throttle responses from LinkedIn
+	ObserveOnly         bool // account break-in run: nothing was sent
+	Burst               int  // activity burst this run was, 1-based; 0 with a single burst per day
+	Bursts              int  // activity bursts planned for the day
+	AcceptedDetected    int
+	InvitationsAccepted int    // incoming invitations accepted
+	Challenge           string // challenge type when login was blocked, empty otherwise
+	StopReason          string
+}
+
+func newRunResult() *RunResult {
+	return &RunResult{
+		StartedAt:      time.Now(),
+		FailureReasons: make(map[messaging.FailureReason]int),
+	}
+}
+
+// recordConnectionFailure counts a failed connection request by reason
+func (r *RunResult) recordConnectionFailure(reason messaging.FailureReason) {
+	r.ConnectionsFailed++
+	r.FailureReasons[reason]++
+}
+
+// finish stamps the end time and fills in the stop reason if the run did
+// not set one itself
+func (r *RunResult) finish(err error) {
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt)
+	if r.StopReason == "" {
+		if err != nil {
+			r.StopReason = StopError
+		} else {
+			r.StopReason = StopCompleted
+		}
+	}
+}
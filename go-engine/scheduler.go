@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math/rand"
+
+	"linkedin-automation/utils"
+)
+
+// Batch sizes for interleaved steps, in actions
+const (
+	interleaveBatchMin = 2
+	interleaveBatchMax = 6
+)
+
+// interleavedSteps can be split into batches and mixed with each other
+var interleavedSteps = map[string]bool{
+	StepConnect:  true,
+	StepFollowUp: true,
+}
+
+// stepDependencies lists the steps that must have finished before a step
+// can start when steps are interleaved. Authentication is not a step:
+// every unit runs after it
+var stepDependencies = map[string][]string{
+	StepFollowUp: {StepDetectAccepted}, // message the connections just found accepted
+}
+
+// runWork is what the units of one run share
+type runWork struct {
+	connectStarted bool            // the search and suggestions phases ran
+	attempted      map[string]bool // profiles and connections acted on
+}
+
+func newRunWork() *runWork {
+	return &runWork{attempted: make(map[string]bool)}
+}
+
+// workUnit is one piece of the workflow: a step, or a batch of one
+type workUnit struct {
+	step  string
+	limit int // actions to take before yielding; 0 runs the step to the end
+}
+
+// workScheduler yields the workflow's units in order. Without
+// interleaving every step is one unit in the configured order, moved back
+// only as far as a step it depends on. With it, connection requests and
+// follow-up messages are cut into small batches taken in random turns, so
+// a run does not always do all of one before the other. Dependencies
+// between steps hold either way
+type workScheduler struct {
+	pending    []string // steps not finished yet, in the configured order
+	interleave bool
+	rng        *rand.Rand
+}
+
+// newWorkScheduler creates a scheduler over the steps in order
+func newWorkScheduler(order []string, interleave bool) *workScheduler {
+	return &workScheduler{
+		pending:    append([]string(nil), order...),
+		interleave: interleave,
+		rng:        utils.NewRand("scheduler"),
+	}
+}
+
+// next returns the next unit to run, or false when every step is done.
+// Batches must be reported with done once their step runs out of work;
+// whole steps are finished as they are handed out
+func (s *workScheduler) next() (workUnit, bool) {
+	var ready []string
+	for _, step := range s.pending {
+		if s.blocked(step) {
+			continue
+		}
+		ready = append(ready, step)
+		if !s.interleave {
+			break
+		}
+	}
+	if len(ready) == 0 {
+		return workUnit{}, false
+	}
+
+	step := ready[0]
+	if s.interleave {
+		step = ready[s.rng.Intn(len(ready))]
+	}
+	if !s.interleave || !interleavedSteps[step] {
+		s.done(step)
+		return workUnit{step: step}, true
+	}
+	size := interleaveBatchMin + s.rng.Intn(interleaveBatchMax-interleaveBatchMin+1)
+	return workUnit{step: step, limit: size}, true
+}
+
+// blocked reports whether a step still waits for a step it depends on
+func (s *workScheduler) blocked(step string) bool {
+	for _, dep := range stepDependencies[step] {
+		for _, pending := range s.pending {
+			if pending == dep {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// done marks a step finished
+func (s *workScheduler) done(step string) {
+	for i, pending := range s.pending {
+		if pending == step {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWorkSchedulerHoldsDependencies(t *testing.T) {
+	orders := [][]string{
+		{StepConnect, StepDetectAccepted, StepFollowUp},
+		{StepFollowUp, StepDetectAccepted, StepConnect},
+		{StepFollowUp, StepConnect, StepDetectAccepted},
+		{StepConnect, StepFollowUp},
+	}
+	for _, interleave := range []bool{false, true} {
+		for _, order := range orders {
+			for seed := int64(1); seed <= 50; seed++ {
+				s := newWorkScheduler(order, interleave)
+				s.rng = rand.New(rand.NewSource(seed))
+				work := rand.New(rand.NewSource(seed))
+				ran := make(map[string]bool)
+				for units := 0; ; units++ {
+					if units > 1000 {
+						t.Fatalf("interleave=%v order=%v: scheduler never finished", interleave, order)
+					}
+					unit, ok := s.next()
+					if !ok {
+						break
+					}
+					if unit.step == StepFollowUp && containsStep(order, StepDetectAccepted) && !ran[StepDetectAccepted] {
+						t.Fatalf("interleave=%v order=%v seed=%d: follow_up handed out before detect_accepted", interleave, order, seed)
+					}
+					if unit.limit == 0 {
+						ran[unit.step] = true
+						continue
+					}
+					if !interleave {
+						t.Fatalf("interleave=false: got a batch of %s", unit.step)
+					}
+					// a batch sometimes finds its step out of work
+					if work.Intn(3) == 0 {
+						s.done(unit.step)
+					}
+				}
+				for _, step := range order {
+					if step != StepConnect && step != StepFollowUp && !ran[step] {
+						t.Errorf("interleave=%v order=%v: %s never ran", interleave, order, step)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWorkSchedulerKeepsOrderWithoutInterleaving(t *testing.T) {
+	order := []string{StepFollowUp, StepConnect, StepDetectAccepted}
+	s := newWorkScheduler(order, false)
+	var got []string
+	for {
+		unit, ok := s.next()
+		if !ok {
+			break
+		}
+		got = append(got, unit.step)
+	}
+	// follow_up waits for detect_accepted, connect keeps its place
+	want := []string{StepConnect, StepDetectAccepted, StepFollowUp}
+	if len(got) != len(want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("steps = %v, want %v", got, want)
+		}
+	}
+}
+
+func containsStep(steps []string, step string) bool {
+	for _, s := range steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// ProfileActivity is what the activity section of a profile shows
+type ProfileActivity struct {
+	Visible bool          // the profile has an activity section
+	Posted  bool          // the section lists at least one dated post, comment or reaction
+	Age     time.Duration // age of the most recent entry, when Posted
+}
+
+// activityScript returns the text of the profile's activity section, or
+// null when the profile does not show one
+const activityScript = `() => {
+	const anchor = document.querySelector('#content_collections, #recent_activity');
+	let section = anchor ? anchor.closest('section') : null;
+	if (!section) {
+		section = [...document.querySelectorAll('main section')].find(s => {
+			const heading = s.querySelector('h2');
+			return heading && /^\s*activity\s*$/i.test(heading.innerText.split('\n')[0]);
+		}) || null;
+	}
+	return section ? section.innerText : null;
+}`
+
+// activityAgePattern matches the relative timestamps on activity entries,
+// such as "2d •", "3w •" or "1yr • Edited"
+var activityAgePattern = regexp.MustCompile(`(?:^|\s)(\d+)\s?(mo|yr|w|d|h|m)\s*•`)
+
+// activityUnits converts timestamp units to durations. Months and years
+// are approximate, which is enough for a recency cut-off in days
+var activityUnits = map[string]time.Duration{
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"mo": 30 * 24 * time.Hour,
+	"yr": 365 * 24 * time.Hour,
+}
+
+// ReadProfileActivity reads how recently an open profile was active from
+// its activity section
+func ReadProfileActivity(page *rod.Page) (*ProfileActivity, error) {
+	res, err := page.Timeout(5 * time.Second).Eval(activityScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile activity: %w", err)
+	}
+	if res.Value.Nil() {
+		return &ProfileActivity{}, nil
+	}
+
+	activity := &ProfileActivity{Visible: true}
+	if age, ok := LatestActivityAge(res.Value.Str()); ok {
+		activity.Posted = true
+		activity.Age = age
+	}
+	return activity, nil
+}
+
+// LatestActivityAge returns the age of the most recent dated entry in the
+// text of an activity section
+func LatestActivityAge(text string) (time.Duration, bool) {
+	var latest time.Duration
+	found := false
+	for _, m := range activityAgePattern.FindAllStringSubmatch(strings.ToLower(text), -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		age := time.Duration(n) * activityUnits[m[2]]
+		if !found || age < latest {
+			latest, found = age, true
+		}
+	}
+	return latest, found
+}
@@ -0,0 +1,47 @@
+package search
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/logger"
+	"linkedin-automation/utils"
+)
+
+// Feed scroll depth range in pixels for one browse
+const (
+	feedScrollMin = 1500
+	feedScrollMax = 4000
+)
+
+// BrowseFeed opens the home feed and reads down it for a while without
+// interacting. It gives new accounts ordinary activity before any outreach
+func (s *Searcher) BrowseFeed(page *rod.Page) error {
+	feedURL := s.site.URL("/feed/")
+	time.Sleep(s.timing.GetPreNavigationDelay())
+	s.pacer.Wait()
+	err := page.Navigate(feedURL)
+	s.logger.Trace(logger.TraceNavigate, feedURL, err)
+	if err != nil {
+		return err
+	}
+	time.Sleep(s.timing.GetPageLoadDelay())
+	if err := utils.WaitLoadBounded(page, s.site.PageLoadTimeout, s.logger); err != nil {
+		return err
+	}
+
+	rng := utils.NewRand("feed")
+	depth := feedScrollMin + rng.Intn(feedScrollMax-feedScrollMin+1)
+	for _, step := range s.scrolling.GenerateScrollSequence(depth, 0) {
+		page.Eval(fmt.Sprintf(`() => window.scrollBy(0, %d)`, step.DeltaY))
+		time.Sleep(step.Duration)
+		if s.scrolling.ShouldPauseWhileScrolling() {
+			time.Sleep(s.scrolling.GetRandomScrollPause())
+		}
+	}
+	time.Sleep(s.timing.GetThinkTime())
+	s.logger.Info("browsed feed", "depth", depth)
+	return nil
+}
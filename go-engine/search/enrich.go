@@ -0,0 +1,99 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/utils"
+)
+
+// enrichScrollDepth is how far down a profile an enrichment visit scrolls
+const enrichScrollDepth = 600
+
+// enrichVisitCount returns how many of n discovered profiles to visit:
+// enrich_fraction of them rounded up, capped at enrich_max_visits when set
+func (s *Searcher) enrichVisitCount(n int) int {
+	if s.config.EnrichFraction <= 0 || n == 0 {
+		return 0
+	}
+	visits := int(math.Ceil(s.config.EnrichFraction * float64(n)))
+	if visits > n {
+		visits = n
+	}
+	if s.config.EnrichMaxVisits > 0 && visits > s.config.EnrichMaxVisits {
+		visits = s.config.EnrichMaxVisits
+	}
+	return visits
+}
+
+// enrich visits a random sample of the discovered profiles to read details
+// the result cards do not show (full headline, mutual connections), stores
+// them and updates the profiles in place. It returns how many were visited
+func (s *Searcher) enrich(page *rod.Page, profiles []ProfileInfo) int {
+	visits := s.enrichVisitCount(len(profiles))
+	if visits == 0 {
+		return 0
+	}
+	s.logger.Info("enriching profiles", "visits", visits, "discovered", len(profiles))
+
+	rng := utils.NewRand("enrich")
+	order := rng.Perm(len(profiles))[:visits]
+
+	visited := 0
+	for i, idx := range order {
+		if i > 0 {
+			time.Sleep(s.timing.GetRandomizedDelay(s.config.EnrichDelayMinMs, s.config.EnrichDelayMaxMs))
+		}
+		if err := s.enrichProfile(page, &profiles[idx]); err != nil {
+			s.logger.LogError("enrich profile", err, map[string]interface{}{"profile": profiles[idx].ProfileURL})
+			continue
+		}
+		visited++
+	}
+	return visited
+}
+
+// enrichProfile opens one profile and records what it shows
+func (s *Searcher) enrichProfile(page *rod.Page, profile *ProfileInfo) error {
+	time.Sleep(s.timing.GetPreNavigationDelay())
+	s.pacer.Wait()
+	err := page.Navigate(profile.ProfileURL)
+	s.logger.Trace(logger.TraceNavigate, profile.ProfileURL, err)
+	if err != nil {
+		return err
+	}
+	time.Sleep(s.timing.GetPageLoadDelay())
+	if err := utils.WaitLoadBounded(page, s.site.PageLoadTimeout, s.logger); err != nil {
+		s.logger.LogError("page load", err, nil)
+	}
+
+	// Glance down the profile the way a person would
+	for _, step := range s.scrolling.GenerateScrollSequence(enrichScrollDepth, 0) {
+		page.Eval(fmt.Sprintf(`() => window.scrollBy(0, %d)`, step.DeltaY))
+		time.Sleep(step.Duration)
+	}
+	time.Sleep(s.timing.GetThinkTime())
+
+	if headlineEl, err := page.Timeout(2 * time.Second).Element(`.text-body-medium.break-words`); err == nil {
+		if headline, err := headlineEl.Text(); err == nil && strings.TrimSpace(headline) != "" {
+			profile.JobTitle = strings.TrimSpace(headline)
+		}
+	}
+	if mutual, err := ExtractMutualConnections(page); err == nil && mutual.Count > 0 {
+		profile.Mutual = *mutual
+	}
+
+	return s.db.SaveProfileDetails(&database.ProfileDetails{
+		ProfileURL:  profile.ProfileURL,
+		Headline:    profile.JobTitle,
+		MutualCount: profile.Mutual.Count,
+		MutualNames: profile.Mutual.Names,
+		UpdatedAt:   time.Now(),
+	})
+}
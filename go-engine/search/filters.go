@@ -0,0 +1,36 @@
+package search
+
+import "strings"
+
+// networkCodes maps connection degrees to the codes of LinkedIn's network
+// search facet
+var networkCodes = map[string]string{
+	"1st": "F", "1": "F", "first": "F", "f": "F",
+	"2nd": "S", "2": "S", "second": "S", "s": "S",
+	"3rd": "O", "3": "O", "third": "O", "3rd+": "O", "o": "O",
+}
+
+// NetworkFacet converts connection degrees such as "2nd" and "3rd" into
+// the codes of the network facet, in F, S, O order and without repeats.
+// Degrees it does not know are returned as unknown
+func NetworkFacet(degrees []string) (codes, unknown []string) {
+	wanted := make(map[string]bool)
+	for _, degree := range degrees {
+		code, ok := networkCodes[strings.ToLower(strings.TrimSpace(degree))]
+		if !ok {
+			unknown = append(unknown, degree)
+			continue
+		}
+		wanted[code] = true
+	}
+	for _, code := range []string{"F", "S", "O"} {
+		if wanted[code] {
+			codes = append(codes, code)
+		}
+	}
+	return codes, unknown
+}
+
+// openToWorkSelector matches the "Open to work" frame on a result card's
+// photo
+const openToWorkSelector = `img[alt*="open to work" i], .entity-result__open-to-work, [data-test-open-to-work]`
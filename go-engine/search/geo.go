@@ -0,0 +1,211 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/config"
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+)
+
+// locationURNs are the geo URNs of common locations, looked up before the
+// cache and the typeahead endpoint
+var locationURNs = map[string]string{
+	"san francisco":          "90009496",
+	"san francisco bay area": "90009496",
+	"new york":               "90009563",
+	"new york city":          "90009563",
+	"los angeles":            "90009494",
+	"chicago":                "90009457",
+	"seattle":                "90009483",
+	"boston":                 "90009611",
+	"austin":                 "90009493",
+	"denver":                 "90009481",
+	"united states":          "103644278",
+}
+
+// geoTypeaheadPath is LinkedIn's location autocomplete, the endpoint behind
+// the location filter of people search
+const geoTypeaheadPath = "/voyager/api/typeahead/hitsV2"
+
+// geoTypeaheadScript fetches a typeahead URL from the page, with the
+// session's cookies and the CSRF token LinkedIn expects from its own
+// scripts
+const geoTypeaheadScript = `async (path) => {
+	const token = (document.cookie.match(/JSESSIONID="?([^";]+)/) || [])[1] || '';
+	const res = await fetch(path, {
+		credentials: 'include',
+		headers: { 'csrf-token': token, 'accept': 'application/json', 'x-restli-protocol-version': '2.0.0' },
+	});
+	return { status: res.status, body: res.ok ? await res.text() : '' };
+}`
+
+// geoURNPattern matches the numeric ID of a geo URN, in either its
+// urn:li:geo or urn:li:fs_geo form
+var geoURNPattern = regexp.MustCompile(`^urn:li:(?:fs_)?geo:(\d+)$`)
+
+// GeoResolver maps location names to geo URNs: first the built-in table,
+// then the geo_cache table, then LinkedIn's typeahead endpoint, whose
+// answers are cached. An unreachable endpoint leaves the location
+// unresolved rather than failing the search
+type GeoResolver struct {
+	db     database.Store
+	logger *logger.Logger
+	site   config.LinkedInConfig
+
+	fetch    typeaheadFetch
+	mu       sync.Mutex
+	resolved map[string]string // this run's lookups, by normalized location
+}
+
+// typeaheadFetch requests a typeahead URL, returning the response status and
+// body
+type typeaheadFetch func(page *rod.Page, path string) (int, []byte, error)
+
+// NewGeoResolver creates a GeoResolver
+func NewGeoResolver(db database.Store, log *logger.Logger, site config.LinkedInConfig) *GeoResolver {
+	return &GeoResolver{
+		db:       db,
+		logger:   log,
+		site:     site,
+		fetch:    pageFetch,
+		resolved: make(map[string]string),
+	}
+}
+
+// normalizeLocation is the key a location is looked up under
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.Join(strings.Fields(location), " "))
+}
+
+// Lookup returns the URN of a location from the built-in table or the
+// cache, without querying LinkedIn
+func (g *GeoResolver) Lookup(location string) (string, bool) {
+	key := normalizeLocation(location)
+	if urn, ok := locationURNs[key]; ok {
+		return urn, true
+	}
+
+	g.mu.Lock()
+	urn, ok := g.resolved[key]
+	g.mu.Unlock()
+	if ok {
+		return urn, urn != ""
+	}
+
+	urn, err := g.db.GetGeoUrn(key)
+	if err != nil {
+		g.logger.LogError("load cached geo URN", err, map[string]interface{}{"location": location})
+		return "", false
+	}
+	if urn == "" {
+		return "", false
+	}
+	g.remember(key, urn)
+	return urn, true
+}
+
+// Resolve returns the URN of a location, asking LinkedIn's typeahead
+// through page when neither the table nor the cache knows it. A location
+// the endpoint could not resolve is not asked about again this run
+func (g *GeoResolver) Resolve(page *rod.Page, location string) (string, bool) {
+	if urn, ok := g.Lookup(location); ok {
+		return urn, true
+	}
+	key := normalizeLocation(location)
+	g.mu.Lock()
+	_, tried := g.resolved[key]
+	g.mu.Unlock()
+	if tried || page == nil {
+		return "", false
+	}
+
+	urn, err := g.typeahead(page, location)
+	g.remember(key, urn)
+	if err != nil {
+		g.logger.Warn("geo URN lookup failed", "location", location, "error", err)
+		return "", false
+	}
+	if urn == "" {
+		g.logger.Warn("LinkedIn knows no location by this name", "location", location)
+		return "", false
+	}
+
+	if err := g.db.SaveGeoUrn(key, urn); err != nil {
+		g.logger.LogError("cache geo URN", err, map[string]interface{}{"location": location})
+	}
+	g.logger.Info("resolved geo URN", "location", location, "urn", urn)
+	return urn, true
+}
+
+// remember records a lookup for the rest of the run; "" marks a location
+// that could not be resolved
+func (g *GeoResolver) remember(key, urn string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.resolved[key] = urn
+}
+
+// typeahead asks LinkedIn's location autocomplete for location
+func (g *GeoResolver) typeahead(page *rod.Page, location string) (string, error) {
+	query := url.Values{}
+	query.Set("keywords", location)
+	query.Set("origin", "OTHER")
+	query.Set("q", "type")
+	query.Set("type", "GEO")
+	path := g.site.URL(geoTypeaheadPath) + "?" + query.Encode() +
+		"&queryContext=List(geoVersion-%3E3,bingGeoSubTypeFilters-%3EMARKET_AREA%7CCOUNTRY_REGION%7CADMIN_DIVISION_1%7CCITY)"
+
+	status, body, err := g.fetch(page, path)
+	if err != nil {
+		return "", fmt.Errorf("typeahead request: %w", err)
+	}
+	if status != 200 {
+		return "", fmt.Errorf("typeahead returned status %d", status)
+	}
+	return ParseGeoTypeahead(body)
+}
+
+// pageFetch runs geoTypeaheadScript in page
+func pageFetch(page *rod.Page, path string) (int, []byte, error) {
+	res, err := page.Timeout(10*time.Second).Eval(geoTypeaheadScript, path)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.Value.Get("status").Int(), []byte(res.Value.Get("body").Str()), nil
+}
+
+// ParseGeoTypeahead returns the numeric geo URN of the first hit in a
+// typeahead response, or "" when there are no hits
+func ParseGeoTypeahead(body []byte) (string, error) {
+	var resp struct {
+		Elements []struct {
+			TargetURN string `json:"targetUrn"`
+			HitInfo   struct {
+				Geo struct {
+					URN string `json:"geoUrn"`
+				} `json:"com.linkedin.voyager.typeahead.TypeaheadGeo"`
+			} `json:"hitInfo"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("decode typeahead response: %w", err)
+	}
+
+	for _, el := range resp.Elements {
+		for _, urn := range []string{el.TargetURN, el.HitInfo.Geo.URN} {
+			if m := geoURNPattern.FindStringSubmatch(urn); m != nil {
+				return m[1], nil
+			}
+		}
+	}
+	return "", nil
+}
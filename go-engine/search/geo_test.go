@@ -0,0 +1,126 @@
+package search
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/config"
+)
+
+// typeaheadHits are typeahead responses by the keywords they answer
+var typeaheadHits = map[string]string{
+	"Lisbon":  `{"elements":[{"targetUrn":"urn:li:fs_geo:105047339","hitInfo":{"com.linkedin.voyager.typeahead.TypeaheadGeo":{"geoUrn":"urn:li:geo:105047339"}}}]}`,
+	"Porto":   `{"elements":[{"targetUrn":"urn:li:company:1","hitInfo":{"com.linkedin.voyager.typeahead.TypeaheadGeo":{"geoUrn":"urn:li:geo:102815138"}}}]}`,
+	"Nowhere": `{"elements":[]}`,
+}
+
+// newTestGeoResolver returns a GeoResolver whose typeahead requests go to
+// a local server answering from typeaheadHits, and a count of the requests
+func newTestGeoResolver(t *testing.T) (*GeoResolver, *int) {
+	t.Helper()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != geoTypeaheadPath || r.URL.Query().Get("type") != "GEO" {
+			http.NotFound(w, r)
+			return
+		}
+		body, ok := typeaheadHits[r.URL.Query().Get("keywords")]
+		if !ok {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	g := newTestSearcher(t, config.SearchConfig{}).geo
+	g.site = config.LinkedInConfig{BaseURL: srv.URL}
+	g.fetch = func(_ *rod.Page, path string) (int, []byte, error) {
+		res, err := http.Get(path)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return res.StatusCode, body, err
+	}
+	return g, &requests
+}
+
+func TestGeoResolverTypeahead(t *testing.T) {
+	g, requests := newTestGeoResolver(t)
+	page := &rod.Page{} // only handed to the fetch func
+
+	tests := []struct {
+		location string
+		urn      string
+		ok       bool
+	}{
+		{"Lisbon", "105047339", true},
+		{"Porto", "102815138", true},
+		{"Nowhere", "", false},
+		{"Failing", "", false},
+	}
+	for _, tt := range tests {
+		urn, ok := g.Resolve(page, tt.location)
+		if urn != tt.urn || ok != tt.ok {
+			t.Errorf("Resolve(%q) = %q, %v, want %q, %v", tt.location, urn, ok, tt.urn, tt.ok)
+		}
+	}
+	if *requests != len(tests) {
+		t.Fatalf("%d typeahead requests, want %d", *requests, len(tests))
+	}
+
+	// answers and misses are remembered for the rest of the run
+	for _, tt := range tests {
+		g.Resolve(page, tt.location)
+	}
+	if *requests != len(tests) {
+		t.Errorf("%d typeahead requests after asking again, want %d", *requests, len(tests))
+	}
+
+	// only resolved locations are cached for later runs
+	for _, tt := range tests {
+		cached, err := g.db.GetGeoUrn(normalizeLocation(tt.location))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cached != tt.urn {
+			t.Errorf("cached URN of %q = %q, want %q", tt.location, cached, tt.urn)
+		}
+	}
+}
+
+func TestGeoResolverSkipsTypeaheadForKnownLocations(t *testing.T) {
+	g, requests := newTestGeoResolver(t)
+	if urn, ok := g.Resolve(&rod.Page{}, " NEW  york "); urn != "90009563" || !ok {
+		t.Errorf("Resolve = %q, %v, want 90009563, true", urn, ok)
+	}
+	if *requests != 0 {
+		t.Errorf("%d typeahead requests for a built-in location, want 0", *requests)
+	}
+}
+
+func TestParseGeoTypeahead(t *testing.T) {
+	tests := []struct {
+		body    string
+		urn     string
+		wantErr bool
+	}{
+		{typeaheadHits["Lisbon"], "105047339", false},
+		{typeaheadHits["Porto"], "102815138", false},
+		{typeaheadHits["Nowhere"], "", false},
+		{`<html>`, "", true},
+	}
+	for _, tt := range tests {
+		urn, err := ParseGeoTypeahead([]byte(tt.body))
+		if urn != tt.urn || (err != nil) != tt.wantErr {
+			t.Errorf("ParseGeoTypeahead(%s) = %q, %v, want %q, error %v", tt.body, urn, err, tt.urn, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// MutualConnections is what a profile shows about shared connections
+type MutualConnections struct {
+	Count int      // total mutual connections, 0 when none are shown
+	Names []string // the names LinkedIn lists by name, usually one or two
+}
+
+// mutualScript returns the first "mutual connection(s)" line on the page's
+// top card, or an empty string
+const mutualScript = `() => {
+	const top = document.querySelector('.pv-top-card, .ph5, main section') || document.body;
+	const candidates = top.querySelectorAll('a[href*="facetNetwork"], a[href*="mutual"], span, li');
+	for (const el of candidates) {
+		const text = (el.innerText || '').trim();
+		if (/mutual connection/i.test(text) && text.length < 200) return text;
+	}
+	return '';
+}`
+
+var (
+	mutualCountOnly = regexp.MustCompile(`(?i)^([\d,]+)\s+mutual connections?$`)
+	mutualOthers    = regexp.MustCompile(`(?i)^(.+?)\s+and\s+([\d,]+)\s+other\s+mutual connections?$`)
+	mutualNamed     = regexp.MustCompile(`(?i)^(.+?)\s+(?:is|are)\s+(?:a\s+)?mutual connections?$`)
+)
+
+// ParseMutualConnections reads LinkedIn's mutual connection summary, e.g.
+// "12 mutual connections", "Jane Doe is a mutual connection", "Jane Doe and
+// John Roe are mutual connections" or "Jane Doe, John Roe and 10 other
+// mutual connections". Unrecognized text yields a zero value
+func ParseMutualConnections(text string) MutualConnections {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if m := mutualCountOnly.FindStringSubmatch(text); m != nil {
+		return MutualConnections{Count: parseCount(m[1])}
+	}
+	if m := mutualOthers.FindStringSubmatch(text); m != nil {
+		names := splitNames(m[1])
+		return MutualConnections{Count: len(names) + parseCount(m[2]), Names: names}
+	}
+	if m := mutualNamed.FindStringSubmatch(text); m != nil {
+		names := splitNames(m[1])
+		return MutualConnections{Count: len(names), Names: names}
+	}
+	return MutualConnections{}
+}
+
+// splitNames splits "A, B and C" into its names
+func splitNames(list string) []string {
+	var names []string
+	for _, part := range strings.Split(strings.ReplaceAll(list, " and ", ", "), ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseCount parses a count that may contain thousands separators
+func parseCount(s string) int {
+	n, _ := strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+	return n
+}
+
+// ExtractMutualConnections reads the mutual connection summary from an open
+// profile page. Profiles that show none return a zero value
+func ExtractMutualConnections(page *rod.Page) (*MutualConnections, error) {
+	res, err := page.Timeout(5 * time.Second).Eval(mutualScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mutual connections: %w", err)
+	}
+	mutual := ParseMutualConnections(res.Value.Str())
+	return &mutual, nil
+}
@@ -8,8 +8,9 @@ import (
 // ProfileURLPattern matches LinkedIn profile URLs
 var ProfileURLPattern = regexp.MustCompile(`https?://(www\.)?linkedin\.com/in/([a-zA-Z0-9\-_%]+)/?`)
 
-// ExtractProfileURLs extracts all valid LinkedIn profile URLs from HTML content
-func ExtractProfileURLs(html string) []string {
+// ExtractProfileURLs extracts all valid LinkedIn profile URLs from HTML content,
+// normalizing them onto baseURL
+func ExtractProfileURLs(html, baseURL string) []string {
 	matches := ProfileURLPattern.FindAllStringSubmatch(html, -1)
 
 	urlSet := make(map[string]bool)
@@ -18,7 +19,7 @@ func ExtractProfileURLs(html string) []string {
 	for _, match := range matches {
 		if len(match) >= 3 {
 			// Normalize URL
-			normalized := "https://www.linkedin.com/in/" + match[2] + "/"
+			normalized := strings.TrimRight(baseURL, "/") + "/in/" + match[2] + "/"
 
 			if !urlSet[normalized] {
 				urlSet[normalized] = true
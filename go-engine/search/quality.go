@@ -0,0 +1,84 @@
+package search
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// Weights of each establishment signal in ProfileQuality.Score
+const (
+	connectionsWeight = 0.4
+	positionsWeight   = 0.3
+	photoWeight       = 0.3
+
+	// fullConnections and fullPositions are the counts at which a signal
+	// contributes its full weight
+	fullConnections = 500
+	fullPositions   = 3
+)
+
+// ProfileQuality holds signals of how established a profile looks
+type ProfileQuality struct {
+	Connections int     // connection count shown on the top card; 500 for "500+"
+	Positions   int     // entries in the experience section
+	HasPhoto    bool    // a real photo rather than the ghost placeholder
+	Private     bool    // connection count and experience are both hidden
+	Score       float64 // 0 (brand new) to 1 (well established)
+}
+
+// qualityScript collects the signals from the rendered profile page
+const qualityScript = `() => {
+	const top = document.querySelector('.pv-top-card, .ph5, main section') || document.body;
+	const match = (top.innerText || '').match(/([\d,]+)\+?\s+connections/i);
+	const connections = match ? parseInt(match[1].replace(/,/g, ''), 10) : -1;
+
+	const anchor = document.querySelector('#experience');
+	const section = anchor ? anchor.closest('section') : null;
+	const positions = section ? section.querySelectorAll('li.artdeco-list__item').length : -1;
+
+	const img = document.querySelector('img.pv-top-card-profile-picture__image, img.pv-top-card-profile-picture__image--show, .pv-top-card__photo img');
+	const hasPhoto = !!img && !/ghost/i.test(img.className + ' ' + (img.getAttribute('src') || ''));
+
+	return { connections, positions, hasPhoto };
+}`
+
+// AssessProfileQuality reads establishment signals from an open profile
+// page and combines them into a score
+func AssessProfileQuality(page *rod.Page) (*ProfileQuality, error) {
+	res, err := page.Timeout(5 * time.Second).Eval(qualityScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile signals: %w", err)
+	}
+
+	connections := res.Value.Get("connections").Int()
+	positions := res.Value.Get("positions").Int()
+	q := &ProfileQuality{
+		Connections: connections,
+		Positions:   positions,
+		HasPhoto:    res.Value.Get("hasPhoto").Bool(),
+		Private:     connections < 0 && positions < 0,
+	}
+	if q.Connections < 0 {
+		q.Connections = 0
+	}
+	if q.Positions < 0 {
+		q.Positions = 0
+	}
+
+	q.Score = connectionsWeight*fraction(q.Connections, fullConnections) +
+		positionsWeight*fraction(q.Positions, fullPositions)
+	if q.HasPhoto {
+		q.Score += photoWeight
+	}
+	return q, nil
+}
+
+// fraction returns n/full capped at 1
+func fraction(n, full int) float64 {
+	if n >= full {
+		return 1
+	}
+	return float64(n) / float64(full)
+}
@@ -13,32 +13,42 @@ import (
 	"linkedin-automation/database"
 	"linkedin-automation/logger"
 	"linkedin-automation/stealth"
+	"linkedin-automation/utils"
 )
 
 // Searcher handles LinkedIn user search
 type Searcher struct {
 	config    config.SearchConfig
-	db        *database.DB
+	db        database.Store
 	logger    *logger.Logger
 	timing    *stealth.TimingController
 	scrolling *stealth.ScrollController
 	mouse     *stealth.MouseHoverController
+	pacer     *stealth.NavigationPacer
+	site      config.LinkedInConfig
+	geo       *GeoResolver
 }
 
 // NewSearcher creates a new Searcher
 func NewSearcher(
 	cfg config.SearchConfig,
-	db *database.DB,
+	db database.Store,
 	log *logger.Logger,
 	stealthCfg config.StealthConfig,
+	pacer *stealth.NavigationPacer,
+	site config.LinkedInConfig,
 ) *Searcher {
+	log = log.WithComponent("search")
 	return &Searcher{
 		config:    cfg,
 		db:        db,
-		logger:    log.WithComponent("search"),
+		logger:    log,
 		timing:    stealth.NewTimingController(stealthCfg.Timing),
 		scrolling: stealth.NewScrollController(stealthCfg.Scrolling),
 		mouse:     stealth.NewMouseHoverController(stealthCfg.Mouse),
+		pacer:     pacer,
+		site:      site,
+		geo:       NewGeoResolver(db, log, site),
 	}
 }
 
@@ -50,20 +60,37 @@ type ProfileInfo struct {
 	JobTitle   string
 	Company    string
 	Location   string
+	PageNumber int // search results page the profile was found on
+	Position   int // 1-based position on that page
+	Mutual     MutualConnections
+	OpenToWork bool // the card shows the "Open to work" frame
 }
 
+// Reasons a search came back without new profiles
+const (
+	EmptyNoResults    = "no_results"    // LinkedIn showed its "No results found" state
+	EmptyChallenge    = "challenge"     // redirected to a checkpoint, captcha or auth wall
+	EmptyParseFailure = "parse_failure" // results rendered but no profile links were parsed
+	EmptyAllProcessed = "all_processed" // profiles were found but all were already processed
+)
+
 // SearchResult contains the results of a search operation
 type SearchResult struct {
 	Profiles     []ProfileInfo
 	TotalFound   int
 	PagesScraped int
 	Duplicates   int
+	NotOpen      int // profiles left out by search.open_to_work_only
+	Enriched     int // profiles visited for details during the search
 	Errors       []string
+	FinalURL     string // URL LinkedIn actually served for the query
+	EmptyReason  string // set when Profiles is empty
+	Diagnostic   string // human-readable detail for EmptyReason
 }
 
 // BuildSearchURL constructs a LinkedIn search URL with filters
 func (s *Searcher) BuildSearchURL() string {
-	baseURL := "https://www.linkedin.com/search/results/people/"
+	baseURL := s.site.URL("/search/results/people/")
 
 	params := url.Values{}
 
@@ -77,9 +104,28 @@ func (s *Searcher) BuildSearchURL() string {
 
 	// Add location filter
 	if len(s.config.Locations) > 0 {
-		params.Set("geoUrn", s.getGeoUrn(s.config.Locations[0]))
+		urns := s.resolveGeoUrns(s.config.Locations)
+		if len(urns) == 0 {
+			s.logger.Warn("no geo URN known for any location, search will not be filtered by location", "locations", s.config.Locations)
+		} else {
+			params.Set("geoUrn", `["`+strings.Join(urns, `","`)+`"]`)
+		}
+	}
+
+	// Add connection degree filter
+	if len(s.config.ConnectionDegrees) > 0 {
+		codes, unknown := NetworkFacet(s.config.ConnectionDegrees)
+		for _, degree := range unknown {
+			s.logger.Warn("unknown connection degree, ignoring it", "degree", degree, "known", "1st, 2nd, 3rd")
+		}
+		if len(codes) > 0 {
+			params.Set("network", `["`+strings.Join(codes, `","`)+`"]`)
+		}
 	}
 
+	// People search has no stable URL facet for "Open to work", so
+	// open_to_work_only is applied to the result cards in Search
+
 	// Add company filter (if available)
 	// Note: Company filtering requires company LinkedIn IDs
 
@@ -88,62 +134,83 @@ func (s *Searcher) BuildSearchURL() string {
 	return baseURL + "?" + params.Encode()
 }
 
-// getGeoUrn returns LinkedIn geo URN for common locations
-func (s *Searcher) getGeoUrn(location string) string {
-	// Common location URNs (in production, this would be a larger mapping)
-	locationURNs := map[string]string{
-		"san francisco":        "90009496",
-		"san francisco bay area": "90009496",
-		"new york":             "90009563",
-		"new york city":        "90009563",
-		"los angeles":          "90009494",
-		"chicago":              "90009457",
-		"seattle":              "90009483",
-		"boston":               "90009611",
-		"austin":               "90009493",
-		"denver":               "90009481",
-		"united states":        "103644278",
-	}
-
-	lower := strings.ToLower(location)
-	if urn, ok := locationURNs[lower]; ok {
-		return "[\"" + urn + "\"]"
+// resolveGeoUrns returns the geo URNs of locations, in order and without
+// repeats. Locations without a known URN are logged and skipped
+func (s *Searcher) resolveGeoUrns(locations []string) []string {
+	var urns []string
+	seen := make(map[string]bool)
+	for _, location := range locations {
+		urn, ok := s.geo.Lookup(location)
+		if !ok {
+			s.logger.Warn("no geo URN known for location, leaving it out of the search", "location", location)
+			continue
+		}
+		if !seen[urn] {
+			seen[urn] = true
+			urns = append(urns, urn)
+		}
 	}
-	return ""
+	return urns
 }
 
 // Search performs a search and extracts profile URLs
 func (s *Searcher) Search(page *rod.Page) (*SearchResult, error) {
 	result := &SearchResult{}
 
+	// Locations missing from the table and the cache are looked up first
+	for _, location := range s.config.Locations {
+		s.geo.Resolve(page, location)
+	}
 	searchURL := s.BuildSearchURL()
 	s.logger.Info("starting search", "url", searchURL)
 
 	// Navigate to search
+	time.Sleep(s.timing.GetPreNavigationDelay())
+	s.pacer.Wait()
 	err := page.Navigate(searchURL)
+	s.logger.Trace(logger.TraceNavigate, searchURL, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to navigate: %w", err)
 	}
 
 	time.Sleep(s.timing.GetPageLoadDelay())
-	err = page.WaitLoad()
+	err = utils.WaitLoadBounded(page, s.site.PageLoadTimeout, s.logger)
 	if err != nil {
 		s.logger.LogError("page load", err, nil)
 	}
 
+	if info, err := page.Info(); err == nil {
+		result.FinalURL = info.URL
+		s.logger.Info("search page loaded", "final_url", info.URL)
+	}
+
 	// Process pages
 	for pageNum := 1; pageNum <= s.config.MaxPages; pageNum++ {
 		s.logger.Info("processing page", "page", pageNum)
 
 		// Extract profiles from current page
-		profiles, err := s.extractProfiles(page)
+		profiles, err := s.extractProfiles(page, pageNum)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("page %d: %v", pageNum, err))
 			continue
 		}
 
+		// An empty first page means the query itself produced nothing
+		if pageNum == 1 && len(profiles) == 0 {
+			result.EmptyReason, result.Diagnostic = s.diagnoseEmpty(page, searchURL, result.FinalURL)
+			s.logger.Warn("search returned no profiles",
+				"reason", result.EmptyReason,
+				"detail", result.Diagnostic,
+				"final_url", result.FinalURL)
+			break
+		}
+
 		// Filter duplicates
 		for _, profile := range profiles {
+			if s.config.OpenToWorkOnly && !profile.OpenToWork {
+				result.NotOpen++
+				continue
+			}
 			processed, _ := s.db.IsProfileProcessed(profile.ProfileURL)
 			if processed {
 				result.Duplicates++
@@ -167,17 +234,99 @@ func (s *Searcher) Search(page *rod.Page) (*SearchResult, error) {
 		}
 	}
 
+	result.Enriched = s.enrich(page, result.Profiles)
+
+	if len(result.Profiles) == 0 && result.EmptyReason == "" && result.TotalFound > 0 {
+		result.EmptyReason = EmptyAllProcessed
+		result.Diagnostic = fmt.Sprintf("all %d profiles found were already processed", result.TotalFound)
+		if result.NotOpen > 0 {
+			result.Diagnostic = fmt.Sprintf("of %d profiles found, %d were not open to work and the rest already processed",
+				result.TotalFound, result.NotOpen)
+		}
+	}
+
 	s.logger.Info("search complete",
 		"total_found", result.TotalFound,
 		"unique", len(result.Profiles),
 		"duplicates", result.Duplicates,
+		"not_open_to_work", result.NotOpen,
+		"enriched", result.Enriched,
 		"pages", result.PagesScraped)
 
 	return result, nil
 }
 
-// extractProfiles extracts profile information from the current page
-func (s *Searcher) extractProfiles(page *rod.Page) ([]ProfileInfo, error) {
+// emptyStateScript reports whether LinkedIn rendered its explicit empty
+// state, and whether any result cards rendered at all
+const emptyStateScript = `() => ({
+	noResults: !!document.querySelector('.search-reusable-search-no-results, .search-no-results, .artdeco-empty-state')
+		|| /no results found/i.test(document.body.innerText),
+	cards: document.querySelectorAll('.reusable-search__result-container, .entity-result').length,
+	captcha: !!document.querySelector('iframe[src*="captcha"], #captcha-internal'),
+})`
+
+// diagnoseEmpty works out why the current search page yielded no profiles
+func (s *Searcher) diagnoseEmpty(page *rod.Page, requestedURL, finalURL string) (string, string) {
+	if strings.Contains(finalURL, "/checkpoint/") || strings.Contains(finalURL, "/authwall") {
+		return EmptyChallenge, "redirected to " + finalURL
+	}
+
+	res, err := page.Eval(emptyStateScript)
+	if err != nil {
+		return EmptyParseFailure, fmt.Sprintf("could not inspect page: %v", err)
+	}
+	if res.Value.Get("captcha").Bool() {
+		return EmptyChallenge, "captcha present on search page"
+	}
+
+	var notes []string
+	if dropped := droppedParams(requestedURL, finalURL); len(dropped) > 0 {
+		notes = append(notes, "LinkedIn dropped query parameters: "+strings.Join(dropped, ", "))
+	}
+	var unknown []string
+	for _, location := range s.config.Locations {
+		if _, ok := s.geo.Lookup(location); !ok {
+			unknown = append(unknown, fmt.Sprintf("%q", location))
+		}
+	}
+	if len(unknown) > 0 {
+		notes = append(notes, "no geo URN known for location "+strings.Join(unknown, ", "))
+	}
+
+	if res.Value.Get("noResults").Bool() {
+		notes = append([]string{"LinkedIn reported no results for the query"}, notes...)
+		return EmptyNoResults, strings.Join(notes, "; ")
+	}
+
+	notes = append([]string{fmt.Sprintf("%d result cards rendered but no profile links parsed", res.Value.Get("cards").Int())}, notes...)
+	return EmptyParseFailure, strings.Join(notes, "; ")
+}
+
+// droppedParams lists query parameters present in requested but missing or
+// emptied in final
+func droppedParams(requested, final string) []string {
+	req, err := url.Parse(requested)
+	if err != nil {
+		return nil
+	}
+	fin, err := url.Parse(final)
+	if err != nil {
+		return nil
+	}
+
+	var dropped []string
+	finalQuery := fin.Query()
+	for key, values := range req.Query() {
+		if len(values) > 0 && values[0] != "" && finalQuery.Get(key) == "" {
+			dropped = append(dropped, key)
+		}
+	}
+	return dropped
+}
+
+// extractProfiles extracts profile information from the current page,
+// tagging each profile with the page number and its position on the page
+func (s *Searcher) extractProfiles(page *rod.Page, pageNum int) ([]ProfileInfo, error) {
 	var profiles []ProfileInfo
 
 	// Scroll to load all results
@@ -204,7 +353,7 @@ func (s *Searcher) extractProfiles(page *rod.Page) ([]ProfileInfo, error) {
 			continue
 		}
 
-		profileURL := fmt.Sprintf("https://www.linkedin.com/in/%s/", matches[1])
+		profileURL := s.site.URL(fmt.Sprintf("/in/%s/", matches[1]))
 
 		// Skip if already seen in this batch
 		if seenURLs[profileURL] {
@@ -213,7 +362,11 @@ func (s *Searcher) extractProfiles(page *rod.Page) ([]ProfileInfo, error) {
 		seenURLs[profileURL] = true
 
 		// Try to extract additional info from the search result card
-		profile := ProfileInfo{ProfileURL: profileURL}
+		profile := ProfileInfo{
+			ProfileURL: profileURL,
+			PageNumber: pageNum,
+			Position:   len(profiles) + 1,
+		}
 
 		// Try to get name and other details from parent elements
 		parent := link
@@ -249,6 +402,19 @@ func (s *Searcher) extractProfiles(page *rod.Page) ([]ProfileInfo, error) {
 				location, _ := locationEl.Text()
 				profile.Location = strings.TrimSpace(location)
 			}
+
+			// Look for the mutual connections insight
+			insightEl, err := parent.Element(".entity-result__simple-insight-text")
+			if err == nil && insightEl != nil {
+				insight, _ := insightEl.Text()
+				if mutual := ParseMutualConnections(insight); mutual.Count > 0 {
+					profile.Mutual = mutual
+				}
+			}
+
+			if has, _, _ := parent.Has(openToWorkSelector); has {
+				profile.OpenToWork = true
+			}
 		}
 
 		profiles = append(profiles, profile)
@@ -316,7 +482,9 @@ func (s *Searcher) goToNextPage(page *rod.Page) bool {
 
 	// Wait for page load
 	time.Sleep(s.timing.GetPageLoadDelay())
-	page.WaitLoad()
+	if err := utils.WaitLoadBounded(page, s.site.PageLoadTimeout, s.logger); err != nil {
+		s.logger.LogError("page load", err, nil)
+	}
 
 	return true
 }
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"linkedin-automation/config"
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/stealth"
+)
+
+// newTestSearcher returns a Searcher for cfg backed by a fresh database
+func newTestSearcher(t *testing.T, cfg config.SearchConfig) *Searcher {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	log, err := logger.New("error", "text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := config.LinkedInConfig{BaseURL: "https://www.linkedin.com"}
+	return NewSearcher(cfg, db, log, config.StealthConfig{}, stealth.NewNavigationPacer(0), site)
+}
+
+// searchQuery returns the query of the URL BuildSearchURL makes for cfg
+func searchQuery(t *testing.T, cfg config.SearchConfig) url.Values {
+	t.Helper()
+	u, err := url.Parse(newTestSearcher(t, cfg).BuildSearchURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
+
+func TestNetworkFacet(t *testing.T) {
+	tests := []struct {
+		degrees, codes, unknown []string
+	}{
+		{[]string{"2nd"}, []string{"S"}, nil},
+		{[]string{"3rd", "1st", "2nd"}, []string{"F", "S", "O"}, nil},
+		{[]string{" 2ND ", "second", "2"}, []string{"S"}, nil},
+		{[]string{"3rd+", "4th"}, []string{"O"}, []string{"4th"}},
+		{nil, nil, nil},
+	}
+	for _, tt := range tests {
+		codes, unknown := NetworkFacet(tt.degrees)
+		if !reflect.DeepEqual(codes, tt.codes) || !reflect.DeepEqual(unknown, tt.unknown) {
+			t.Errorf("NetworkFacet(%q) = %q, %q, want %q, %q", tt.degrees, codes, unknown, tt.codes, tt.unknown)
+		}
+	}
+}
+
+func TestBuildSearchURLNetwork(t *testing.T) {
+	tests := []struct {
+		degrees []string
+		network string // "" when the facet is left out
+	}{
+		{nil, ""},
+		{[]string{"2nd"}, `["S"]`},
+		{[]string{"3rd", "2nd"}, `["S","O"]`},
+		{[]string{"4th"}, ""},
+	}
+	for _, tt := range tests {
+		q := searchQuery(t, config.SearchConfig{JobTitles: []string{"Engineer"}, ConnectionDegrees: tt.degrees})
+		if got := q.Get("network"); got != tt.network {
+			t.Errorf("degrees %q: network = %q, want %q", tt.degrees, got, tt.network)
+		}
+		if got := q.Get("keywords"); got != "Engineer" {
+			t.Errorf("degrees %q: keywords = %q, want Engineer", tt.degrees, got)
+		}
+	}
+}
+
+func TestBuildSearchURLOpenToWork(t *testing.T) {
+	// open_to_work_only filters result cards, it adds nothing to the URL
+	cfg := config.SearchConfig{Keywords: []string{"golang"}, ConnectionDegrees: []string{"2nd"}}
+	plain := searchQuery(t, cfg)
+	cfg.OpenToWorkOnly = true
+	if open := searchQuery(t, cfg); !reflect.DeepEqual(open, plain) {
+		t.Errorf("open_to_work_only changed the query: %v, want %v", open, plain)
+	}
+}
+
+func TestBuildSearchURLGeoUrns(t *testing.T) {
+	tests := []struct {
+		locations []string
+		geoUrn    string // "" when the facet is left out
+	}{
+		{[]string{"Seattle"}, `["90009483"]`},
+		{[]string{"San Francisco", "Atlantis", "New York"}, `["90009496","90009563"]`},
+		// both names map to the same URN, which is listed once
+		{[]string{"san francisco", "San  Francisco Bay Area"}, `["90009496"]`},
+		{[]string{"Atlantis", "El Dorado"}, ""},
+	}
+	for _, tt := range tests {
+		q := searchQuery(t, config.SearchConfig{Keywords: []string{"golang"}, Locations: tt.locations})
+		if got := q.Get("geoUrn"); got != tt.geoUrn {
+			t.Errorf("locations %q: geoUrn = %q, want %q", tt.locations, got, tt.geoUrn)
+		}
+	}
+}
+
+func TestResolveGeoUrnsUsesCache(t *testing.T) {
+	s := newTestSearcher(t, config.SearchConfig{})
+	if err := s.db.SaveGeoUrn(normalizeLocation("Lisbon, Portugal"), "105047339"); err != nil {
+		t.Fatal(err)
+	}
+	got := s.resolveGeoUrns([]string{"Boston", "Lisbon,  Portugal", "Atlantis"})
+	if want := []string{"90009611", "105047339"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveGeoUrns = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// SharedAffiliations is what a profile's highlights say the member has in
+// common with us
+type SharedAffiliations struct {
+	School string // "You both studied at X"
+	Group  string // "You're both members of X"
+}
+
+// sharedScript returns the short lines of the profile that mention
+// something both members share, usually from the Highlights section
+const sharedScript = `() => {
+	const main = document.querySelector('main') || document.body;
+	const lines = [];
+	for (const el of main.querySelectorAll('span, div, li, p')) {
+		if (el.children.length > 2) continue;
+		const text = (el.innerText || '').trim();
+		if (text.length < 200 && /\byou(?:'re| are)? both\b/i.test(text)) lines.push(text);
+	}
+	return lines;
+}`
+
+var (
+	sharedSchool = regexp.MustCompile(`(?i)^you both (?:studied|went to school) at\s+(.+?)(?:\s+from\s+\d{4}.*|\s*\(.*\))?\.?$`)
+	sharedGroup  = regexp.MustCompile(`(?i)^you(?:'re| are)? both (?:members of|in|belong to)\s+(?:the\s+)?(.+?)(?:\s+group)?\.?$`)
+)
+
+// ParseSharedAffiliations reads LinkedIn's "You both studied at X" and
+// "You're both members of the X group" lines. The first school and the
+// first group win; unrecognized lines are ignored
+func ParseSharedAffiliations(lines []string) SharedAffiliations {
+	var shared SharedAffiliations
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if m := sharedSchool.FindStringSubmatch(line); m != nil && shared.School == "" {
+			shared.School = strings.TrimSpace(m[1])
+		}
+		if m := sharedGroup.FindStringSubmatch(line); m != nil && shared.Group == "" {
+			shared.Group = strings.TrimSpace(m[1])
+		}
+	}
+	return shared
+}
+
+// ExtractSharedAffiliations reads the school and group an open profile
+// shares with us. Profiles that show none return a zero value
+func ExtractSharedAffiliations(page *rod.Page) (*SharedAffiliations, error) {
+	res, err := page.Timeout(5 * time.Second).Eval(sharedScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared affiliations: %w", err)
+	}
+
+	var lines []string
+	for _, v := range res.Value.Arr() {
+		lines = append(lines, v.Str())
+	}
+	shared := ParseSharedAffiliations(lines)
+	return &shared, nil
+}
@@ -0,0 +1,186 @@
+package selectors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// Known LinkedIn UI variants
+const (
+	VariantClassic = "classic" // pv-top-card / entity-result markup
+	VariantPVS     = "pvs"     // pvs-profile-actions / reusable-search markup
+	VariantUnknown = "unknown"
+)
+
+// Selector keys looked up through the registry
+const (
+	ConnectButton    = "connect_button"
+	MoreActions      = "more_actions"
+	ConnectInMenu    = "connect_in_menu"
+	StickyConnect    = "sticky_connect"
+	AddNoteButton    = "add_note_button"
+	NoteField        = "note_field"
+	NoteCounter      = "note_counter"
+	SendInvitation   = "send_invitation"
+	MessageButton    = "message_button"
+	MessageInput     = "message_input"
+	MessageThread    = "message_thread"
+	MessageSend      = "message_send"
+	SearchResultName = "search_result_name"
+	TopCardActions   = "top_card_actions"
+)
+
+// variantSets holds the selectors known to work for each UI variant
+var variantSets = map[string]map[string][]string{
+	VariantClassic: {
+		ConnectButton: {
+			`button[aria-label*="Invite"]`,
+			`button[aria-label*="Connect"]`,
+			`.pv-top-card-v2-ctas button:has-text("Connect")`,
+		},
+		MoreActions:   {`button[aria-label="More actions"]`},
+		ConnectInMenu: {`div[data-control-name="connect"]`},
+		StickyConnect: {
+			`.pv-profile-sticky-header-v2__actions-container button[aria-label*="Invite"]`,
+			`.pv-profile-sticky-header-v2__actions-container button[aria-label*="Connect"]`,
+		},
+		AddNoteButton: {`button[aria-label="Add a note"]`},
+		NoteField:     {`textarea[name="message"]`, `textarea#custom-message`},
+		NoteCounter:   {`.send-invite__custom-message-counter`, `.artdeco-text-input__counter`},
+		SendInvitation: {
+			`button[aria-label="Send now"]`,
+			`button[aria-label="Send invitation"]`,
+		},
+		MessageButton: {
+			`button[aria-label*="Message"]`,
+			`button.message-anywhere-button`,
+		},
+		MessageInput:     {`div.msg-form__contenteditable`, `textarea.msg-form__textarea`},
+		MessageThread:    {`.msg-s-message-list`, `.msg-s-message-list-content`},
+		MessageSend:      {`button[type="submit"].msg-form__send-button`, `button.msg-form__send-button`},
+		SearchResultName: {`.entity-result__title-text`},
+		TopCardActions:   {`.pv-top-card-v2-ctas button`},
+	},
+	VariantPVS: {
+		ConnectButton: {
+			`button.pvs-profile-actions__action[aria-label*="connect"]`,
+			`.pvs-profile-actions button[aria-label*="Invite"]`,
+			`button:has-text("Connect")`,
+		},
+		MoreActions:   {`.pvs-profile-actions button[aria-label="More actions"]`, `button[aria-label="More actions"]`},
+		ConnectInMenu: {`div[aria-label*="Invite"][role="button"]`, `div[data-control-name="connect"]`},
+		StickyConnect: {
+			`.pvs-sticky-header-profile-actions button[aria-label*="Invite"]`,
+			`.pvs-sticky-header-profile-actions button[aria-label*="connect"]`,
+			`.pv-profile-sticky-header-v2__actions-container button[aria-label*="Invite"]`,
+		},
+		AddNoteButton: {`button[aria-label="Add a note"]`},
+		NoteField:     {`textarea#custom-message`, `textarea[name="message"]`},
+		NoteCounter:   {`.artdeco-text-input__counter`, `textarea#custom-message ~ span`},
+		SendInvitation: {
+			`button[aria-label="Send invitation"]`,
+			`button[aria-label="Send now"]`,
+		},
+		MessageButton: {
+			`.pvs-profile-actions button[aria-label*="Message"]`,
+			`a[href*="/messaging/"]`,
+			`button:has-text("Message")`,
+		},
+		MessageInput:     {`div.msg-form__contenteditable[role="textbox"]`, `div.msg-form__contenteditable`},
+		MessageThread:    {`.msg-s-message-list-content`, `.msg-s-message-list`},
+		MessageSend:      {`button.msg-form__send-button`, `button[aria-label="Send"]`},
+		SearchResultName: {`.entity-result__title-text`, `span[aria-hidden="true"]`},
+		TopCardActions:   {`.pvs-profile-actions button`, `.pv-top-card-v2-ctas button`},
+	},
+}
+
+// variantMarkers are probed in order to identify the served UI variant
+var variantMarkers = []struct {
+	variant  string
+	selector string
+}{
+	{VariantPVS, `.pvs-profile-actions, .reusable-search__result-container`},
+	{VariantClassic, `.pv-top-card-v2-ctas, .entity-result__title-text`},
+}
+
+// maxDetectProbes is how many pages DetectIfUnknown probes before it
+// settles for trying every selector set, so markup no marker knows does
+// not cost each page the probe timeouts
+const maxDetectProbes = 3
+
+// Registry resolves selector keys to the selectors for the detected variant
+type Registry struct {
+	mu      sync.RWMutex
+	variant string
+	probes  int // pages DetectIfUnknown has probed
+}
+
+// NewRegistry creates a registry with no variant detected yet
+func NewRegistry() *Registry {
+	return &Registry{variant: VariantUnknown}
+}
+
+// Detect probes the page for known variant markers and records the result.
+// It returns VariantUnknown when no marker is found
+func (r *Registry) Detect(page *rod.Page) string {
+	variant := VariantUnknown
+	for _, m := range variantMarkers {
+		has, _, err := page.Timeout(2 * time.Second).Has(m.selector)
+		if err == nil && has {
+			variant = m.variant
+			break
+		}
+	}
+
+	r.mu.Lock()
+	r.variant = variant
+	r.mu.Unlock()
+	return variant
+}
+
+// DetectIfUnknown runs Detect on page while no variant is known, for up to
+// maxDetectProbes pages. The markers are on profile and search result
+// pages, not on the feed a run starts from, so the first of those settles
+// the variant. It reports whether this call detected it
+func (r *Registry) DetectIfUnknown(page *rod.Page) (string, bool) {
+	r.mu.Lock()
+	if r.variant != VariantUnknown || r.probes >= maxDetectProbes {
+		defer r.mu.Unlock()
+		return r.variant, false
+	}
+	r.probes++
+	r.mu.Unlock()
+
+	variant := r.Detect(page)
+	return variant, variant != VariantUnknown
+}
+
+// Variant returns the detected UI variant
+func (r *Registry) Variant() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.variant
+}
+
+// Get returns the selectors for key. When the variant is unknown, the
+// selectors of every known variant are returned (deduplicated)
+func (r *Registry) Get(key string) []string {
+	variant := r.Variant()
+	if set, ok := variantSets[variant]; ok {
+		return set[key]
+	}
+
+	seen := make(map[string]bool)
+	var all []string
+	for _, v := range []string{VariantClassic, VariantPVS} {
+		for _, sel := range variantSets[v][key] {
+			if !seen[sel] {
+				seen[sel] = true
+				all = append(all, sel)
+			}
+		}
+	}
+	return all
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// serverShutdownTimeout bounds how long open API requests get to finish
+// once the server is told to exit
+const serverShutdownTimeout = 10 * time.Second
+
+// apiServer is the --serve control API: a backend starts and stops runs of
+// the one Automation and reads how they went. Only one run is in progress
+// at a time
+type apiServer struct {
+	auto   *Automation
+	token  string
+	runner func() (*RunResult, error) // one run; Automation.serveRun outside tests
+
+	mu       sync.Mutex
+	running  bool
+	stopping bool
+	done     chan struct{} // closed once the current run has returned
+	last     *runReport    // outcome of the last finished run
+}
+
+// apiStatus is the body of GET /status, and of the replies to POST /run
+// and POST /stop
+type apiStatus struct {
+	Running  bool
+	Stopping bool       // a stop was requested and the run is winding down
+	LastRun  *runReport `json:",omitempty"`
+}
+
+// apiError is the body of every error reply
+type apiError struct {
+	Error string
+}
+
+// serve runs the control API on addr until SIGINT or SIGTERM, then stops
+// a run in progress and waits for it to return
+func (a *Automation) serve(addr string) error {
+	s := &apiServer{auto: a, token: a.config.API.Token, runner: a.serveRun}
+	srv := &http.Server{Addr: addr, Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	a.logger.Info("control API listening", "addr", addr)
+	fmt.Printf("✓ Control API listening on %s\n", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-quit:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	s.stopRun()
+	s.wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
+
+// routes returns the API's handler, every endpoint behind the token check
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run", only(http.MethodPost, s.handleRun))
+	mux.HandleFunc("/stop", only(http.MethodPost, s.handleStop))
+	mux.HandleFunc("/status", only(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/activity/today", only(http.MethodGet, s.handleActivityToday))
+	return s.authorize(mux)
+}
+
+// authorize lets through requests carrying "Authorization: Bearer <token>"
+// with the configured api.token
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, apiError{"missing or invalid token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// only rejects requests made with another method than method
+func only(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{method + " only"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handleRun starts a run in the background. The reply does not wait for
+// it: GET /status tells when it is done and how it went
+func (s *apiServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		writeJSON(w, http.StatusConflict, apiError{"a run is already in progress"})
+		return
+	}
+
+	// A previous run's stop left stopChan closed
+	s.auto.rearmStop()
+	s.running = true
+	s.stopping = false
+	s.done = make(chan struct{})
+	go s.run(s.done)
+
+	s.auto.logger.Info("run started through the API")
+	writeJSON(w, http.StatusAccepted, s.status())
+}
+
+// handleStop asks the run in progress to stop. It finishes its in-flight
+// action first, as on SIGINT
+func (s *apiServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		writeJSON(w, http.StatusConflict, apiError{"no run in progress"})
+		return
+	}
+
+	s.auto.closeStop()
+	s.stopping = true
+	s.auto.logger.Info("stop requested through the API")
+	writeJSON(w, http.StatusAccepted, s.status())
+}
+
+func (s *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *apiServer) handleActivityToday(w http.ResponseWriter, r *http.Request) {
+	activity, err := s.auto.db.GetOrCreateDailyActivity()
+	if err != nil {
+		s.auto.logger.LogError("load daily activity", err, nil)
+		writeJSON(w, http.StatusInternalServerError, apiError{"could not load today's activity"})
+		return
+	}
+	writeJSON(w, http.StatusOK, activity)
+}
+
+// run does one run through s.runner and keeps its outcome for GET /status
+func (s *apiServer) run(done chan struct{}) {
+	defer close(done)
+
+	result, err := s.runner()
+	report := &runReport{RunResult: result}
+	if err != nil {
+		s.auto.logger.Error("Automation error", "error", err)
+		report.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.stopping = false
+	s.last = report
+}
+
+// serveRun is one Automation.Run started through the API, with the
+// summary and report of a command line run
+func (a *Automation) serveRun() (*RunResult, error) {
+	result, err := a.Run()
+	a.printSummary(result)
+	a.afterRun(result, err)
+	return result, err
+}
+
+// stopRun stops the run in progress, if any
+func (s *apiServer) stopRun() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.auto.closeStop()
+		s.stopping = true
+	}
+}
+
+// wait blocks until the run in progress, if any, has returned
+func (s *apiServer) wait() {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// status reports the server's state. s.mu must be held
+func (s *apiServer) status() apiStatus {
+	return apiStatus{Running: s.running, Stopping: s.stopping, LastRun: s.last}
+}
+
+// rearmStop gives the next run an open stopChan once a stop has closed it
+func (a *Automation) rearmStop() {
+	a.stopMu.Lock()
+	defer a.stopMu.Unlock()
+	select {
+	case <-a.stopChan:
+		a.stopChan = make(chan struct{})
+	default:
+	}
+}
+
+// writeJSON writes v as the JSON body of a reply with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+)
+
+const testToken = "secret"
+
+// newTestAPI serves an apiServer whose runs block until stopped and end
+// as interrupted, so no browser is needed
+func newTestAPI(t *testing.T) (*apiServer, *httptest.Server) {
+	t.Helper()
+	log, err := logger.New("error", "text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	a := &Automation{logger: log, db: db, stopChan: make(chan struct{})}
+	s := &apiServer{auto: a, token: testToken}
+	s.runner = func() (*RunResult, error) {
+		// handleRun rearms stopChan before starting the run
+		<-a.stopChan
+		result := newRunResult()
+		result.StopReason = StopInterrupted
+		result.finish(nil)
+		return result, nil
+	}
+	srv := httptest.NewServer(s.routes())
+	t.Cleanup(func() {
+		srv.Close()
+		s.stopRun()
+		s.wait()
+	})
+	return s, srv
+}
+
+// call makes an authorized request and decodes a successful reply as a status
+func call(t *testing.T, srv *httptest.Server, method, path string) (int, apiStatus) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st apiStatus
+	if resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, st
+}
+
+func TestAPIRejectsBadToken(t *testing.T) {
+	_, srv := newTestAPI(t)
+	for _, header := range []string{"", "Bearer wrong", testToken, "Bearer " + testToken + "x"} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/status", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("Authorization %q: no WWW-Authenticate challenge", header)
+		}
+	}
+}
+
+func TestAPIRunStopStatus(t *testing.T) {
+	s, srv := newTestAPI(t)
+
+	if code, _ := call(t, srv, http.MethodPost, "/stop"); code != http.StatusConflict {
+		t.Errorf("stop while idle: status %d, want 409", code)
+	}
+	if code, _ := call(t, srv, http.MethodGet, "/run"); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /run: status %d, want 405", code)
+	}
+
+	// Two runs in a row: the second needs the stopChan the first closed
+	// to have been replaced
+	for i := 0; i < 2; i++ {
+		code, st := call(t, srv, http.MethodPost, "/run")
+		if code != http.StatusAccepted || !st.Running {
+			t.Fatalf("run %d: status %d, %+v, want 202 and running", i, code, st)
+		}
+		if code, _ := call(t, srv, http.MethodPost, "/run"); code != http.StatusConflict {
+			t.Errorf("run %d: second POST /run: status %d, want 409", i, code)
+		}
+		if _, st := call(t, srv, http.MethodGet, "/status"); !st.Running || st.Stopping {
+			t.Errorf("run %d: status %+v, want running and not stopping", i, st)
+		}
+
+		code, st = call(t, srv, http.MethodPost, "/stop")
+		if code != http.StatusAccepted || !st.Stopping {
+			t.Fatalf("run %d: stop: status %d, %+v, want 202 and stopping", i, code, st)
+		}
+		waitDone(t, s)
+
+		code, st = call(t, srv, http.MethodGet, "/status")
+		if code != http.StatusOK || st.Running || st.Stopping {
+			t.Fatalf("run %d: status after stop: %d, %+v, want 200 and idle", i, code, st)
+		}
+		if st.LastRun == nil || st.LastRun.StopReason != StopInterrupted {
+			t.Errorf("run %d: last run %+v, want one stopped as %s", i, st.LastRun, StopInterrupted)
+		}
+	}
+}
+
+func TestAPIActivityToday(t *testing.T) {
+	s, srv := newTestAPI(t)
+	if err := s.auto.db.IncrementConnectionCount(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/activity/today", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var activity database.DailyActivity
+	if err := json.NewDecoder(resp.Body).Decode(&activity); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || activity.ConnectionsSent != 1 {
+		t.Errorf("status %d, %d connections sent, want 200 and 1", resp.StatusCode, activity.ConnectionsSent)
+	}
+}
+
+// waitDone waits for the run in progress to return
+func waitDone(t *testing.T, s *apiServer) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		s.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after stop")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// inFlight runs one browser action (navigate, type, send) on a page bound
+// to a context of its own. A stop request does not cut the action off:
+// it gets workflow.shutdown_grace_period to finish, after which the
+// context is cancelled and every pending browser call fails. It reports
+// whether the action was aborted that way, in which case its outcome is
+// unknown and must not be counted as sent
+func (a *Automation) inFlight(action func(page *rod.Page)) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-a.stopChan:
+		case <-done:
+			return
+		}
+
+		grace := a.config.Workflow.ShutdownGracePeriod
+		a.logger.Info("stop requested, finishing the in-flight action", "grace", grace)
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			a.logger.Warn("shutdown grace period passed, aborting the in-flight action")
+			cancel()
+		case <-done:
+		}
+	}()
+
+	action(a.page.Context(ctx))
+	close(done)
+	return ctx.Err() != nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"linkedin-automation/config"
+	"linkedin-automation/utils"
 )
 
 // Point represents a 2D point
@@ -23,7 +24,7 @@ type BezierMouse struct {
 func NewBezierMouse(cfg config.BezierConfig) *BezierMouse {
 	return &BezierMouse{
 		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:    utils.NewRand("bezier"),
 	}
 }
 
@@ -188,3 +189,42 @@ func (bm *BezierMouse) GetMovementDurations(pathLength int, totalDuration time.D
 
 	return durations
 }
+
+// clickMargin keeps click points this many pixels inside the element's
+// edges
+const clickMargin = 2.0
+
+// RandomPointInQuad picks where to click inside an element's quad, the
+// eight coordinates of its corners clockwise from the top left as CDP
+// reports them. The point falls around the center with a normal spread of
+// click_spread times the element's size, and never closer than a couple of
+// pixels to an edge; elements too small for that are clicked dead center.
+// Any four-sided shape works, not only upright rectangles. A quad missing
+// corners has no inside to pick from and yields (0, 0); callers fall back
+// to a plain click for those
+func (bm *BezierMouse) RandomPointInQuad(quad []float64) (x, y float64) {
+	if len(quad) < 8 {
+		return 0, 0
+	}
+
+	width := math.Hypot(quad[2]-quad[0], quad[3]-quad[1])
+	height := math.Hypot(quad[6]-quad[0], quad[7]-quad[1])
+	u := bm.clickFraction(width)
+	v := bm.clickFraction(height)
+
+	// Bilinear interpolation between the corners stays inside the quad
+	topX, topY := quad[0]+u*(quad[2]-quad[0]), quad[1]+u*(quad[3]-quad[1])
+	bottomX, bottomY := quad[6]+u*(quad[4]-quad[6]), quad[7]+u*(quad[5]-quad[7])
+	return topX + v*(bottomX-topX), topY + v*(bottomY-topY)
+}
+
+// clickFraction returns how far along a side of the given length to click,
+// from 0 to 1
+func (bm *BezierMouse) clickFraction(length float64) float64 {
+	if length <= 2*clickMargin || bm.config.ClickSpread <= 0 {
+		return 0.5
+	}
+	f := 0.5 + bm.rng.NormFloat64()*bm.config.ClickSpread
+	limit := clickMargin / length
+	return math.Max(limit, math.Min(1-limit, f))
+}
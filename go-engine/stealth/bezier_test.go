@@ -0,0 +1,83 @@
+package stealth
+
+import (
+	"math"
+	"testing"
+
+	"linkedin-automation/config"
+)
+
+// rotatedQuad returns the corners of a w by h rectangle centered on cx, cy
+// and rotated by deg degrees, clockwise from the top left like CDP
+func rotatedQuad(cx, cy, w, h, deg float64) []float64 {
+	sin, cos := math.Sincos(deg * math.Pi / 180)
+	var quad []float64
+	for _, c := range [][2]float64{{-w / 2, -h / 2}, {w / 2, -h / 2}, {w / 2, h / 2}, {-w / 2, h / 2}} {
+		quad = append(quad, cx+c[0]*cos-c[1]*sin, cy+c[0]*sin+c[1]*cos)
+	}
+	return quad
+}
+
+// edgeDistance returns how far x, y lies inside the quad: the distance to
+// its nearest edge, negative when the point is outside
+func edgeDistance(quad []float64, x, y float64) float64 {
+	nearest := math.Inf(1)
+	for i := 0; i < 4; i++ {
+		ax, ay := quad[2*i], quad[2*i+1]
+		bx, by := quad[(2*i+2)%8], quad[(2*i+3)%8]
+		// corners run clockwise on screen, so the inside is to the right
+		d := ((bx-ax)*(y-ay) - (by-ay)*(x-ax)) / math.Hypot(bx-ax, by-ay)
+		nearest = math.Min(nearest, d)
+	}
+	return nearest
+}
+
+func TestRandomPointInQuadStaysInside(t *testing.T) {
+	tests := []struct {
+		name string
+		quad []float64
+	}{
+		{"button", rotatedQuad(400, 300, 120, 32, 0)},
+		{"rotated", rotatedQuad(400, 300, 120, 32, 30)},
+		{"upside down", rotatedQuad(150, 80, 60, 60, 135)},
+		{"thin", rotatedQuad(200, 200, 300, 5, -15)},
+		{"skewed", []float64{100, 100, 220, 110, 230, 160, 90, 150}},
+	}
+	for _, spread := range []float64{0.15, 0.5, 3} {
+		bm := NewBezierMouse(config.BezierConfig{ClickSpread: spread})
+		for _, tt := range tests {
+			for i := 0; i < 2000; i++ {
+				x, y := bm.RandomPointInQuad(tt.quad)
+				if d := edgeDistance(tt.quad, x, y); d < -1e-9 {
+					t.Fatalf("%s, spread %v: (%.2f, %.2f) is %.2fpx outside the quad", tt.name, spread, x, y, -d)
+				}
+			}
+		}
+	}
+}
+
+func TestRandomPointInQuadKeepsMargin(t *testing.T) {
+	bm := NewBezierMouse(config.BezierConfig{ClickSpread: 3})
+	quad := rotatedQuad(400, 300, 80, 24, 20)
+	for i := 0; i < 2000; i++ {
+		x, y := bm.RandomPointInQuad(quad)
+		if d := edgeDistance(quad, x, y); d < clickMargin-1e-6 {
+			t.Fatalf("(%.2f, %.2f) is %.2fpx from an edge, want at least %v", x, y, d, clickMargin)
+		}
+	}
+}
+
+func TestRandomPointInQuadTinyAndShort(t *testing.T) {
+	bm := NewBezierMouse(config.BezierConfig{ClickSpread: 0.5})
+	tiny := rotatedQuad(50, 60, 3, 3, 45)
+	for i := 0; i < 100; i++ {
+		if x, y := bm.RandomPointInQuad(tiny); math.Abs(x-50) > 1e-9 || math.Abs(y-60) > 1e-9 {
+			t.Fatalf("tiny quad clicked at (%v, %v), want its center (50, 60)", x, y)
+		}
+	}
+	for _, quad := range [][]float64{nil, {1, 2}, {10, 10, 20, 10, 20, 20}} {
+		if x, y := bm.RandomPointInQuad(quad); x != 0 || y != 0 {
+			t.Errorf("RandomPointInQuad(%v) = (%v, %v), want (0, 0)", quad, x, y)
+		}
+	}
+}
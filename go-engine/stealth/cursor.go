@@ -0,0 +1,53 @@
+package stealth
+
+import (
+	"math/rand"
+	"sync"
+
+	"linkedin-automation/utils"
+)
+
+// Cursor tracks where the mouse pointer last moved, so each movement starts
+// where the previous one ended instead of sweeping in from the corner. A
+// single cursor is shared by every module that moves the mouse on the page.
+// On a new document (after a navigation or reload) the tracked position is
+// moved to a random point in the viewport, where a real pointer could be
+type Cursor struct {
+	mu       sync.Mutex
+	x, y     float64
+	document string // the document the position was recorded on
+	rng      *rand.Rand
+}
+
+// NewCursor creates a cursor with no position yet
+func NewCursor() *Cursor {
+	return &Cursor{rng: utils.NewRand("cursor")}
+}
+
+// CurrentPosition returns the last recorded position
+func (c *Cursor) CurrentPosition() (x, y float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.x, c.y
+}
+
+// Begin returns where a movement on document starts. A document other than
+// the one the position was recorded on places the cursor at a random point
+// in the middle part of a width x height viewport first
+func (c *Cursor) Begin(document string, width, height float64) (x, y float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if document != c.document || document == "" {
+		c.document = document
+		c.x = width * (0.2 + 0.6*c.rng.Float64())
+		c.y = height * (0.2 + 0.6*c.rng.Float64())
+	}
+	return c.x, c.y
+}
+
+// MovedTo records that the pointer is now at (x, y)
+func (c *Cursor) MovedTo(x, y float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.x, c.y = x, y
+}
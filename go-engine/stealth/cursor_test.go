@@ -0,0 +1,37 @@
+package stealth
+
+import "testing"
+
+func TestCursorPosition(t *testing.T) {
+	const width, height = 1000, 800
+	inMiddle := func(x, y float64) bool {
+		return x >= 0.2*width && x <= 0.8*width && y >= 0.2*height && y <= 0.8*height
+	}
+
+	c := NewCursor()
+	x, y := c.Begin("doc-1", width, height)
+	if !inMiddle(x, y) {
+		t.Errorf("first movement starts at (%v, %v), outside the middle of the viewport", x, y)
+	}
+	if cx, cy := c.CurrentPosition(); cx != x || cy != y {
+		t.Errorf("CurrentPosition = (%v, %v), want the start (%v, %v)", cx, cy, x, y)
+	}
+
+	c.MovedTo(42, 17)
+	if cx, cy := c.CurrentPosition(); cx != 42 || cy != 17 {
+		t.Errorf("CurrentPosition after MovedTo(42, 17) = (%v, %v)", cx, cy)
+	}
+	// the next movement on the same document starts where the last ended
+	if x, y := c.Begin("doc-1", width, height); x != 42 || y != 17 {
+		t.Errorf("Begin on the same document = (%v, %v), want (42, 17)", x, y)
+	}
+
+	// a new document places the pointer again
+	x, y = c.Begin("doc-2", width, height)
+	if !inMiddle(x, y) {
+		t.Errorf("movement on a new document starts at (%v, %v), outside the middle of the viewport", x, y)
+	}
+	if cx, cy := c.CurrentPosition(); cx != x || cy != y {
+		t.Errorf("CurrentPosition = (%v, %v), want the new start (%v, %v)", cx, cy, x, y)
+	}
+}
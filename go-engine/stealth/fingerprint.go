@@ -1,30 +1,43 @@
 package stealth
 
 import (
+	"encoding/json"
+	"fmt"
 	"math/rand"
-	"time"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
 
 	"linkedin-automation/config"
+	"linkedin-automation/utils"
 )
 
 // FingerprintMasker implements browser fingerprint masking (MANDATORY)
 type FingerprintMasker struct {
-	config config.FingerprintConfig
-	rng    *rand.Rand
+	config    config.FingerprintConfig
+	rng       *rand.Rand
+	userAgent string // UA the browser runs with; navigator overrides follow it
+
+	// Locale of every page, picked on first use so all pages agree
+	localePicked   bool
+	timezone       string // "" keeps the host's
+	acceptLanguage string
 }
 
 // NewFingerprintMasker creates a new fingerprint masker
 func NewFingerprintMasker(cfg config.FingerprintConfig) *FingerprintMasker {
 	return &FingerprintMasker{
 		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:    utils.NewRand("fingerprint"),
 	}
 }
 
 // Viewport represents browser viewport dimensions
 type Viewport struct {
-	Width  int
-	Height int
+	Width       int
+	Height      int
+	ScaleFactor float64 // device pixel ratio of the display it would be on
 }
 
 // Common user agents for rotation
@@ -41,14 +54,14 @@ var userAgents = []string{
 
 // Common viewport sizes (realistic desktop resolutions)
 var viewports = []Viewport{
-	{1920, 1080},
-	{1366, 768},
-	{1536, 864},
-	{1440, 900},
-	{1280, 720},
-	{1600, 900},
-	{2560, 1440},
-	{1680, 1050},
+	{1920, 1080, 1},
+	{1366, 768, 1},
+	{1536, 864, 1.25}, // 1920x1080 at Windows' 125% scaling
+	{1440, 900, 2},    // MacBook Air
+	{1280, 720, 1.5},  // 1920x1080 at 150% scaling
+	{1600, 900, 1},
+	{2560, 1440, 1},
+	{1680, 1050, 2}, // MacBook Pro
 }
 
 // Common timezones
@@ -70,26 +83,45 @@ var acceptLanguages = []string{
 	"en-US,en;q=0.9,es;q=0.8",
 }
 
-// GetRandomUserAgent returns a random user agent string
+// GetRandomUserAgent returns a random user agent string and makes it the
+// one the masking scripts match. Under the strict ua_consistency policy
+// only Chromium user agents are picked
 func (fm *FingerprintMasker) GetRandomUserAgent() string {
-	if !fm.config.RotateUserAgent {
-		return userAgents[0]
+	fm.userAgent = userAgents[0]
+	if fm.config.RotateUserAgent {
+		candidates := userAgents
+		if fm.config.UAConsistency == ConsistencyStrict {
+			candidates = nil
+			for _, ua := range userAgents {
+				if ParseUserAgent(ua).Chromium() {
+					candidates = append(candidates, ua)
+				}
+			}
+		}
+		fm.userAgent = candidates[fm.rng.Intn(len(candidates))]
 	}
-	return userAgents[fm.rng.Intn(len(userAgents))]
+	return fm.userAgent
+}
+
+// SetUserAgent sets the user agent the masking scripts match, for maskers
+// that did not pick it themselves
+func (fm *FingerprintMasker) SetUserAgent(ua string) {
+	fm.userAgent = ua
 }
 
 // GetRandomViewport returns a random viewport size
 func (fm *FingerprintMasker) GetRandomViewport() Viewport {
 	if !fm.config.RandomizeViewport {
-		return Viewport{1920, 1080}
+		return Viewport{1920, 1080, 1}
 	}
 
 	base := viewports[fm.rng.Intn(len(viewports))]
 
 	// Add small random variation (±5 pixels)
 	return Viewport{
-		Width:  base.Width + fm.rng.Intn(11) - 5,
-		Height: base.Height + fm.rng.Intn(11) - 5,
+		Width:       base.Width + fm.rng.Intn(11) - 5,
+		Height:      base.Height + fm.rng.Intn(11) - 5,
+		ScaleFactor: base.ScaleFactor,
 	}
 }
 
@@ -106,6 +138,69 @@ func (fm *FingerprintMasker) GetRandomAcceptLanguage() string {
 	return acceptLanguages[fm.rng.Intn(len(acceptLanguages))]
 }
 
+// Timezone returns the time zone pages run in: stealth.fingerprint.timezone
+// if set, else one picked at random under randomize_timezone, else "" to
+// keep the host's. It stays the same for the masker's lifetime
+func (fm *FingerprintMasker) Timezone() string {
+	fm.pickLocale()
+	return fm.timezone
+}
+
+// AcceptLanguage returns the Accept-Language pages send, and whose
+// languages navigator.languages lists: stealth.fingerprint.accept_language
+// if set, else one picked at random for the masker's lifetime
+func (fm *FingerprintMasker) AcceptLanguage() string {
+	fm.pickLocale()
+	return fm.acceptLanguage
+}
+
+func (fm *FingerprintMasker) pickLocale() {
+	if fm.localePicked {
+		return
+	}
+	fm.localePicked = true
+
+	fm.timezone = fm.config.Timezone
+	if fm.timezone == "" && fm.config.RandomizeTimezone {
+		fm.timezone = fm.GetRandomTimezone()
+	}
+	fm.acceptLanguage = fm.config.AcceptLanguage
+	if fm.acceptLanguage == "" {
+		fm.acceptLanguage = fm.GetRandomAcceptLanguage()
+	}
+}
+
+// AcceptLanguageTags returns the language tags of an Accept-Language
+// header in order, without their weights
+func AcceptLanguageTags(header string) []string {
+	var tags []string
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if tag != "" && tag != "*" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// GetLanguageScript returns JavaScript making navigator.language and
+// navigator.languages agree with the Accept-Language header
+func (fm *FingerprintMasker) GetLanguageScript() string {
+	tags := AcceptLanguageTags(fm.AcceptLanguage())
+	if len(tags) == 0 {
+		return ""
+	}
+	list, _ := json.Marshal(tags)
+	return `
+		Object.defineProperty(navigator, 'languages', {
+			get: () => ` + string(list) + `,
+		});
+		Object.defineProperty(navigator, 'language', {
+			get: () => ` + fmt.Sprintf("%q", tags[0]) + `,
+		});
+	`
+}
+
 // GetWebdriverDisableScript returns JavaScript to disable webdriver detection
 func (fm *FingerprintMasker) GetWebdriverDisableScript() string {
 	if !fm.config.DisableWebdriverFlag {
@@ -118,25 +213,7 @@ func (fm *FingerprintMasker) GetWebdriverDisableScript() string {
 			get: () => undefined,
 		});
 
-		// Override the navigator.plugins to appear non-automated
-		Object.defineProperty(navigator, 'plugins', {
-			get: () => [
-				{ name: 'Chrome PDF Plugin', filename: 'internal-pdf-viewer' },
-				{ name: 'Chrome PDF Viewer', filename: 'mhjfbmdgcfjbbpaeojofohoefgiehjai' },
-				{ name: 'Native Client', filename: 'internal-nacl-plugin' }
-			],
-		});
-
-		// Override navigator.languages
-		Object.defineProperty(navigator, 'languages', {
-			get: () => ['en-US', 'en'],
-		});
-
-		// Remove automation indicators from Chrome
-		if (window.chrome) {
-			window.chrome.runtime = undefined;
-		}
-
+` + overridesFor(ParseUserAgent(fm.userAgent)).script() + `
 		// Override permissions query
 		const originalQuery = window.navigator.permissions.query;
 		window.navigator.permissions.query = (parameters) => (
@@ -186,28 +263,73 @@ func (fm *FingerprintMasker) GetCanvasObfuscationScript() string {
 // GetAllMaskingScripts returns all JavaScript for fingerprint masking
 func (fm *FingerprintMasker) GetAllMaskingScripts() string {
 	scripts := fm.GetWebdriverDisableScript()
+	scripts += fm.GetLanguageScript()
 	scripts += fm.GetCanvasObfuscationScript()
 	return scripts
 }
 
-// GetBrowserArgs returns Chrome/Chromium arguments for stealth
-func (fm *FingerprintMasker) GetBrowserArgs() []string {
-	args := []string{
-		"--disable-blink-features=AutomationControlled",
-		"--disable-dev-shm-usage",
-		"--no-first-run",
-		"--no-default-browser-check",
-		"--disable-infobars",
-		"--disable-extensions",
-		"--disable-popup-blocking",
+// InstallOnPage registers the masking scripts to run in every document the
+// page loads, before the document's own scripts, and emulates the masker's
+// time zone and language. A script evaluated once would be lost on the
+// next navigation, which gives LinkedIn a fresh document with
+// navigator.webdriver back. Install it right after creating the page,
+// before the first navigation
+func (fm *FingerprintMasker) InstallOnPage(page *rod.Page) error {
+	// Date and Intl.DateTimeFormat().resolvedOptions().timeZone follow it
+	if tz := fm.Timezone(); tz != "" {
+		if err := (proto.EmulationSetTimezoneOverride{TimezoneID: tz}).Call(page); err != nil {
+			return fmt.Errorf("emulate timezone %s: %w", tz, err)
+		}
+	}
+	if lang := fm.AcceptLanguage(); lang != "" {
+		if _, err := page.SetExtraHeaders([]string{"Accept-Language", lang}); err != nil {
+			return fmt.Errorf("set Accept-Language: %w", err)
+		}
+		// Intl formats numbers and dates for the first language; ICU
+		// spells its locales with an underscore. A header of only "*"
+		// names none and keeps the browser's
+		if tags := AcceptLanguageTags(lang); len(tags) > 0 {
+			locale := strings.ReplaceAll(tags[0], "-", "_")
+			if err := (proto.EmulationSetLocaleOverride{Locale: locale}).Call(page); err != nil {
+				return fmt.Errorf("emulate locale %s: %w", locale, err)
+			}
+		}
+	}
+
+	script := fm.GetAllMaskingScripts()
+	if script == "" {
+		return nil
 	}
+	// Scoped so the scripts' helpers stay out of the page's globals
+	_, err := page.EvalOnNewDocument("(() => {\n" + script + "\n})();")
+	return err
+}
+
+// maxWindowOffset is how far from the screen's top-left corner a window
+// is moved, in pixels
+const maxWindowOffset = 100
 
-	// Add random window position
-	if fm.config.RandomizeViewport {
-		x := fm.rng.Intn(100)
-		y := fm.rng.Intn(100)
-		args = append(args, "--window-position="+string(rune(x))+","+string(rune(y)))
+// WindowPosition returns a random position for a window of the given size,
+// within maxWindowOffset of the top-left corner and never so far that the
+// window runs off the configured screen
+func (fm *FingerprintMasker) WindowPosition(window Viewport) (x, y int) {
+	room := func(screen, size int) int {
+		if free := screen - size; free < maxWindowOffset {
+			if free < 0 {
+				return 0
+			}
+			return free
+		}
+		return maxWindowOffset
 	}
+	x = fm.rng.Intn(room(fm.config.ScreenWidth, window.Width) + 1)
+	y = fm.rng.Intn(room(fm.config.ScreenHeight, window.Height) + 1)
+	return x, y
+}
 
-	return args
+// WindowPositionArg returns the value of Chrome's --window-position flag
+// for a window of the given size, placed by WindowPosition
+func (fm *FingerprintMasker) WindowPositionArg(window Viewport) string {
+	x, y := fm.WindowPosition(window)
+	return fmt.Sprintf("%d,%d", x, y)
 }
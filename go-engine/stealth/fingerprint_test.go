@@ -0,0 +1,146 @@
+package stealth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/config"
+)
+
+// parseWindowPosition reads a --window-position value back into x and y
+func parseWindowPosition(t *testing.T, arg string) (x, y int) {
+	t.Helper()
+	parts := strings.Split(arg, ",")
+	if len(parts) != 2 {
+		t.Fatalf("window position %q is not x,y", arg)
+	}
+	x, errX := strconv.Atoi(parts[0])
+	y, errY := strconv.Atoi(parts[1])
+	if errX != nil || errY != nil {
+		t.Fatalf("window position %q is not two integers", arg)
+	}
+	return x, y
+}
+
+func TestWindowPositionKeepsWindowOnScreen(t *testing.T) {
+	tests := []struct {
+		name       string
+		screenW    int
+		screenH    int
+		window     Viewport
+		maxX, maxY int
+	}{
+		{"plenty of room", 2560, 1440, Viewport{Width: 1366, Height: 768}, maxWindowOffset, maxWindowOffset},
+		{"little room", 1920, 1080, Viewport{Width: 1880, Height: 1050}, 40, 30},
+		{"exact fit", 1920, 1080, Viewport{Width: 1920, Height: 1080}, 0, 0},
+		{"larger than the screen", 1366, 768, Viewport{Width: 1920, Height: 1080}, 0, 0},
+	}
+	for _, tt := range tests {
+		fm := NewFingerprintMasker(config.FingerprintConfig{ScreenWidth: tt.screenW, ScreenHeight: tt.screenH})
+		for i := 0; i < 500; i++ {
+			x, y := parseWindowPosition(t, fm.WindowPositionArg(tt.window))
+			if x < 0 || y < 0 || x > tt.maxX || y > tt.maxY {
+				t.Fatalf("%s: window at %d,%d, want within 0..%d, 0..%d", tt.name, x, y, tt.maxX, tt.maxY)
+			}
+			if tt.window.Width <= tt.screenW && x+tt.window.Width > tt.screenW ||
+				tt.window.Height <= tt.screenH && y+tt.window.Height > tt.screenH {
+				t.Fatalf("%s: window at %d,%d runs off the %dx%d screen", tt.name, x, y, tt.screenW, tt.screenH)
+			}
+		}
+	}
+}
+
+// launchTestBrowser starts a headless Chrome or Chromium, skipping the test
+// when there is none, and opens a blank page
+func launchTestBrowser(t *testing.T) *rod.Page {
+	t.Helper()
+	bin, ok := launcher.LookPath()
+	if !ok {
+		t.Skip("no Chrome or Chromium found")
+	}
+	u, err := launcher.New().Bin(bin).Headless(true).Leakless(false).Launch()
+	if err != nil {
+		t.Skipf("launch browser: %v", err)
+	}
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { browser.Close() })
+
+	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+// TestInstallOnPageSurvivesNavigation checks the masking on a second page
+// load, which gets a fresh document. It needs a local Chrome or Chromium
+// and is skipped without one
+func TestInstallOnPageSurvivesNavigation(t *testing.T) {
+	page := launchTestBrowser(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><body>%s</body></html>", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	fm := NewFingerprintMasker(config.FingerprintConfig{DisableWebdriverFlag: true})
+	if err := fm.InstallOnPage(page); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/first", "/second"} {
+		if err := page.Navigate(srv.URL + path); err != nil {
+			t.Fatal(err)
+		}
+		if err := page.WaitLoad(); err != nil {
+			t.Fatal(err)
+		}
+		res, err := page.Eval(`() => typeof navigator.webdriver`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Value.Str(); got != "undefined" {
+			t.Errorf("%s: navigator.webdriver is %s, want undefined", path, got)
+		}
+	}
+}
+
+// TestInstallOnPageTimezone reads the emulated time zone and locale back
+// from a real page. It needs a local Chrome or Chromium and is skipped
+// without one
+func TestInstallOnPageTimezone(t *testing.T) {
+	page := launchTestBrowser(t)
+
+	fm := NewFingerprintMasker(config.FingerprintConfig{Timezone: "Asia/Tokyo", AcceptLanguage: "de-DE,de;q=0.9"})
+	if err := fm.InstallOnPage(page); err != nil {
+		t.Fatal(err)
+	}
+	res, err := page.Eval(`() => [Intl.DateTimeFormat().resolvedOptions().timeZone, new Date(0).getTimezoneOffset(), Intl.NumberFormat().resolvedOptions().locale]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tz := res.Value.Get("0").Str(); tz != "Asia/Tokyo" {
+		t.Errorf("time zone = %q, want Asia/Tokyo", tz)
+	}
+	if offset := res.Value.Get("1").Int(); offset != -9*60 {
+		t.Errorf("Date offset = %d minutes, want %d", offset, -9*60)
+	}
+	if locale := res.Value.Get("2").Str(); locale != "de-DE" {
+		t.Errorf("Intl locale = %q, want de-DE", locale)
+	}
+
+	// A header naming no language leaves the locale alone
+	fm = NewFingerprintMasker(config.FingerprintConfig{AcceptLanguage: "*"})
+	if err := fm.InstallOnPage(page); err != nil {
+		t.Errorf("InstallOnPage with Accept-Language *: %v", err)
+	}
+}
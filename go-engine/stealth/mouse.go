@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"linkedin-automation/config"
+	"linkedin-automation/utils"
 )
 
 // MouseHoverController implements mouse hovering and movement
@@ -17,7 +18,7 @@ type MouseHoverController struct {
 func NewMouseHoverController(cfg config.MouseConfig) *MouseHoverController {
 	return &MouseHoverController{
 		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:    utils.NewRand("mouse"),
 	}
 }
 
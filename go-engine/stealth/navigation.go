@@ -0,0 +1,48 @@
+package stealth
+
+import (
+	"sync"
+	"time"
+)
+
+// NavigationPacer enforces a minimum wall-clock interval between page
+// navigations. A single pacer is shared by every module that navigates so
+// the overall navigation rate stays smooth even when actions fail fast
+type NavigationPacer struct {
+	minInterval time.Duration
+	mu          sync.Mutex
+	last        time.Time
+}
+
+// NewNavigationPacer creates a pacer with the given minimum interval in
+// milliseconds. Zero or negative disables pacing
+func NewNavigationPacer(minIntervalMs int) *NavigationPacer {
+	return &NavigationPacer{
+		minInterval: time.Duration(minIntervalMs) * time.Millisecond,
+	}
+}
+
+// Wait blocks until the minimum interval since the previous navigation has
+// elapsed, then records the current time as the latest navigation
+func (np *NavigationPacer) Wait() {
+	if np == nil {
+		return
+	}
+
+	np.mu.Lock()
+	defer np.mu.Unlock()
+
+	if !np.last.IsZero() {
+		if remaining := np.minInterval - time.Since(np.last); remaining > 0 {
+			time.Sleep(remaining)
+		}
+	}
+	np.last = time.Now()
+}
+
+// LastNavigation returns when the most recent navigation was recorded
+func (np *NavigationPacer) LastNavigation() time.Time {
+	np.mu.Lock()
+	defer np.mu.Unlock()
+	return np.last
+}
@@ -0,0 +1,151 @@
+package stealth
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/config"
+	"linkedin-automation/utils"
+)
+
+// Throughput of the emulated connection, in megabits per second: a home
+// broadband line, picked once per throttler
+const (
+	downloadMbpsMin = 20
+	downloadMbpsMax = 100
+	uploadMbpsMin   = 5
+	uploadMbpsMax   = 20
+)
+
+// NetworkThrottler emulates the latency and bandwidth of a real
+// connection, so pages do not load with the instant, uniform timing of a
+// browser next to its server. The latency is drawn again every
+// latency_refresh, as a real connection's drifts
+type NetworkThrottler struct {
+	config   config.NetworkConfig
+	rng      *rand.Rand
+	download float64 // bytes per second
+	upload   float64
+
+	mu        sync.Mutex
+	pages     []*rod.Page // pages kept throttled by Follow
+	following bool        // refresh is running
+}
+
+// NewNetworkThrottler creates a throttler for the configured latency range
+func NewNetworkThrottler(cfg config.NetworkConfig) *NetworkThrottler {
+	rng := utils.NewRand("network")
+	mbps := func(min, max int) float64 {
+		return float64(min+rng.Intn(max-min+1)) * 1e6 / 8
+	}
+	return &NetworkThrottler{
+		config:   cfg,
+		rng:      rng,
+		download: mbps(downloadMbpsMin, downloadMbpsMax),
+		upload:   mbps(uploadMbpsMin, uploadMbpsMax),
+	}
+}
+
+// Conditions returns the emulation parameters for the next interval: a
+// latency drawn from the configured range and the throttler's throughput
+func (nt *NetworkThrottler) Conditions() *proto.NetworkEmulateNetworkConditions {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	latency := nt.config.LatencyMinMs
+	if spread := nt.config.LatencyMaxMs - nt.config.LatencyMinMs; spread > 0 {
+		latency += nt.rng.Intn(spread + 1)
+	}
+	return &proto.NetworkEmulateNetworkConditions{
+		Latency:            float64(latency),
+		DownloadThroughput: nt.download,
+		UploadThroughput:   nt.upload,
+	}
+}
+
+// Apply throttles page with fresh conditions. It does nothing unless
+// simulate_latency is on
+func (nt *NetworkThrottler) Apply(page *rod.Page) error {
+	if !nt.config.SimulateLatency {
+		return nil
+	}
+	// Emulation takes effect once the page reports network activity
+	if err := (proto.NetworkEnable{}).Call(page); err != nil {
+		return err
+	}
+	return nt.Conditions().Call(page)
+}
+
+// Clear lifts the throttling from page
+func (nt *NetworkThrottler) Clear(page *rod.Page) error {
+	return proto.NetworkEmulateNetworkConditions{
+		DownloadThroughput: -1,
+		UploadThroughput:   -1,
+	}.Call(page)
+}
+
+// Follow throttles page and keeps drawing it a new latency every
+// latency_refresh, for as long as the page can be reached. Pages followed
+// later are kept throttled alongside it, so closing one, such as a tab
+// opened for a session refresh, leaves the others throttled
+func (nt *NetworkThrottler) Follow(page *rod.Page) error {
+	if !nt.config.SimulateLatency {
+		return nil
+	}
+	if err := nt.Apply(page); err != nil {
+		return err
+	}
+
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	for _, followed := range nt.pages {
+		if followed == page {
+			return nil
+		}
+	}
+	nt.pages = append(nt.pages, page)
+	if nt.following || nt.config.LatencyRefresh <= 0 {
+		return nil
+	}
+	nt.following = true
+	go nt.refresh()
+	return nil
+}
+
+// refresh re-applies the conditions to each followed page, dropping the
+// pages it fails on, until none is left
+func (nt *NetworkThrottler) refresh() {
+	ticker := time.NewTicker(nt.config.LatencyRefresh)
+	defer ticker.Stop()
+	for range ticker.C {
+		nt.mu.Lock()
+		pages := append([]*rod.Page(nil), nt.pages...)
+		nt.mu.Unlock()
+
+		lost := make(map[*rod.Page]bool)
+		for _, page := range pages {
+			if err := nt.Apply(page); err != nil {
+				lost[page] = true
+			}
+		}
+
+		nt.mu.Lock()
+		kept := nt.pages[:0]
+		for _, page := range nt.pages {
+			if !lost[page] {
+				kept = append(kept, page)
+			}
+		}
+		nt.pages = kept
+		if len(nt.pages) == 0 {
+			nt.following = false
+			nt.mu.Unlock()
+			return
+		}
+		nt.mu.Unlock()
+	}
+}
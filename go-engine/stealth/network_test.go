@@ -0,0 +1,50 @@
+package stealth
+
+import (
+	"testing"
+
+	"linkedin-automation/config"
+)
+
+func TestNetworkConditionsInRange(t *testing.T) {
+	tests := []struct{ min, max int }{
+		{40, 120},
+		{0, 10},
+		{75, 75},
+	}
+	for _, tt := range tests {
+		nt := NewNetworkThrottler(config.NetworkConfig{SimulateLatency: true, LatencyMinMs: tt.min, LatencyMaxMs: tt.max})
+		seen := make(map[float64]bool)
+		for i := 0; i < 500; i++ {
+			c := nt.Conditions()
+			if c.Latency < float64(tt.min) || c.Latency > float64(tt.max) {
+				t.Fatalf("%d-%d ms: latency %g out of range", tt.min, tt.max, c.Latency)
+			}
+			if c.DownloadThroughput <= 0 || c.UploadThroughput <= 0 {
+				t.Fatalf("%d-%d ms: throughput %g down, %g up, want both positive", tt.min, tt.max, c.DownloadThroughput, c.UploadThroughput)
+			}
+			seen[c.Latency] = true
+		}
+		// the latency is drawn again on each call
+		if tt.max > tt.min && len(seen) < 2 {
+			t.Errorf("%d-%d ms: 500 draws gave the same latency", tt.min, tt.max)
+		}
+	}
+}
+
+func TestNetworkThroughputFixed(t *testing.T) {
+	nt := NewNetworkThrottler(config.NetworkConfig{LatencyMinMs: 10, LatencyMaxMs: 200})
+	first := nt.Conditions()
+	if min, max := float64(downloadMbpsMin)*1e6/8, float64(downloadMbpsMax)*1e6/8; first.DownloadThroughput < min || first.DownloadThroughput > max {
+		t.Errorf("download %g bytes/s, want %g-%g", first.DownloadThroughput, min, max)
+	}
+	if min, max := float64(uploadMbpsMin)*1e6/8, float64(uploadMbpsMax)*1e6/8; first.UploadThroughput < min || first.UploadThroughput > max {
+		t.Errorf("upload %g bytes/s, want %g-%g", first.UploadThroughput, min, max)
+	}
+	for i := 0; i < 50; i++ {
+		c := nt.Conditions()
+		if c.DownloadThroughput != first.DownloadThroughput || c.UploadThroughput != first.UploadThroughput {
+			t.Fatalf("throughput changed from %g/%g to %g/%g", first.DownloadThroughput, first.UploadThroughput, c.DownloadThroughput, c.UploadThroughput)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package stealth
+
+import (
+	"math"
+	"time"
+)
+
+// maxRhythmAmplitude keeps the slowest multiplier finite and the fastest
+// above zero
+const maxRhythmAmplitude = 0.9
+
+// SessionRhythm scales inter-action delays over the course of a session:
+// slower while warming up and winding down, faster in the middle. The
+// multiplier follows 1 + A*cos(2πp), where p is the fraction of planned
+// actions already done
+type SessionRhythm struct {
+	amplitude float64
+	planned   int
+	done      int
+	started   time.Time
+}
+
+// NewSessionRhythm creates a rhythm with the given amplitude (0 disables it)
+func NewSessionRhythm(amplitude float64) *SessionRhythm {
+	if amplitude < 0 {
+		amplitude = 0
+	}
+	if amplitude > maxRhythmAmplitude {
+		amplitude = maxRhythmAmplitude
+	}
+	return &SessionRhythm{amplitude: amplitude, started: time.Now()}
+}
+
+// Plan adds n actions to the number expected this session
+func (sr *SessionRhythm) Plan(n int) {
+	if n > 0 {
+		sr.planned += n
+	}
+}
+
+// Progress returns how far through the planned actions the session is, 0-1
+func (sr *SessionRhythm) Progress() float64 {
+	if sr.planned == 0 {
+		return 0
+	}
+	return math.Min(float64(sr.done)/float64(sr.planned), 1)
+}
+
+// Multiplier returns the delay scale for the next action
+func (sr *SessionRhythm) Multiplier() float64 {
+	if sr.amplitude == 0 {
+		return 1
+	}
+	return 1 + sr.amplitude*math.Cos(2*math.Pi*sr.Progress())
+}
+
+// Apply scales delay by the current multiplier and counts one action
+func (sr *SessionRhythm) Apply(delay time.Duration) time.Duration {
+	scaled := time.Duration(float64(delay) * sr.Multiplier())
+	sr.done++
+	return scaled
+}
+
+// Elapsed returns the time since the session started
+func (sr *SessionRhythm) Elapsed() time.Duration {
+	return time.Since(sr.started)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"linkedin-automation/config"
+	"linkedin-automation/utils"
 )
 
 // ScrollController implements random scrolling behavior
@@ -17,7 +18,7 @@ type ScrollController struct {
 func NewScrollController(cfg config.ScrollingConfig) *ScrollController {
 	return &ScrollController{
 		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:    utils.NewRand("scrolling"),
 	}
 }
 
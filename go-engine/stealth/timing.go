@@ -6,8 +6,20 @@ import (
 	"time"
 
 	"linkedin-automation/config"
+	"linkedin-automation/utils"
 )
 
+// Supported delay distributions
+const (
+	DistributionUniform   = "uniform"
+	DistributionNormal    = "normal"
+	DistributionLogNormal = "lognormal"
+)
+
+// lognormalTailFactor bounds how far past the configured maximum a
+// log-normal delay may extend
+const lognormalTailFactor = 3.0
+
 // TimingController implements randomized timing patterns (MANDATORY)
 type TimingController struct {
 	config config.TimingConfig
@@ -18,18 +30,17 @@ type TimingController struct {
 func NewTimingController(cfg config.TimingConfig) *TimingController {
 	return &TimingController{
 		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:    utils.NewRand("timing"),
 	}
 }
 
 // GetActionDelay returns a randomized delay for general actions
-// Uses normal distribution: N(μ=500ms, σ=200ms)
+// Centered on μ=500ms, σ=200ms using the configured distribution
 func (tc *TimingController) GetActionDelay() time.Duration {
 	mu := 500.0 // mean in milliseconds
 	sigma := 200.0
 
-	// Box-Muller transform for normal distribution
-	delay := tc.normalRandom(mu, sigma)
+	delay := tc.sample(mu, sigma)
 
 	// Ensure positive delay
 	if delay < 100 {
@@ -86,6 +97,19 @@ func (tc *TimingController) GetPageLoadDelay() time.Duration {
 	return time.Duration(delay) * time.Millisecond
 }
 
+// GetPreNavigationDelay returns the pause taken before navigating to the
+// next profile or search page, modelling the decision to click. It is drawn
+// fresh for every navigation and is independent of GetPageLoadDelay
+func (tc *TimingController) GetPreNavigationDelay() time.Duration {
+	return tc.GetRandomizedDelay(tc.config.PreNavMinMs, tc.config.PreNavMaxMs)
+}
+
+// GetFocusDelay returns the pause between focusing a field and the first
+// keystroke. It is taken once per field, apart from the per-key delays
+func (tc *TimingController) GetFocusDelay() time.Duration {
+	return tc.GetRandomizedDelay(tc.config.FocusDelayMinMs, tc.config.FocusDelayMaxMs)
+}
+
 // GetCapitalLetterDelay returns additional delay before typing capital letters
 func (tc *TimingController) GetCapitalLetterDelay() time.Duration {
 	// Shift key hold simulation: 30-80ms extra
@@ -131,23 +155,61 @@ func (tc *TimingController) normalRandom(mu, sigma float64) float64 {
 }
 
 // GetRandomizedDelay returns a delay within the given range with some randomization
+// With the lognormal distribution the upper bound is soft: samples may run past
+// maxMs (up to lognormalTailFactor times it) to model occasional long pauses
 func (tc *TimingController) GetRandomizedDelay(minMs, maxMs int) time.Duration {
 	mu := float64(minMs+maxMs) / 2
 	sigma := float64(maxMs-minMs) / 4
 
-	delay := tc.normalRandom(mu, sigma)
+	delay := tc.sample(mu, sigma)
 
 	// Clamp
+	upper := float64(maxMs)
+	if tc.config.Distribution == DistributionLogNormal {
+		upper *= lognormalTailFactor
+	}
 	if delay < float64(minMs) {
 		delay = float64(minMs)
 	}
-	if delay > float64(maxMs) {
-		delay = float64(maxMs)
+	if delay > upper {
+		delay = upper
 	}
 
 	return time.Duration(delay) * time.Millisecond
 }
 
+// sample draws a value with the given mean and standard deviation from the
+// configured distribution (normal when unset or unknown)
+func (tc *TimingController) sample(mu, sigma float64) float64 {
+	switch tc.config.Distribution {
+	case DistributionUniform:
+		return tc.uniformRandom(mu, sigma)
+	case DistributionLogNormal:
+		return tc.logNormalRandom(mu, sigma)
+	default:
+		return tc.normalRandom(mu, sigma)
+	}
+}
+
+// uniformRandom generates a uniformly distributed number with the given mean
+// and standard deviation (width = σ·√12)
+func (tc *TimingController) uniformRandom(mu, sigma float64) float64 {
+	halfWidth := sigma * math.Sqrt(3)
+	return mu - halfWidth + tc.rng.Float64()*2*halfWidth
+}
+
+// logNormalRandom generates a log-normally distributed number whose mean and
+// standard deviation match mu and sigma. The long right tail produces the
+// occasional much longer pause typical of human inter-action times
+func (tc *TimingController) logNormalRandom(mu, sigma float64) float64 {
+	if mu <= 0 {
+		return tc.normalRandom(mu, sigma)
+	}
+	variance := math.Log(1 + (sigma*sigma)/(mu*mu))
+	logMu := math.Log(mu) - variance/2
+	return math.Exp(tc.normalRandom(logMu, math.Sqrt(variance)))
+}
+
 // SleepWithJitter sleeps for a duration with added jitter
 func (tc *TimingController) SleepWithJitter(base time.Duration, jitterPercent float64) {
 	jitter := float64(base) * jitterPercent * (tc.rng.Float64()*2 - 1)
@@ -0,0 +1,112 @@
+package stealth
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"linkedin-automation/config"
+)
+
+// draw returns n samples of the controller's distribution
+func draw(dist string, mu, sigma float64, n int) []float64 {
+	tc := NewTimingController(config.TimingConfig{Distribution: dist})
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = tc.sample(mu, sigma)
+	}
+	return samples
+}
+
+func moments(samples []float64) (mean, stddev float64) {
+	for _, s := range samples {
+		mean += s
+	}
+	mean /= float64(len(samples))
+	for _, s := range samples {
+		stddev += (s - mean) * (s - mean)
+	}
+	return mean, math.Sqrt(stddev / float64(len(samples)))
+}
+
+func TestSampleShape(t *testing.T) {
+	const (
+		mu    = 500.0
+		sigma = 200.0
+		n     = 50000
+	)
+	tests := []struct {
+		dist string
+		// share of samples above mu+3σ: none for uniform, about 0.13% for
+		// normal and well over that for the log-normal's long tail
+		tailMin, tailMax float64
+	}{
+		{DistributionUniform, 0, 0},
+		{DistributionNormal, 0.0005, 0.0025},
+		{DistributionLogNormal, 0.01, 0.03},
+		{"", 0.0005, 0.0025}, // normal by default
+	}
+	for _, tt := range tests {
+		samples := draw(tt.dist, mu, sigma, n)
+		mean, stddev := moments(samples)
+		if math.Abs(mean-mu) > 0.02*mu {
+			t.Errorf("%q: mean %.1f, want about %.0f", tt.dist, mean, mu)
+		}
+		if math.Abs(stddev-sigma) > 0.05*sigma {
+			t.Errorf("%q: standard deviation %.1f, want about %.0f", tt.dist, stddev, sigma)
+		}
+
+		tail := 0
+		for _, s := range samples {
+			if s > mu+3*sigma {
+				tail++
+			}
+		}
+		if share := float64(tail) / n; share < tt.tailMin || share > tt.tailMax {
+			t.Errorf("%q: %.4f of samples past mu+3σ, want %.4f..%.4f", tt.dist, share, tt.tailMin, tt.tailMax)
+		}
+	}
+}
+
+func TestUniformStaysInRange(t *testing.T) {
+	halfWidth := 200 * math.Sqrt(3)
+	for _, s := range draw(DistributionUniform, 500, 200, 10000) {
+		if s < 500-halfWidth || s > 500+halfWidth {
+			t.Fatalf("uniform sample %.1f outside 500±%.1f", s, halfWidth)
+		}
+	}
+}
+
+func TestLogNormalSkew(t *testing.T) {
+	samples := draw(DistributionLogNormal, 500, 200, 20000)
+	for _, s := range samples {
+		if s <= 0 {
+			t.Fatalf("log-normal sample %.1f is not positive", s)
+		}
+	}
+	sort.Float64s(samples)
+	mean, _ := moments(samples)
+	if median := samples[len(samples)/2]; median >= mean {
+		t.Errorf("median %.1f not below mean %.1f, want a right skew", median, mean)
+	}
+}
+
+func TestGetRandomizedDelayBounds(t *testing.T) {
+	tests := []struct {
+		dist       string
+		maxAllowed float64
+	}{
+		{DistributionUniform, 2000},
+		{DistributionNormal, 2000},
+		{DistributionLogNormal, 2000 * lognormalTailFactor},
+	}
+	for _, tt := range tests {
+		tc := NewTimingController(config.TimingConfig{Distribution: tt.dist})
+		for i := 0; i < 5000; i++ {
+			ms := float64(tc.GetRandomizedDelay(1000, 2000).Milliseconds())
+			if ms < 1000 || ms > tt.maxAllowed {
+				t.Fatalf("%q: delay %.0fms outside 1000..%.0f", tt.dist, ms, tt.maxAllowed)
+			}
+		}
+	}
+}
@@ -2,11 +2,14 @@ package stealth
 
 import (
 	"math/rand"
-	"strings"
 	"time"
 	"unicode"
 
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+
 	"linkedin-automation/config"
+	"linkedin-automation/utils"
 )
 
 // TypingSimulator implements realistic typing simulation
@@ -19,7 +22,7 @@ type TypingSimulator struct {
 func NewTypingSimulator(cfg config.TimingConfig) *TypingSimulator {
 	return &TypingSimulator{
 		config: cfg,
-		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:    utils.NewRand("typing"),
 	}
 }
 
@@ -32,6 +35,13 @@ type TypedChar struct {
 	IsBurstPause bool
 }
 
+// PressBackspace deletes the character before the caret with a real
+// Backspace key press, as the correction of a simulated typo. Typing "\b"
+// through Element.Input inserts text instead of deleting, leaving the typo
+func PressBackspace(page *rod.Page) error {
+	return page.Keyboard.Type(input.Backspace)
+}
+
 // Common typos map (adjacent keys on QWERTY keyboard)
 var adjacentKeys = map[rune][]rune{
 	'a': {'s', 'q', 'w', 'z'},
@@ -195,12 +205,3 @@ func (ts *TypingSimulator) SimulateTextSelection() time.Duration {
 	// Selection + processing time
 	return time.Duration(100+ts.rng.Intn(200)) * time.Millisecond
 }
-
-// SubstituteTemplate replaces template variables with actual values
-func SubstituteTemplate(template string, vars map[string]string) string {
-	result := template
-	for key, value := range vars {
-		result = strings.ReplaceAll(result, "{{"+key+"}}", value)
-	}
-	return result
-}
@@ -0,0 +1,114 @@
+package stealth
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/config"
+)
+
+// typingTexts are typed with a typo on most characters
+var typingTexts = []string{
+	"Hi Jane, great to connect!",
+	"Looking forward to it. Best, Tom",
+	"Grüße aus München – José",
+}
+
+var typoHeavy = config.TimingConfig{TypingMinDelayMs: 1, TypingMaxDelayMs: 1, TypoProbability: 0.6}
+
+// replay applies a typing sequence to an empty field the way the typing
+// loops do: characters are inserted and a backspace deletes the last one
+func replay(sequence []TypedChar) string {
+	var field []rune
+	for _, c := range sequence {
+		switch {
+		case c.IsBurstPause:
+		case c.IsBackspace:
+			field = field[:len(field)-1]
+		default:
+			field = append(field, c.Char)
+		}
+	}
+	return string(field)
+}
+
+func TestTypingSequenceCorrectsTypos(t *testing.T) {
+	ts := NewTypingSimulator(typoHeavy)
+	for _, text := range typingTexts {
+		sequence := ts.GenerateTypingSequence(text)
+		typos := 0
+		for _, c := range sequence {
+			if c.IsBackspace {
+				typos++
+			}
+		}
+		if typos == 0 {
+			t.Errorf("%q: no typos made at typo probability %v", text, typoHeavy.TypoProbability)
+		}
+		if got := replay(sequence); got != text {
+			t.Errorf("sequence for %q leaves %q in the field", text, got)
+		}
+	}
+}
+
+// TestPressBackspaceInBrowser types the sequences into a real field, so it
+// needs a local Chrome or Chromium and is skipped without one
+func TestPressBackspaceInBrowser(t *testing.T) {
+	bin, ok := launcher.LookPath()
+	if !ok {
+		t.Skip("no Chrome or Chromium found")
+	}
+	u, err := launcher.New().Bin(bin).Headless(true).Leakless(false).Launch()
+	if err != nil {
+		t.Skipf("launch browser: %v", err)
+	}
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer browser.Close()
+
+	page, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := page.SetDocumentContent(`<textarea id="note"></textarea>`); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := NewTypingSimulator(typoHeavy)
+	for _, text := range typingTexts {
+		field, err := page.Element("#note")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := field.Eval(`() => { this.value = '' }`); err != nil {
+			t.Fatal(err)
+		}
+		if err := field.Focus(); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range ts.GenerateTypingSequence(text) {
+			switch {
+			case c.IsBurstPause:
+			case c.IsBackspace:
+				err = PressBackspace(page)
+			default:
+				err = field.Input(string(c.Char))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		value, err := field.Property("value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := value.Str(); got != text {
+			t.Errorf("field holds %q, want %q", got, text)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package stealth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Browsers a user agent can claim
+const (
+	BrowserChrome  = "chrome"
+	BrowserEdge    = "edge"
+	BrowserFirefox = "firefox"
+	BrowserSafari  = "safari"
+	BrowserUnknown = "unknown"
+)
+
+// Platforms a user agent can claim
+const (
+	PlatformWindows = "windows"
+	PlatformMac     = "mac"
+	PlatformLinux   = "linux"
+	PlatformUnknown = "unknown"
+)
+
+// UA consistency policies
+const (
+	ConsistencyWarn   = "warn"   // log mismatches and continue
+	ConsistencyStrict = "strict" // rotate among Chromium user agents only and refuse mismatches
+)
+
+// UserAgentProfile is the browser and platform a user agent claims
+type UserAgentProfile struct {
+	Browser  string
+	Platform string
+}
+
+// Chromium reports whether the claimed browser is built on Chromium, like
+// the browser the automation actually drives
+func (p UserAgentProfile) Chromium() bool {
+	return p.Browser == BrowserChrome || p.Browser == BrowserEdge
+}
+
+// ParseUserAgent identifies the browser and platform a user agent claims.
+// Order matters: Edge UAs also name Chrome and Safari, Chrome UAs name Safari
+func ParseUserAgent(ua string) UserAgentProfile {
+	p := UserAgentProfile{Browser: BrowserUnknown, Platform: PlatformUnknown}
+
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		p.Browser = BrowserEdge
+	case strings.Contains(ua, "Firefox/"):
+		p.Browser = BrowserFirefox
+	case strings.Contains(ua, "Chrome/"):
+		p.Browser = BrowserChrome
+	case strings.Contains(ua, "Safari/") && strings.Contains(ua, "Version/"):
+		p.Browser = BrowserSafari
+	}
+
+	switch {
+	case strings.Contains(ua, "Windows"):
+		p.Platform = PlatformWindows
+	case strings.Contains(ua, "Macintosh"):
+		p.Platform = PlatformMac
+	case strings.Contains(ua, "Linux"):
+		p.Platform = PlatformLinux
+	}
+	return p
+}
+
+// navigatorOverrides are the navigator values the masking script installs
+type navigatorOverrides struct {
+	Vendor     string
+	Platform   string
+	ProductSub string
+	OSCPU      string   // Firefox only
+	Plugins    []string // plugin names, all served by the internal PDF viewer
+	KeepChrome bool     // keep window.chrome (with runtime removed)
+	UAData     bool     // keep navigator.userAgentData
+}
+
+// pdfPlugins is the plugin list current Chromium and Firefox builds expose
+var pdfPlugins = []string{
+	"PDF Viewer",
+	"Chrome PDF Viewer",
+	"Chromium PDF Viewer",
+	"Microsoft Edge PDF Viewer",
+	"WebKit built-in PDF",
+}
+
+// overridesFor returns navigator overrides matching the claimed browser and
+// platform. Unknown browsers get the Chrome values the engine has anyway
+func overridesFor(p UserAgentProfile) navigatorOverrides {
+	o := navigatorOverrides{
+		Vendor:     "Google Inc.",
+		ProductSub: "20030107",
+		Plugins:    pdfPlugins,
+		KeepChrome: true,
+		UAData:     true,
+	}
+
+	switch p.Browser {
+	case BrowserFirefox:
+		o = navigatorOverrides{Vendor: "", ProductSub: "20100101", Plugins: pdfPlugins}
+	case BrowserSafari:
+		o = navigatorOverrides{Vendor: "Apple Computer, Inc.", ProductSub: "20030107", Plugins: pdfPlugins}
+	}
+
+	switch p.Platform {
+	case PlatformWindows:
+		o.Platform = "Win32"
+		if p.Browser == BrowserFirefox {
+			o.OSCPU = "Windows NT 10.0; Win64; x64"
+		}
+	case PlatformMac:
+		o.Platform = "MacIntel"
+		if p.Browser == BrowserFirefox {
+			o.OSCPU = "Intel Mac OS X 10.15"
+		}
+	case PlatformLinux:
+		o.Platform = "Linux x86_64"
+		if p.Browser == BrowserFirefox {
+			o.OSCPU = "Linux x86_64"
+		}
+	}
+	return o
+}
+
+// script renders the overrides as JavaScript run before page scripts
+func (o navigatorOverrides) script() string {
+	var b strings.Builder
+
+	define := func(prop, value string) {
+		fmt.Fprintf(&b, "\t\tObject.defineProperty(navigator, '%s', { get: () => %s });\n", prop, value)
+	}
+
+	b.WriteString("\n\t\t// Navigator values matching the user agent\n")
+	define("vendor", fmt.Sprintf("%q", o.Vendor))
+	define("productSub", fmt.Sprintf("%q", o.ProductSub))
+	if o.Platform != "" {
+		define("platform", fmt.Sprintf("%q", o.Platform))
+	}
+	if o.OSCPU != "" {
+		define("oscpu", fmt.Sprintf("%q", o.OSCPU))
+	}
+
+	plugins := make([]string, len(o.Plugins))
+	for i, name := range o.Plugins {
+		plugins[i] = fmt.Sprintf("{ name: %q, filename: 'internal-pdf-viewer' }", name)
+	}
+	define("plugins", "["+strings.Join(plugins, ", ")+"]")
+
+	if !o.UAData {
+		define("userAgentData", "undefined")
+	}
+	if o.KeepChrome {
+		b.WriteString("\t\tif (window.chrome) {\n\t\t\twindow.chrome.runtime = undefined;\n\t\t}\n")
+	} else {
+		b.WriteString("\t\tdelete window.chrome;\n")
+	}
+	return b.String()
+}
+
+// CheckUserAgentConsistency lists the ways the navigator overrides for ua
+// would still disagree with it
+func CheckUserAgentConsistency(ua string) []string {
+	p := ParseUserAgent(ua)
+	o := overridesFor(p)
+
+	var problems []string
+	if p.Browser == BrowserUnknown {
+		problems = append(problems, "browser not recognized, using Chrome navigator values")
+	}
+	if p.Platform == PlatformUnknown {
+		problems = append(problems, "platform not recognized, navigator.platform left as is")
+	}
+	if !p.Chromium() && p.Browser != BrowserUnknown {
+		problems = append(problems, fmt.Sprintf("%s user agent on a Chromium engine; rendering and JS feature checks still reveal Chromium", p.Browser))
+	}
+	if p.Browser == BrowserSafari && p.Platform != PlatformMac {
+		problems = append(problems, "Safari user agent on a platform other than macOS")
+	}
+	if o.Platform == "" && p.Platform != PlatformUnknown {
+		problems = append(problems, "no navigator.platform value for "+p.Platform)
+	}
+	return problems
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/messaging"
+)
+
+// statusLinkedInBlocked is the non-standard status LinkedIn answers
+// scripted traffic with
+const statusLinkedInBlocked = 999
+
+// throttleMonitor watches LinkedIn's responses on the automation page for
+// throttling: 429 and 999 statuses, and rate-limit headers with nothing
+// left. A throttle response starts a cooldown, taken from Retry-After when
+// the response has one, that the workflow waits out before its next action
+type throttleMonitor struct {
+	fallback time.Duration
+	db       database.Store
+	logger   *logger.Logger
+
+	mu      sync.Mutex
+	watched *rod.Page
+	until   time.Time
+	reason  string
+}
+
+func newThrottleMonitor(fallback time.Duration, db database.Store, log *logger.Logger) *throttleMonitor {
+	return &throttleMonitor{fallback: fallback, db: db, logger: log.WithComponent("throttle")}
+}
+
+// watch starts listening to page's responses. Watching the same page again
+// is a no-op, so it can be called after every login
+func (m *throttleMonitor) watch(page *rod.Page) {
+	m.mu.Lock()
+	if m.watched == page {
+		m.mu.Unlock()
+		return
+	}
+	m.watched = page
+	m.mu.Unlock()
+
+	go page.EachEvent(func(e *proto.NetworkResponseReceived) {
+		if e.Response != nil {
+			m.observe(e.Response.URL, e.Response.Status, e.Response.Headers)
+		}
+	})()
+	m.logger.Debug("watching LinkedIn responses for throttling")
+}
+
+// observe checks one response and starts or extends the cooldown when it
+// signals throttling
+func (m *throttleMonitor) observe(url string, status int, headers proto.NetworkHeaders) {
+	if !strings.Contains(url, "linkedin.com") {
+		return
+	}
+	reason, throttled := throttleReason(status, headers)
+	if !throttled {
+		return
+	}
+
+	cooldown, ok := retryAfter(header(headers, "Retry-After"), time.Now())
+	if !ok {
+		cooldown = m.fallback
+	}
+	m.start(reason, url, cooldown)
+}
+
+// flag starts the fallback cooldown for a throttling signal seen outside
+// the response stream, such as a persistent error page
+func (m *throttleMonitor) flag(reason, url string) {
+	m.start(reason, url, m.fallback)
+}
+
+// start starts or extends the cooldown and records the hit
+func (m *throttleMonitor) start(reason, url string, cooldown time.Duration) {
+	now := time.Now()
+	m.mu.Lock()
+	until := now.Add(cooldown)
+	extended := until.After(m.until)
+	if extended {
+		m.until, m.reason = until, reason
+	}
+	m.mu.Unlock()
+	if !extended {
+		return
+	}
+
+	m.logger.Warn("LinkedIn throttled a request", "reason", reason, "url", url, "cooldown", cooldown.Round(time.Second))
+	err := m.db.RecordLimitHit(&database.LimitHit{Kind: messaging.LimitThrottle, DetectedAt: now})
+	if err != nil {
+		m.logger.LogError("record throttle", err, nil)
+	}
+}
+
+// cooldown returns when the current cooldown ends and what caused it, or
+// the zero time when none is running
+func (m *throttleMonitor) cooldown() (time.Time, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Now().Before(m.until) {
+		return m.until, m.reason
+	}
+	return time.Time{}, ""
+}
+
+// throttleReason reports whether a response signals throttling and why
+func throttleReason(status int, headers proto.NetworkHeaders) (string, bool) {
+	switch status {
+	case http.StatusTooManyRequests:
+		return "429", true
+	case statusLinkedInBlocked:
+		return "999", true
+	}
+	for _, name := range []string{"X-RateLimit-Remaining", "X-Li-RateLimit-Remaining"} {
+		if v := strings.TrimSpace(header(headers, name)); v == "0" {
+			return strings.ToLower(name) + "=0", true
+		}
+	}
+	return "", false
+}
+
+// retryAfter parses a Retry-After value, either delay seconds or an HTTP
+// date, into a wait from now
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// header looks up a response header case-insensitively
+func header(headers proto.NetworkHeaders, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// afterThrottle waits out a throttle cooldown before the next action of
+// step. Business hours and the step's active hours are re-checked after
+// the wait, as after a clock jump. proceed is false when the step should
+// end; stop is set when the whole run should end too
+func (a *Automation) afterThrottle(step string, result *RunResult) (proceed, stop bool) {
+	if a.throttle == nil {
+		return true, false
+	}
+	until, reason := a.throttle.cooldown()
+	if until.IsZero() {
+		return true, false
+	}
+
+	result.Throttled++
+	wait := time.Until(until)
+	a.logger.Warn("pausing for LinkedIn throttling", "reason", reason, "step", step, "resume", until.Format("15:04:05"))
+	fmt.Printf("\n⚠ LinkedIn is throttling requests (%s), pausing %s until %s\n", reason, wait.Round(time.Second), until.Format("15:04:05"))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-a.stopChan:
+		return false, true
+	case <-timer.C:
+	}
+	a.clock = newClockWatch()
+
+	if !a.config.IsBusinessHours() {
+		fmt.Println("Outside business hours after the throttle pause, ending the run")
+		result.StopReason = StopOutsideHours
+		return false, true
+	}
+	if hours, effective, ok := a.stepHours(step); ok && !a.config.InActiveHours(hours) {
+		fmt.Printf("Outside %s active hours (%s) after the throttle pause, ending the step\n", step, effective)
+		return false, false
+	}
+	return true, false
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"github.com/go-rod/rod"
+)
+
+// Viewport assumed when the page cannot be measured
+const (
+	fallbackViewportWidth  = 1920
+	fallbackViewportHeight = 1080
+)
+
+// documentScript identifies the document a page shows along with its
+// viewport size. performance.timeOrigin changes on every navigation and
+// reload
+const documentScript = `() => [String(performance.timeOrigin), innerWidth, innerHeight]`
+
+// PageDocument returns an identifier of the document page currently shows,
+// which changes on every navigation and reload, and its viewport size. The
+// identifier is empty when the page cannot be read
+func PageDocument(page *rod.Page) (id string, width, height float64) {
+	res, err := page.Eval(documentScript)
+	if err != nil {
+		return "", fallbackViewportWidth, fallbackViewportHeight
+	}
+	values := res.Value.Arr()
+	if len(values) < 3 {
+		return "", fallbackViewportWidth, fallbackViewportHeight
+	}
+	return values[0].Str(), values[1].Num(), values[2].Num()
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Emoji policies for connection notes
+const (
+	EmojiAllow       = "allow"
+	EmojiStrip       = "strip"
+	EmojiLimitPrefix = "limit-"
+)
+
+// ParseEmojiPolicy turns allow, strip or limit-N into the number of emoji a
+// note may keep: -1 for allow, 0 for strip, N for limit-N
+func ParseEmojiPolicy(policy string) (int, error) {
+	switch {
+	case policy == "" || policy == EmojiAllow:
+		return -1, nil
+	case policy == EmojiStrip:
+		return 0, nil
+	case strings.HasPrefix(policy, EmojiLimitPrefix):
+		n, err := strconv.Atoi(strings.TrimPrefix(policy, EmojiLimitPrefix))
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid emoji limit in %q", policy)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("emoji policy must be allow, strip or limit-N, got %q", policy)
+}
+
+// emojiSegment is a run of text or a single emoji cluster
+type emojiSegment struct {
+	text  string
+	emoji bool
+}
+
+// Code points that extend or join an emoji cluster
+const (
+	zwj             = '\u200D'
+	variationText   = '\uFE0E'
+	variationEmoji  = '\uFE0F'
+	combiningKeycap = '\u20E3'
+	skinToneFirst   = '\U0001F3FB'
+	skinToneLast    = '\U0001F3FF'
+	tagFirst        = '\U000E0020'
+	tagLast         = '\U000E007F'
+	regionalFirst   = '\U0001F1E6'
+	regionalLast    = '\U0001F1FF'
+)
+
+// Blocks whose symbols are emoji without a variation selector
+const (
+	emojiBlockFirst    = '\U0001F000'
+	emojiBlockLast     = '\U0001FAFF'
+	symbolsFirst       = '\u2600' // miscellaneous symbols and dingbats
+	symbolsLast        = '\u27BF'
+	technicalFirst     = '\u2300'
+	technicalLast      = '\u23FF'
+	arrowsSymbolsFirst = '\u2B00'
+	arrowsSymbolsLast  = '\u2BFF'
+)
+
+// isEmojiBase reports whether r is presented as emoji on its own
+func isEmojiBase(r rune) bool {
+	return (r >= emojiBlockFirst && r <= emojiBlockLast) ||
+		(r >= symbolsFirst && r <= symbolsLast) ||
+		(r >= technicalFirst && r <= technicalLast) ||
+		(r >= arrowsSymbolsFirst && r <= arrowsSymbolsLast)
+}
+
+func isRegional(r rune) bool {
+	return r >= regionalFirst && r <= regionalLast
+}
+
+// isEmojiExtender reports code points that attach to the preceding emoji
+func isEmojiExtender(r rune) bool {
+	return r == variationEmoji || r == combiningKeycap ||
+		(r >= skinToneFirst && r <= skinToneLast) ||
+		(r >= tagFirst && r <= tagLast)
+}
+
+// emojiClusterLen returns how many runes starting at i form one emoji
+// cluster: a base with its modifiers, ZWJ sequences, flag pairs and keycaps.
+// It returns 0 when runes[i] does not start an emoji
+func emojiClusterLen(runes []rune, i int) int {
+	r := runes[i]
+	next := func(k int) rune {
+		if k < len(runes) {
+			return runes[k]
+		}
+		return 0
+	}
+
+	// Flags are pairs of regional indicators
+	if isRegional(r) {
+		if isRegional(next(i + 1)) {
+			return 2
+		}
+		return 1
+	}
+
+	// Keycaps: digit, # or *, optional FE0F, then U+20E3
+	if (r >= '0' && r <= '9') || r == '#' || r == '*' {
+		if next(i+1) == combiningKeycap {
+			return 2
+		}
+		if next(i+1) == variationEmoji && next(i+2) == combiningKeycap {
+			return 3
+		}
+		return 0
+	}
+
+	// Text-presentation selector keeps the symbol as text
+	if next(i+1) == variationText {
+		return 0
+	}
+	if !isEmojiBase(r) && next(i+1) != variationEmoji {
+		return 0
+	}
+
+	j := i + 1
+	for j < len(runes) {
+		switch {
+		case isEmojiExtender(runes[j]):
+			j++
+		case runes[j] == zwj && j+1 < len(runes):
+			j += 2
+		default:
+			return j - i
+		}
+	}
+	return j - i
+}
+
+// splitEmoji splits s into text runs and whole emoji clusters
+func splitEmoji(s string) []emojiSegment {
+	runes := []rune(s)
+	var segments []emojiSegment
+	start := 0
+	for i := 0; i < len(runes); {
+		n := emojiClusterLen(runes, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		if start < i {
+			segments = append(segments, emojiSegment{text: string(runes[start:i])})
+		}
+		segments = append(segments, emojiSegment{text: string(runes[i : i+n]), emoji: true})
+		i += n
+		start = i
+	}
+	if start < len(runes) {
+		segments = append(segments, emojiSegment{text: string(runes[start:])})
+	}
+	return segments
+}
+
+// CountEmoji returns the number of emoji in s, counting a ZWJ sequence or
+// flag as one
+func CountEmoji(s string) int {
+	count := 0
+	for _, seg := range splitEmoji(s) {
+		if seg.emoji {
+			count++
+		}
+	}
+	return count
+}
+
+// ApplyEmojiPolicy keeps at most limit emoji in note, dropping later ones
+// whole along with one adjacent space. A negative limit keeps them all
+func ApplyEmojiPolicy(note string, limit int) string {
+	if limit < 0 {
+		return note
+	}
+
+	var b strings.Builder
+	kept := 0
+	dropSpace := false
+	for _, seg := range splitEmoji(note) {
+		if !seg.emoji {
+			text := seg.text
+			if dropSpace {
+				text = strings.TrimPrefix(text, " ")
+				dropSpace = false
+			}
+			b.WriteString(text)
+			continue
+		}
+		if kept < limit {
+			b.WriteString(seg.text)
+			kept++
+			continue
+		}
+
+		// Drop the emoji and the space that separated it
+		out := b.String()
+		if strings.HasSuffix(out, " ") {
+			b.Reset()
+			b.WriteString(out[:len(out)-1])
+		} else {
+			dropSpace = true
+		}
+	}
+	return b.String()
+}
+
+// emojiSafeCut moves a cut at rune index cut back to the start of any
+// emoji cluster it would split
+func emojiSafeCut(runes []rune, cut int) int {
+	for i := 0; i < cut; {
+		n := emojiClusterLen(runes, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		if i+n > cut {
+			return i
+		}
+		i += n
+	}
+	return cut
+}
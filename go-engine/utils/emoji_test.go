@@ -0,0 +1,77 @@
+package utils
+
+import "testing"
+
+const (
+	family     = "👨‍👩‍👧" // ZWJ sequence of three people
+	profession = "👩🏽‍💻"  // skin tone, then ZWJ
+	flag       = "🇵🇹"
+	keycap     = "1️⃣"
+	waveTone   = "👋🏽"
+)
+
+func TestApplyEmojiPolicy(t *testing.T) {
+	note := "Great to meet you " + family + " " + profession + " " + flag + " " + keycap + " " + waveTone + " see you"
+	if n := CountEmoji(note); n != 5 {
+		t.Fatalf("CountEmoji = %d, want 5", n)
+	}
+
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{"allow", note},
+		{"limit-1", "Great to meet you " + family + " see you"},
+		{"limit-3", "Great to meet you " + family + " " + profession + " " + flag + " see you"},
+		{"strip", "Great to meet you see you"},
+	}
+	for _, tt := range tests {
+		limit, err := ParseEmojiPolicy(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ApplyEmojiPolicy(note, limit); got != tt.want {
+			t.Errorf("%s: ApplyEmojiPolicy = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+
+	// each cluster alone is kept or dropped whole
+	for _, emoji := range []string{family, profession, flag, keycap, waveTone} {
+		if got := ApplyEmojiPolicy("Hi "+emoji, 1); got != "Hi "+emoji {
+			t.Errorf("limit-1 on %q = %q", emoji, got)
+		}
+		if got := ApplyEmojiPolicy("Hi "+emoji+"!", 0); got != "Hi!" {
+			t.Errorf("strip on %q = %q, want %q", emoji, got, "Hi!")
+		}
+	}
+}
+
+func TestEmojiSafeCut(t *testing.T) {
+	for _, emoji := range []string{family, profession, flag, keycap, waveTone} {
+		runes := []rune("Hi " + emoji + "!")
+		end := len(runes) - 1 // where the cluster ends
+		for cut := 4; cut < end; cut++ {
+			if got := emojiSafeCut(runes, cut); got != 3 {
+				t.Errorf("%q: cut at %d moved to %d, want 3", emoji, cut, got)
+			}
+		}
+		for _, cut := range []int{2, 3, end, end + 1} {
+			if got := emojiSafeCut(runes, cut); got != cut {
+				t.Errorf("%q: cut at %d moved to %d, want it kept", emoji, cut, got)
+			}
+		}
+	}
+}
+
+func TestParseEmojiPolicy(t *testing.T) {
+	for policy, want := range map[string]int{"": -1, "allow": -1, "strip": 0, "limit-0": 0, "limit-2": 2} {
+		if got, err := ParseEmojiPolicy(policy); err != nil || got != want {
+			t.Errorf("ParseEmojiPolicy(%q) = %d, %v, want %d", policy, got, err, want)
+		}
+	}
+	for _, policy := range []string{"limit-x", "limit-", "limit--1", "none", "Strip"} {
+		if _, err := ParseEmojiPolicy(policy); err == nil {
+			t.Errorf("ParseEmojiPolicy(%q) succeeded", policy)
+		}
+	}
+}
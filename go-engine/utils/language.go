@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Where an inferred language came from
+const (
+	LanguageFromScript   = "headline_script"
+	LanguageFromLocation = "location"
+	LanguageFromWords    = "headline_words"
+)
+
+// locationLanguages maps countries and large cities, as LinkedIn shows them
+// in profile locations, to the language most members there write in.
+// Multilingual countries (Switzerland, Belgium, Canada, India) are left out
+// so they fall through to the headline or the default language
+var locationLanguages = map[string]string{
+	"germany": "de", "deutschland": "de", "berlin": "de", "munich": "de", "münchen": "de",
+	"hamburg": "de", "frankfurt": "de", "cologne": "de", "köln": "de", "stuttgart": "de",
+	"austria": "de", "österreich": "de", "vienna": "de", "wien": "de",
+	"france": "fr", "paris": "fr", "lyon": "fr", "marseille": "fr", "toulouse": "fr",
+	"spain": "es", "españa": "es", "madrid": "es", "barcelona": "es", "valencia": "es",
+	"mexico": "es", "méxico": "es", "argentina": "es", "colombia": "es", "chile": "es", "peru": "es", "perú": "es",
+	"portugal": "pt", "lisbon": "pt", "lisboa": "pt", "porto": "pt",
+	"brazil": "pt", "brasil": "pt", "são paulo": "pt", "sao paulo": "pt", "rio de janeiro": "pt",
+	"italy": "it", "italia": "it", "rome": "it", "roma": "it", "milan": "it", "milano": "it", "turin": "it", "torino": "it",
+	"netherlands": "nl", "nederland": "nl", "amsterdam": "nl", "rotterdam": "nl", "utrecht": "nl", "the hague": "nl",
+	"poland": "pl", "polska": "pl", "warsaw": "pl", "warszawa": "pl", "kraków": "pl", "krakow": "pl",
+	"sweden": "sv", "sverige": "sv", "stockholm": "sv", "gothenburg": "sv", "göteborg": "sv",
+	"turkey": "tr", "türkiye": "tr", "istanbul": "tr", "ankara": "tr",
+	"japan": "ja", "tokyo": "ja", "osaka": "ja",
+	"china": "zh", "beijing": "zh", "shanghai": "zh", "shenzhen": "zh", "taiwan": "zh", "taipei": "zh",
+	"south korea": "ko", "korea": "ko", "seoul": "ko",
+	"russia": "ru", "moscow": "ru", "saint petersburg": "ru",
+}
+
+// otherPlaces are locations whose name contains one of locationLanguages
+// but that lie elsewhere, so "Santa Fe, New Mexico" is not taken for Mexico
+var otherPlaces = []string{"new mexico"}
+
+// scriptLanguages maps writing systems to a language. Han is checked last
+// because Japanese headlines mix kanji with kana
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Han, "zh"},
+}
+
+// headlineWords are function words and job titles common in headlines
+// ("Entwickler bei X", "Ingénieur chez Y") that only one of the
+// Latin-script languages uses. Words English headlines use too, in names
+// like "MIT" or "Y Combinator", are left out
+var headlineWords = map[string]string{
+	"bei": "de", "und": "de", "für": "de", "der": "de", "entwickler": "de", "leiter": "de",
+	"chez": "fr", "et": "fr", "pour": "fr", "des": "fr", "ingénieur": "fr", "développeur": "fr",
+	"para": "es", "del": "es", "jefe": "es", "ingeniero": "es", "desarrollador": "es",
+	"na": "pt", "em": "pt", "gerente": "pt", "engenheiro": "pt", "desenvolvedor": "pt",
+	"presso": "it", "di": "it", "ingegnere": "it", "sviluppatore": "it",
+	"bij": "nl", "voor": "nl", "het": "nl", "ontwikkelaar": "nl", "medewerker": "nl",
+}
+
+// minHeadlineWords is how many tell-tale words a headline needs before its
+// language is trusted; one can be a name or an abbreviation
+const minHeadlineWords = 2
+
+// InferLanguage guesses the language a member writes in from their profile
+// location and headline. A non-Latin headline is the strongest signal, so
+// its script is checked first; then the location, with extra (keyword →
+// language) taking precedence over the built-in map; then tell-tale words
+// in a Latin headline. It returns the language and where it came from, or
+// empty strings when nothing matched
+func InferLanguage(location, headline string, extra map[string]string) (lang, source string) {
+	if lang := scriptLanguage(headline); lang != "" {
+		return lang, LanguageFromScript
+	}
+	if lang := LocationLanguage(location, extra); lang != "" {
+		return lang, LanguageFromLocation
+	}
+	if lang := headlineWordLanguage(headline); lang != "" {
+		return lang, LanguageFromWords
+	}
+	return "", ""
+}
+
+// LocationLanguage returns the language for a profile location such as
+// "Munich, Bavaria, Germany" or "Greater Paris Metropolitan Region". The
+// most specific part (the city) is tried first. The built-in map is not
+// consulted for parts naming one of otherPlaces
+func LocationLanguage(location string, extra map[string]string) string {
+	location = strings.ToLower(strings.TrimSpace(location))
+	if location == "" {
+		return ""
+	}
+	for _, part := range strings.Split(location, ",") {
+		part = strings.TrimSpace(part)
+		if lang := placeLanguage(part, extra); lang != "" {
+			return lang
+		}
+		if isOtherPlace(part) {
+			continue
+		}
+		if lang := placeLanguage(part, locationLanguages); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+// placeLanguage returns the language of the first place in places that
+// part names
+func placeLanguage(part string, places map[string]string) string {
+	for place, lang := range places {
+		if containsWords(part, strings.ToLower(place)) {
+			return lang
+		}
+	}
+	return ""
+}
+
+// isOtherPlace reports whether part names one of otherPlaces
+func isOtherPlace(part string) bool {
+	for _, other := range otherPlaces {
+		if containsWords(part, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptLanguage returns the language of the first non-Latin script with
+// letters in s, in scriptLanguages order
+func scriptLanguage(s string) string {
+	for _, sl := range scriptLanguages {
+		for _, r := range s {
+			if unicode.Is(sl.script, r) {
+				return sl.lang
+			}
+		}
+	}
+	return ""
+}
+
+// headlineWordLanguage returns the language with the most tell-tale words
+// in headline, or "" with fewer than minHeadlineWords of them or on a tie
+func headlineWordLanguage(headline string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(headline), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if lang, ok := headlineWords[word]; ok {
+			counts[lang]++
+		}
+	}
+
+	best, bestCount, tie := "", 0, false
+	for lang, n := range counts {
+		switch {
+		case n > bestCount:
+			best, bestCount, tie = lang, n, false
+		case n == bestCount:
+			tie = true
+		}
+	}
+	if tie || bestCount < minHeadlineWords {
+		return ""
+	}
+	return best
+}
+
+// containsWords reports whether phrase occurs in s on word boundaries, so
+// "rome" matches "Rome Area" but not "Jerome"
+func containsWords(s, phrase string) bool {
+	if phrase == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(s[i:], phrase)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(phrase)
+		if !letterBefore(s, start) && !letterAfter(s, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func letterBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r := []rune(s[:i])
+	return unicode.IsLetter(r[len(r)-1])
+}
+
+func letterAfter(s string, i int) bool {
+	for _, r := range s[i:] {
+		return unicode.IsLetter(r)
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package utils
+
+import "testing"
+
+func TestLocationLanguage(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{"Munich, Bavaria, Germany", "de"},
+		{"Greater Paris Metropolitan Region", "fr"},
+		{"São Paulo, São Paulo, Brazil", "pt"},
+		{"Mexico City, Mexico", "es"},
+		{"Milano, Lombardia, Italia", "it"},
+		{"Santa Fe, New Mexico, United States", ""},
+		{"Jerome, Idaho, United States", ""}, // not Rome
+		{"Zurich, Switzerland", ""},          // multilingual, left to the headline
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := LocationLanguage(tt.location, nil); got != tt.want {
+			t.Errorf("LocationLanguage(%q) = %q, want %q", tt.location, got, tt.want)
+		}
+	}
+
+	// configured locations are tried before the built-in ones
+	extra := map[string]string{"Zurich": "de", "New Mexico": "es"}
+	if got := LocationLanguage("Zurich, Switzerland", extra); got != "de" {
+		t.Errorf("LocationLanguage with a configured Zurich = %q, want de", got)
+	}
+	if got := LocationLanguage("Santa Fe, New Mexico, United States", extra); got != "es" {
+		t.Errorf("LocationLanguage with a configured New Mexico = %q, want es", got)
+	}
+}
+
+func TestInferLanguage(t *testing.T) {
+	tests := []struct {
+		location, headline string
+		want, wantSource   string
+	}{
+		{"", "Software Engineer at MIT", "", ""},
+		{"", "Partner at Y Combinator", "", ""},
+		{"Santa Fe, New Mexico, United States", "Product Manager", "", ""},
+		{"", "Director of Product per Acme, van Dyke Labs", "", ""},
+		{"", "Entwickler bei SAP", "de", LanguageFromWords},
+		{"", "Ingénieur logiciel chez Dassault et Thales", "fr", LanguageFromWords},
+		{"", "Ingeniero de software", "", ""}, // a single tell-tale word
+		{"Berlin, Germany", "Software Engineer at MIT", "de", LanguageFromLocation},
+		{"London, United Kingdom", "ソフトウェアエンジニア", "ja", LanguageFromScript},
+	}
+	for _, tt := range tests {
+		lang, source := InferLanguage(tt.location, tt.headline, nil)
+		if lang != tt.want || source != tt.wantSource {
+			t.Errorf("InferLanguage(%q, %q) = %q, %q, want %q, %q", tt.location, tt.headline, lang, source, tt.want, tt.wantSource)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/logger"
+)
+
+// ErrErrorPage is returned when LinkedIn keeps serving its generic
+// "something went wrong" page after every reload
+var ErrErrorPage = errors.New("LinkedIn error page")
+
+var (
+	errorPageMu        sync.Mutex
+	errorPageRetries   = 2
+	errorPageBackoff   = 30 * time.Second
+	errorPagePersisted func(url string)
+)
+
+// SetErrorPageRecovery configures how WaitLoadBounded recovers from
+// LinkedIn's error page: up to retries reloads, waiting backoff before the
+// first and doubling it before each further one. persisted, if not nil, is
+// called when the page is still an error page after the last reload. Call
+// it before any component navigates
+func SetErrorPageRecovery(retries int, backoff time.Duration, persisted func(url string)) {
+	errorPageMu.Lock()
+	defer errorPageMu.Unlock()
+	errorPageRetries, errorPageBackoff, errorPagePersisted = retries, backoff, persisted
+}
+
+// errorPageScript reports whether the page is LinkedIn's generic error
+// page rather than content, and the URL it is on. The text check is kept to
+// short pages so an error toast on a full page does not count
+const errorPageScript = `() => {
+	const text = document.body ? (document.body.innerText || '').trim() : '';
+	const error = text.length < 3000 &&
+		/something went wrong|this page (?:isn.t|is not) working|please try again later/i.test(text);
+	return { error, url: location.href };
+}`
+
+// WaitLoadBounded waits for the page load event for at most timeout.
+// LinkedIn's long-polling can hold the load event back indefinitely while
+// the DOM is already usable, so once the timeout passes the wait is given up
+// and the caller proceeds, unless the document is still loading. A zero
+// timeout waits without a bound. When the loaded page is LinkedIn's
+// "something went wrong" page, it is reloaded after a backoff, as set by
+// SetErrorPageRecovery; if it persists, ErrErrorPage is returned
+func WaitLoadBounded(page *rod.Page, timeout time.Duration, log *logger.Logger) error {
+	if err := waitLoad(page, timeout, log); err != nil {
+		return err
+	}
+
+	errorPageMu.Lock()
+	retries, backoff, persisted := errorPageRetries, errorPageBackoff, errorPagePersisted
+	errorPageMu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		url, isError := onErrorPage(page)
+		if !isError {
+			return nil
+		}
+		if attempt >= retries {
+			log.Warn("LinkedIn error page persists after reloading", "url", url, "reloads", retries)
+			if persisted != nil {
+				persisted(url)
+			}
+			return fmt.Errorf("%w at %s", ErrErrorPage, url)
+		}
+
+		wait := backoff << attempt
+		log.Warn("LinkedIn error page, reloading after backoff", "url", url, "attempt", attempt+1, "backoff", wait)
+		select {
+		case <-page.GetContext().Done():
+			return page.GetContext().Err()
+		case <-time.After(wait):
+		}
+		if err := page.Reload(); err != nil {
+			return fmt.Errorf("failed to reload error page: %w", err)
+		}
+		if err := waitLoad(page, timeout, log); err != nil {
+			return err
+		}
+	}
+}
+
+// onErrorPage reports the page's URL and whether it shows LinkedIn's error
+// page. A page that cannot be checked is assumed fine
+func onErrorPage(page *rod.Page) (string, bool) {
+	res, err := page.Eval(errorPageScript)
+	if err != nil {
+		return "", false
+	}
+	return res.Value.Get("url").Str(), res.Value.Get("error").Bool()
+}
+
+// waitLoad is the bounded wait for the load event behind WaitLoadBounded
+func waitLoad(page *rod.Page, timeout time.Duration, log *logger.Logger) error {
+	if timeout <= 0 {
+		return page.WaitLoad()
+	}
+
+	bounded := page.Timeout(timeout)
+	err := bounded.WaitLoad()
+	bounded.CancelTimeout()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	res, evalErr := page.Eval(`() => document.readyState`)
+	if evalErr != nil {
+		return fmt.Errorf("page load timed out after %s: %w", timeout, evalErr)
+	}
+	state := res.Value.Str()
+	if state == "loading" {
+		return fmt.Errorf("page still loading after %s", timeout)
+	}
+	log.Warn("page load did not finish in time, proceeding", "timeout", timeout, "ready_state", state)
+	return nil
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	seedMu     sync.Mutex
+	masterSeed = time.Now().UnixNano()
+	seedCounts = make(map[string]uint64)
+)
+
+// SetMasterSeed makes every RNG created by NewRand afterwards derive from
+// seed, so a run with the same seed replays identically. Call it before any
+// component is constructed
+func SetMasterSeed(seed int64) {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+	masterSeed = seed
+	seedCounts = make(map[string]uint64)
+}
+
+// MasterSeed returns the seed RNGs are currently derived from
+func MasterSeed() int64 {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+	return masterSeed
+}
+
+// NewRand returns an RNG for the named component. Its seed is derived from
+// the master seed, the component name and how many RNGs the component has
+// created so far, so components that build a fresh RNG per action still
+// replay in order
+func NewRand(component string) *rand.Rand {
+	seedMu.Lock()
+	n := seedCounts[component]
+	seedCounts[component] = n + 1
+	master := masterSeed
+	seedMu.Unlock()
+
+	var buf [8]byte
+	h := fnv.New64a()
+	binary.LittleEndian.PutUint64(buf[:], uint64(master))
+	h.Write(buf[:])
+	h.Write([]byte(component))
+	binary.LittleEndian.PutUint64(buf[:], n)
+	h.Write(buf[:])
+
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// JitteredLimit varies limit by up to percent in either direction, by an
+// amount derived from the day and name alone: the same day gives the same
+// limit all day, whatever the master seed, and the next day a different
+// one. A positive limit never drops below 1
+func JitteredLimit(limit, percent int, day time.Time, name string) int {
+	if limit <= 0 || percent <= 0 {
+		return limit
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(day.Format("2006-01-02")))
+	h.Write([]byte(name))
+	// Map the hash onto [-1, 1]
+	offset := float64(h.Sum64()%20001)/10000 - 1
+
+	jittered := limit + int(math.Round(float64(limit)*float64(percent)/100*offset))
+	if jittered < 1 {
+		return 1
+	}
+	return jittered
+}
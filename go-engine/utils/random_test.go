@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestJitteredLimitWithinPercent(t *testing.T) {
+	day := time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)
+	tests := []struct{ limit, percent int }{
+		{100, 10}, {50, 20}, {25, 30}, {7, 50},
+	}
+	for _, tt := range tests {
+		spread := int(math.Round(float64(tt.limit) * float64(tt.percent) / 100))
+		seen := make(map[int]bool)
+		for i := 0; i < 365; i++ {
+			got := JitteredLimit(tt.limit, tt.percent, day.AddDate(0, 0, i), "connections")
+			if got < tt.limit-spread || got > tt.limit+spread {
+				t.Fatalf("JitteredLimit(%d, %d%%) = %d, want %d..%d", tt.limit, tt.percent, got, tt.limit-spread, tt.limit+spread)
+			}
+			seen[got] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("JitteredLimit(%d, %d%%) gave %v over a year, want it to vary by day", tt.limit, tt.percent, seen)
+		}
+	}
+}
+
+func TestJitteredLimitStableForDay(t *testing.T) {
+	morning := time.Date(2024, 3, 5, 0, 1, 0, 0, time.Local)
+	want := JitteredLimit(100, 25, morning, "connections")
+	for _, at := range []time.Time{morning.Add(8 * time.Hour), morning.Add(23 * time.Hour)} {
+		if got := JitteredLimit(100, 25, at, "connections"); got != want {
+			t.Errorf("JitteredLimit at %s = %d, want %d as earlier that day", at.Format(time.Kitchen), got, want)
+		}
+	}
+
+	// Unaffected by the master seed
+	SetMasterSeed(42)
+	if got := JitteredLimit(100, 25, morning, "connections"); got != want {
+		t.Errorf("JitteredLimit after reseeding = %d, want %d", got, want)
+	}
+}
+
+func TestJitteredLimitFloor(t *testing.T) {
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 365; i++ {
+		if got := JitteredLimit(1, 100, day.AddDate(0, 0, i), "messages"); got < 1 {
+			t.Fatalf("JitteredLimit(1, 100%%) = %d on day %d, want at least 1", got, i)
+		}
+	}
+
+	for _, tt := range []struct{ limit, percent int }{{0, 20}, {-1, 20}, {30, 0}} {
+		if got := JitteredLimit(tt.limit, tt.percent, day, "messages"); got != tt.limit {
+			t.Errorf("JitteredLimit(%d, %d%%) = %d, want it unchanged", tt.limit, tt.percent, got)
+		}
+	}
+}
@@ -3,32 +3,88 @@ package utils
 import (
 	"fmt"
 	"math"
-	"math/rand"
+	"sync"
 	"time"
 )
 
 // RetryConfig configures retry behavior
 type RetryConfig struct {
-	MaxRetries     int
-	InitialDelay   time.Duration
-	MaxDelay       time.Duration
-	JitterPercent  float64
+	MaxRetries    int
+	InitialDelay  time.Duration
+	MaxDelay      time.Duration
+	JitterPercent float64
+	Retryable     func(error) bool // nil retries every error
+	Budget        *RetryBudget     // shared run-wide cap, nil for none
 }
 
-// DefaultRetryConfig returns sensible defaults
-func DefaultRetryConfig() RetryConfig {
+// RetryBudget caps the total number of retries across every action in a
+// run, so a degraded session cannot retry each action its full count
+type RetryBudget struct {
+	mu         sync.Mutex
+	remaining  int
+	depleted   bool
+	onDepleted func()
+}
+
+// NewRetryBudget creates a budget of total retries. onDepleted, if set, is
+// called once, the first time a retry is refused
+func NewRetryBudget(total int, onDepleted func()) *RetryBudget {
+	return &RetryBudget{remaining: total, onDepleted: onDepleted}
+}
+
+// Take spends one retry, reporting false once the budget is used up
+func (b *RetryBudget) Take() bool {
+	b.mu.Lock()
+	if b.remaining > 0 {
+		b.remaining--
+		b.mu.Unlock()
+		return true
+	}
+	notify := !b.depleted
+	b.depleted = true
+	b.mu.Unlock()
+
+	if notify && b.onDepleted != nil {
+		b.onDepleted()
+	}
+	return false
+}
+
+// Remaining returns how many retries are left
+func (b *RetryBudget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+// Depleted reports whether a retry has been refused for lack of budget
+func (b *RetryBudget) Depleted() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.depleted
+}
+
+// NavigationRetryConfig retries transient navigation failures a couple of
+// times with short backoff
+func NavigationRetryConfig() RetryConfig {
 	return RetryConfig{
-		MaxRetries:    5,
-		InitialDelay:  1 * time.Second,
-		MaxDelay:      30 * time.Second,
+		MaxRetries:    2,
+		InitialDelay:  2 * time.Second,
+		MaxDelay:      10 * time.Second,
 		JitterPercent: 0.5,
+		Retryable:     IsTransientError,
 	}
 }
 
-// RetryWithBackoff executes a function with exponential backoff
+// RetryWithBackoff executes a function with exponential backoff. Each retry
+// is drawn from cfg.Budget when one is set; once it runs dry the last error
+// is returned without further attempts
 func RetryWithBackoff(cfg RetryConfig, fn func() error) error {
 	var lastErr error
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rng := NewRand("retry")
 
 	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
 		err := fn()
@@ -40,6 +96,12 @@ func RetryWithBackoff(cfg RetryConfig, fn func() error) error {
 		if attempt == cfg.MaxRetries {
 			break
 		}
+		if cfg.Retryable != nil && !cfg.Retryable(err) {
+			return err
+		}
+		if cfg.Budget != nil && !cfg.Budget.Take() {
+			return fmt.Errorf("retry budget exhausted: %w", lastErr)
+		}
 
 		// Calculate exponential backoff with jitter
 		delay := cfg.InitialDelay * time.Duration(math.Pow(2, float64(attempt)))
@@ -57,6 +119,15 @@ func RetryWithBackoff(cfg RetryConfig, fn func() error) error {
 	return fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
+// RecoverAsError converts a panic in the calling function into an error
+// stored in *err. Use it as `defer utils.RecoverAsError(&err)` with a
+// named error return so a single failed action cannot crash the run
+func RecoverAsError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("recovered from panic: %v", r)
+	}
+}
+
 // IsTransientError checks if an error is transient and retryable
 func IsTransientError(err error) bool {
 	if err == nil {
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// templateVars are the variables note and message templates may use
+var templateVars = map[string]bool{
+	"firstName": true, "lastName": true, "jobTitle": true, "company": true, "location": true,
+	"mutualCount": true, "mutualName": true, "sharedSchool": true, "sharedGroup": true,
+	"greeting": true, "closing": true,
+}
+
+// ValidateTemplate checks that a template's placeholders name known
+// variables, are closed and that its {{#if}} sections balance. Each problem
+// names the character position it was found at
+func ValidateTemplate(template string) []string {
+	var problems []string
+	at := func(pos int) int { return utf8.RuneCountInString(template[:pos]) }
+
+	var open []int // positions of the {{#if}} sections still open
+	for _, tok := range tokenizeTemplate(template) {
+		switch tok.kind {
+		case tokenText:
+			if i := strings.Index(tok.text, "{{"); i >= 0 {
+				problems = append(problems, fmt.Sprintf("unclosed placeholder %q at position %d", tok.text[i:], at(tok.pos+i)))
+			}
+		case tokenVar, tokenIf:
+			if !templateVars[tok.name] {
+				problems = append(problems, fmt.Sprintf("unknown variable %q at position %d", tok.name, at(tok.pos)))
+			}
+			if tok.kind == tokenIf {
+				open = append(open, tok.pos)
+			}
+		case tokenEndIf:
+			if len(open) == 0 {
+				problems = append(problems, fmt.Sprintf("{{/if}} without a matching {{#if}} at position %d", at(tok.pos)))
+				continue
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	for _, pos := range open {
+		problems = append(problems, fmt.Sprintf("{{#if}} without a matching {{/if}} at position %d", at(pos)))
+	}
+	return problems
+}
+
+// TemplateVariable is a {{var}} or {{var|fallback}} placeholder
+type TemplateVariable struct {
+	Name        string
+	HasFallback bool
+}
+
+// TemplateVariables returns the {{var}} placeholders of template in order,
+// leaving out the {{#if}} sections
+func TemplateVariables(template string) []TemplateVariable {
+	var vars []TemplateVariable
+	for _, tok := range tokenizeTemplate(template) {
+		if tok.kind == tokenVar {
+			vars = append(vars, TemplateVariable{Name: tok.name, HasFallback: tok.hasFallback})
+		}
+	}
+	return vars
+}
+
+// Kinds of template token
+type tokenKind int
+
+const (
+	tokenText  tokenKind = iota // literal text
+	tokenVar                    // {{var}} or {{var|fallback}}
+	tokenIf                     // {{#if var}}
+	tokenEndIf                  // {{/if}}
+)
+
+// templateToken is one piece of a template
+type templateToken struct {
+	kind        tokenKind
+	pos         int    // byte offset in the template
+	text        string // literal text of a tokenText
+	name        string // variable of a tokenVar or tokenIf
+	fallback    string // text a tokenVar renders when its variable is empty
+	hasFallback bool
+}
+
+// tokenizeTemplate splits a template into text and placeholders. A "{{"
+// with no "}}" after it is kept as text
+func tokenizeTemplate(template string) []templateToken {
+	var tokens []templateToken
+	for pos := 0; pos < len(template); {
+		rest := template[pos:]
+		start := strings.Index(rest, "{{")
+		end := -1
+		if start >= 0 {
+			end = strings.Index(rest[start+2:], "}}")
+		}
+		if start < 0 || end < 0 {
+			tokens = append(tokens, templateToken{kind: tokenText, pos: pos, text: rest})
+			break
+		}
+		if start > 0 {
+			tokens = append(tokens, templateToken{kind: tokenText, pos: pos, text: rest[:start]})
+		}
+		tok := parsePlaceholder(rest[start+2 : start+2+end])
+		tok.pos = pos + start
+		tokens = append(tokens, tok)
+		pos += start + 2 + end + 2
+	}
+	return tokens
+}
+
+// parsePlaceholder reads what is between "{{" and "}}"
+func parsePlaceholder(inner string) templateToken {
+	inner = strings.TrimSpace(inner)
+	if inner == "/if" {
+		return templateToken{kind: tokenEndIf}
+	}
+	if name, ok := strings.CutPrefix(inner, "#if "); ok {
+		return templateToken{kind: tokenIf, name: strings.TrimSpace(name)}
+	}
+	name, fallback, ok := strings.Cut(inner, "|")
+	return templateToken{kind: tokenVar, name: strings.TrimSpace(name), fallback: fallback, hasFallback: ok}
+}
+
+// RenderTemplate fills a template with vars. {{var}} is replaced by the
+// variable's value and {{var|fallback}} by the fallback when the value is
+// empty. {{#if var}}...{{/if}} sections, which may nest, are kept only when
+// the variable has a value. Unknown variables count as empty, a stray
+// {{/if}} is dropped and an unclosed section runs to the end
+func RenderTemplate(template string, vars map[string]string) string {
+	var out strings.Builder
+	// shown holds, for each open section, whether its body is rendered
+	var shown []bool
+	visible := func() bool { return len(shown) == 0 || shown[len(shown)-1] }
+
+	for _, tok := range tokenizeTemplate(template) {
+		switch tok.kind {
+		case tokenIf:
+			shown = append(shown, visible() && strings.TrimSpace(vars[tok.name]) != "")
+		case tokenEndIf:
+			if len(shown) > 0 {
+				shown = shown[:len(shown)-1]
+			}
+		case tokenText:
+			if visible() {
+				out.WriteString(tok.text)
+			}
+		case tokenVar:
+			if !visible() {
+				continue
+			}
+			if value := vars[tok.name]; strings.TrimSpace(value) != "" {
+				out.WriteString(value)
+			} else {
+				out.WriteString(tok.fallback)
+			}
+		}
+	}
+	return out.String()
+}
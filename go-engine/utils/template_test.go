@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]string{"firstName": "Jane", "company": "Acme", "jobTitle": "  ", "mutualName": "Tom"}
+	tests := []struct {
+		name, template, want string
+	}{
+		{"plain", "Hi {{firstName}} at {{company}}", "Hi Jane at Acme"},
+		{"spaces inside", "Hi {{ firstName }}", "Hi Jane"},
+		{"missing variable", "Hi {{lastName}}!", "Hi !"},
+		{"unknown variable", "Hi {{nickname}}!", "Hi !"},
+		{"fallback unused", "Hi {{firstName|there}}", "Hi Jane"},
+		{"fallback for missing", "Hi {{lastName|there}}", "Hi there"},
+		{"fallback for blank", "Your work as {{jobTitle|a leader}}", "Your work as a leader"},
+		{"empty fallback", "Hi{{lastName|}}.", "Hi."},
+		{"if kept", "Hi{{#if company}} from {{company}}{{/if}}.", "Hi from Acme."},
+		{"if dropped", "Hi{{#if location}} in {{location}}{{/if}}.", "Hi."},
+		{"if on blank", "{{#if jobTitle}}As {{jobTitle}}, {{/if}}hello", "hello"},
+		{"nested both kept", "{{#if company}}At {{company}}{{#if mutualName}} with {{mutualName}}{{/if}}.{{/if}}", "At Acme with Tom."},
+		{"nested inner dropped", "{{#if company}}At {{company}}{{#if location}} in {{location}}{{/if}}.{{/if}}", "At Acme."},
+		{"nested outer dropped", "{{#if location}}In {{location}}{{#if company}} at {{company}}{{/if}}.{{/if}}ok", "ok"},
+		{"stray endif", "Hi {{firstName}}{{/if}}!", "Hi Jane!"},
+		{"unclosed if runs to end", "Hi{{#if location}} in {{location}}", "Hi"},
+		{"unclosed placeholder", "Hi {{firstName", "Hi {{firstName"},
+	}
+	for _, tt := range tests {
+		if got := RenderTemplate(tt.template, vars); got != tt.want {
+			t.Errorf("%s: RenderTemplate(%q) = %q, want %q", tt.name, tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	got := TemplateVariables("{{#if company}}{{firstName}} at {{company|your company}}{{/if}} {{lastName}}")
+	want := []TemplateVariable{{Name: "firstName"}, {Name: "company", HasFallback: true}, {Name: "lastName"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateVariables = %+v, want %+v", got, want)
+	}
+}
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		problems []string
+	}{
+		{"Hi {{firstName|there}}{{#if company}} at {{company}}{{/if}}", nil},
+		{"Hi {{firstname}}", []string{`unknown variable "firstname" at position 3`}},
+		{"{{#if title}}x{{/if}}", []string{`unknown variable "title" at position 0`}},
+		{"Hi {{firstName", []string{`unclosed placeholder "{{firstName" at position 3`}},
+		{"Hi{{/if}}", []string{"{{/if}} without a matching {{#if}} at position 2"}},
+		{"{{#if company}}{{#if location}}x{{/if}}", []string{"{{#if}} without a matching {{/if}} at position 0"}},
+		// positions count characters, not bytes
+		{"Grüße {{vorname}}", []string{`unknown variable "vorname" at position 6`}},
+	}
+	for _, tt := range tests {
+		if got := ValidateTemplate(tt.template); !reflect.DeepEqual(got, tt.problems) {
+			t.Errorf("ValidateTemplate(%q) = %q, want %q", tt.template, got, tt.problems)
+		}
+	}
+}
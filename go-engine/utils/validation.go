@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // LinkedInURLRegex matches LinkedIn profile URLs
@@ -66,6 +67,25 @@ func ExtractProfileIDFromURL(profileURL string) string {
 	return path
 }
 
+// CanonicalProfileURL reduces a profile page URL to scheme://host/in/<slug>/,
+// dropping query strings and sub-pages such as /in/<slug>/overlay/... It
+// reports false for URLs that are not /in/ profile pages
+func CanonicalProfileURL(profileURL string) (string, bool) {
+	parsed, err := url.Parse(profileURL)
+	if err != nil || !strings.HasPrefix(parsed.Path, "/in/") {
+		return "", false
+	}
+
+	slug := strings.TrimPrefix(parsed.Path, "/in/")
+	if i := strings.Index(slug, "/"); i >= 0 {
+		slug = slug[:i]
+	}
+	if slug == "" {
+		return "", false
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/in/" + slug + "/", true
+}
+
 // ValidateEmail validates an email address format
 func ValidateEmail(email string) error {
 	if email == "" {
@@ -80,19 +100,103 @@ func ValidateEmail(email string) error {
 	return nil
 }
 
+// Connection note limits, in characters. LinkedIn accepts up to 300 from
+// Premium accounts, while free accounts are held to 200 in many markets
+const (
+	DefaultMaxNoteLength  = 200
+	LinkedInMaxNoteLength = 300
+)
+
 // ValidateNoteLength validates that a connection note is within LinkedIn's limits
 func ValidateNoteLength(note string, maxLength int) error {
 	if maxLength == 0 {
-		maxLength = 300
+		maxLength = DefaultMaxNoteLength
 	}
 
-	if len(note) > maxLength {
-		return fmt.Errorf("note exceeds maximum length of %d characters (got %d)", maxLength, len(note))
+	if n := utf8.RuneCountInString(note); n > maxLength {
+		return fmt.Errorf("note exceeds maximum length of %d characters (got %d)", maxLength, n)
 	}
 
 	return nil
 }
 
+// NoteInBand reports whether note is between min and max characters.
+// A zero bound is not enforced
+func NoteInBand(note string, min, max int) bool {
+	n := utf8.RuneCountInString(note)
+	return n >= min && (max <= 0 || n <= max)
+}
+
+// ellipsis ends a trimmed note, one character where "..." would take three
+const ellipsis = "…"
+
+// TrimNote shortens note to at most max characters, cutting at the last
+// word boundary and ending it with an ellipsis. Emoji clusters are never split
+func TrimNote(note string, max int) string {
+	runes := []rune(note)
+	if max <= 0 || len(runes) <= max {
+		return note
+	}
+	if max <= 1 {
+		return string(runes[:emojiSafeCut(runes, max)])
+	}
+
+	cut := max - 1
+	if space := strings.LastIndexAny(string(runes[:cut+1]), " \n"); space > 0 {
+		cut = utf8.RuneCountInString(string(runes[:cut+1])[:space])
+	}
+	cut = emojiSafeCut(runes, cut)
+	return strings.TrimRight(string(runes[:cut]), " \n,;:") + ellipsis
+}
+
+// SignNote appends signature on its own line after body and trims the
+// body, never the signature, so the signed note fits in max characters.
+// Without a signature it is TrimNote
+func SignNote(body, signature string, max int) string {
+	signature = strings.TrimSpace(signature)
+	if signature == "" {
+		return TrimNote(body, max)
+	}
+	room := max - utf8.RuneCountInString(signature) - 1
+	if max > 0 && room <= 0 {
+		return signature
+	}
+	return TrimNote(body, room) + "\n" + signature
+}
+
+// FitNoteLength pads a note body shorter than min with padding and trims
+// one longer than max, counting characters rather than bytes. The
+// signature, if any, is appended after the padding and kept whole. It
+// reports whether the result is within the band
+func FitNoteLength(body, signature string, min, max int, padding string) (string, bool) {
+	if min > 0 && padding != "" && utf8.RuneCountInString(SignNote(body, signature, 0)) < min {
+		body = strings.TrimRight(body, " ") + " " + strings.TrimSpace(padding)
+	}
+	note := SignNote(body, signature, max)
+	return note, NoteInBand(note, min, max)
+}
+
+// NormalizeNoteNewlines canonicalizes line breaks in a note: CRLF and CR
+// become LF, trailing spaces on each line are dropped and runs of blank
+// lines collapse to a single blank line. Length limits should be checked
+// on the normalized text since every remaining newline counts as a character
+func NormalizeNoteNewlines(note string) string {
+	note = strings.ReplaceAll(note, "\r\n", "\n")
+	note = strings.ReplaceAll(note, "\r", "\n")
+
+	lines := strings.Split(note, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	note = strings.Join(lines, "\n")
+
+	for strings.Contains(note, "\n\n\n") {
+		note = strings.ReplaceAll(note, "\n\n\n", "\n\n")
+	}
+
+	return strings.TrimSpace(note)
+}
+
 // SanitizeText removes potentially dangerous characters from text
 func SanitizeText(text string) string {
 	// Remove control characters except newlines and tabs
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTrimNote(t *testing.T) {
+	tests := []struct {
+		name string
+		note string
+		max  int
+		want string
+	}{
+		{"no limit", "Hello there", 0, "Hello there"},
+		{"accents fit exactly", "Grüße, José", 11, "Grüße, José"},
+		{"emoji fits exactly", "Hi 👋🏽", 5, "Hi 👋🏽"},
+		{"word boundary", "Hello wonderful world", 12, "Hello…"},
+		{"accented words", "Ça va très bien", 10, "Ça va…"},
+		{"trailing punctuation dropped", "Thanks, Zoë and all", 10, "Thanks…"},
+		{"no boundary", "Supercalifragilistic", 6, "Super…"},
+		{"emoji cluster kept whole", "👋🏽👋🏽👋🏽", 4, "👋🏽…"},
+		{"max one", "Hello", 1, "H"},
+		{"max one on an emoji", "👋🏽 hi", 1, ""},
+		{"negative max", "Hello", -1, "Hello"},
+	}
+	for _, tt := range tests {
+		if got := TrimNote(tt.note, tt.max); got != tt.want {
+			t.Errorf("%s: TrimNote(%q, %d) = %q, want %q", tt.name, tt.note, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestTrimNoteNeverExceedsMax(t *testing.T) {
+	notes := []string{
+		"Hi Zoë, loved your talk on Kubernetes at KubeCon 🚀",
+		"Ölçüm ve değerlendirme üzerine çalışıyorum",
+		"👨‍👩‍👧 family first, then 🇵🇹 and ☕️",
+		"Averyveryveryverylongwordwithoutanyspaces",
+	}
+	for _, note := range notes {
+		for max := 1; max <= utf8.RuneCountInString(note)+1; max++ {
+			got := TrimNote(note, max)
+			if n := utf8.RuneCountInString(got); n > max {
+				t.Fatalf("TrimNote(%q, %d) = %q, %d characters", note, max, got, n)
+			}
+			if !utf8.ValidString(got) {
+				t.Fatalf("TrimNote(%q, %d) = %q, not valid UTF-8", note, max, got)
+			}
+		}
+	}
+}
+
+func TestNoteInBand(t *testing.T) {
+	tests := []struct {
+		note     string
+		min, max int
+		want     bool
+	}{
+		{"Hello", 0, 0, true},
+		{"Hello", 5, 5, true},
+		{"Hello", 6, 0, false},
+		{"Hello", 0, 4, false},
+		{"Grüße", 5, 5, true}, // characters, not bytes
+		{"", 1, 10, false},
+	}
+	for _, tt := range tests {
+		if got := NoteInBand(tt.note, tt.min, tt.max); got != tt.want {
+			t.Errorf("NoteInBand(%q, %d, %d) = %v, want %v", tt.note, tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestFitNoteLength(t *testing.T) {
+	vars := map[string]string{"firstName": "Zoë", "company": "Acme Robotics"}
+	short := RenderTemplate("Hi {{firstName}}!", vars)
+	long := RenderTemplate("Hi {{firstName}}, your team at {{company}} ships impressive robots and I would love to hear more about it.", vars)
+	const padding = "Looking forward to connecting."
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		min, max  int
+		padding   string
+		want      string
+		wantOK    bool
+	}{
+		{"too short, padded", short, "", 30, 60, padding, "Hi Zoë! Looking forward to connecting.", true},
+		{"too short, padded before the signature", short, "– Sam", 30, 60, padding, "Hi Zoë! Looking forward to connecting.\n– Sam", true},
+		{"too short without padding", short, "", 30, 60, "", "Hi Zoë!", false},
+		{"too short even padded", short, "", 50, 80, padding, "Hi Zoë! Looking forward to connecting.", false},
+		{"too long, trimmed", long, "", 20, 40, padding, "Hi Zoë, your team at Acme Robotics…", true},
+		{"too long, trimmed with the signature", long, "– Sam", 20, 40, padding, "Hi Zoë, your team at Acme…\n– Sam", true},
+		{"in band", short, "", 5, 60, padding, "Hi Zoë!", true},
+	}
+	for _, tt := range tests {
+		got, ok := FitNoteLength(tt.body, tt.signature, tt.min, tt.max, tt.padding)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: FitNoteLength = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+		if n := utf8.RuneCountInString(got); n > tt.max {
+			t.Errorf("%s: %d characters, max %d", tt.name, n, tt.max)
+		}
+	}
+}
+
+func TestSignNoteKeepsSignature(t *testing.T) {
+	body := "Hi Zoë, I came across your talk on distributed tracing and would love to connect to swap notes 🚀"
+	signature := "– Sam, Acme Robotics"
+	for max := utf8.RuneCountInString(signature) + 2; max <= 120; max++ {
+		got := SignNote(body, signature, max)
+		if n := utf8.RuneCountInString(got); n > max {
+			t.Fatalf("SignNote at max %d = %q, %d characters", max, got, n)
+		}
+		if !strings.HasSuffix(got, "\n"+signature) {
+			t.Fatalf("SignNote at max %d = %q, signature not kept whole", max, got)
+		}
+	}
+
+	if got, want := SignNote(body, signature, 40), "Hi Zoë, I came…\n"+signature; got != want {
+		t.Errorf("SignNote at max 40 = %q, want %q", got, want)
+	}
+	// no room for the body
+	if got := SignNote(body, signature, utf8.RuneCountInString(signature)); got != signature {
+		t.Errorf("SignNote with room for the signature only = %q", got)
+	}
+}
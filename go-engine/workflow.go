@@ -0,0 +1,683 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+
+	"linkedin-automation/config"
+	"linkedin-automation/database"
+	"linkedin-automation/messaging"
+	"linkedin-automation/utils"
+)
+
+// Workflow steps that run after authentication
+const (
+	StepConnect        = "connect"         // search, then send connection requests
+	StepDetectAccepted = "detect_accepted" // refresh acceptance of pending invitations
+	StepFollowUp       = "follow_up"       // message accepted connections
+)
+
+// Phases selectable with --only. The connect step is split into its search
+// and connect phases, which share work through the persisted search queue
+const (
+	PhaseSearch  = "search"  // search and queue new profiles
+	PhaseConnect = "connect" // send connection requests to queued profiles
+	PhaseMessage = "message" // the follow_up step
+	PhaseDetect  = "detect"  // the detect_accepted step
+)
+
+// unrecordedCriteria marks connections to profiles queued before the search
+// criteria were stored with the queue
+const unrecordedCriteria = "unrecorded"
+
+// phaseList collects repeated --only flags. An empty list runs every phase
+type phaseList []string
+
+func (p *phaseList) String() string {
+	return strings.Join(*p, ",")
+}
+
+// Set validates and appends one --only value; comma-separated lists are
+// accepted too
+func (p *phaseList) Set(value string) error {
+	for _, phase := range strings.Split(value, ",") {
+		phase = strings.TrimSpace(phase)
+		switch phase {
+		case PhaseSearch, PhaseConnect, PhaseMessage, PhaseDetect:
+		default:
+			return fmt.Errorf("unknown phase %q (want search, connect, message or detect)", phase)
+		}
+		*p = append(*p, phase)
+	}
+	return nil
+}
+
+// has reports whether phase should run
+func (p phaseList) has(phase string) bool {
+	if len(p) == 0 {
+		return true
+	}
+	for _, selected := range p {
+		if selected == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// stepPhases maps each workflow step to the phases it is made of
+var stepPhases = map[string][]string{
+	StepConnect:        {PhaseSearch, PhaseConnect},
+	StepDetectAccepted: {PhaseDetect},
+	StepFollowUp:       {PhaseMessage},
+}
+
+// stepSelected reports whether any phase of step was selected with --only
+func (a *Automation) stepSelected(step string) bool {
+	for _, phase := range stepPhases[step] {
+		if a.phases.has(phase) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultStepOrder is the order used when workflow.step_order is empty
+var defaultStepOrder = []string{StepConnect, StepDetectAccepted, StepFollowUp}
+
+// resolveStepOrder returns the steps to run after authentication: the
+// configured order (or the default), shuffled when randomize_order is set
+func resolveStepOrder(cfg config.WorkflowConfig) []string {
+	order := append([]string(nil), defaultStepOrder...)
+	if len(cfg.StepOrder) > 0 {
+		order = append([]string(nil), cfg.StepOrder...)
+	}
+
+	if cfg.RandomizeOrder {
+		rng := utils.NewRand("workflow")
+		rng.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+	return order
+}
+
+// runStep executes one workflow unit: a whole step, or a batch of up to
+// unit.limit actions of one. stopped is true if the run was interrupted
+// and should stop; more is true when a batch left work for later units.
+// Connect and follow-up are skipped outside their action's active hours,
+// and follow-up in observe-only runs
+func (a *Automation) runStep(unit workUnit, n int, result *RunResult) (stopped, more bool) {
+	step := unit.step
+	if step == StepFollowUp && a.observing() {
+		fmt.Printf("\n[Step %d] Skipping %s: observe-only run\n", n, step)
+		return false, false
+	}
+	if hours, effective, ok := a.stepHours(step); ok && !a.config.InActiveHours(hours) {
+		a.logger.Info("outside active hours, skipping step", "step", step, "hours", effective.String())
+		fmt.Printf("\n[Step %d] Skipping %s: outside its active hours (%s)\n", n, step, effective)
+		return false, false
+	}
+
+	switch step {
+	case StepConnect:
+		return a.runConnectStep(n, unit.limit, result)
+	case StepDetectAccepted:
+		a.runDetectAcceptedStep(n, result)
+	case StepFollowUp:
+		return a.runFollowUpStep(n, unit.limit, result)
+	default:
+		a.logger.Warn("unknown workflow step, skipping", "step", step)
+	}
+	return false, false
+}
+
+// stepHours returns the configured and effective active hours of the
+// action a step performs. ok is false for steps without a window
+func (a *Automation) stepHours(step string) (hours, effective config.ActiveHours, ok bool) {
+	switch step {
+	case StepConnect:
+		return a.config.Connection.ActiveHours, a.config.ConnectionHours(), true
+	case StepFollowUp:
+		return a.config.Messaging.ActiveHours, a.config.MessagingHours(), true
+	}
+	return config.ActiveHours{}, config.ActiveHours{}, false
+}
+
+// runConnectStep runs the search phase, which queues new profiles, and the
+// connect phase, which sends connection requests to the queue. Either can
+// be left out with --only; connect alone works through profiles queued by
+// an earlier run. A resumed run skips the search and picks up the
+// interrupted run's queue. Observe-only runs search but do not connect.
+// With a limit, only that many requests are sent and more reports whether
+// the queue has profiles left; the search and suggestions phases run with
+// the first batch only
+func (a *Automation) runConnectStep(n, limit int, result *RunResult) (stopped, more bool) {
+	source := a.config.Connection.Source
+	searching := source != messaging.SourcePYMK && a.phases.has(PhaseSearch) && a.resume == nil
+	first := !a.work.connectStarted
+	a.work.connectStarted = true
+	if searching && first {
+		a.runSearchPhase(n, result)
+	}
+	if !a.phases.has(PhaseConnect) {
+		return false, false
+	}
+	if a.observing() {
+		fmt.Println("\nSkipping connection requests: observe-only run")
+		return false, false
+	}
+	if first && source != messaging.SourceSearch && a.burst.connectionsLeft(result.ConnectionsSent) {
+		if a.runSuggestionsPhase(n, result) {
+			return true, false
+		}
+	}
+	if source == messaging.SourcePYMK {
+		return false, false
+	}
+
+	if searching && first {
+		fmt.Println("\nSending connection requests...")
+	} else {
+		fmt.Printf("\n[Step %d] Sending connection requests to queued profiles...\n", n)
+	}
+
+	queue, err := a.connectQueue()
+	if err != nil {
+		a.logger.LogError("load search queue", err, nil)
+		fmt.Printf("⚠ Could not load queued profiles: %v\n", err)
+		return false, false
+	}
+	// Earlier batches of this run already tried these
+	untried := queue[:0]
+	for _, profile := range queue {
+		if !a.work.attempted[profile.ProfileURL] {
+			untried = append(untried, profile)
+		}
+	}
+	queue = untried
+	if len(queue) == 0 && !first {
+		fmt.Println("✓ No queued profiles left")
+		return false, false
+	}
+	if len(queue) == 0 && a.resume != nil {
+		fmt.Println("✓ Nothing left in the interrupted run's queue")
+		return false, false
+	}
+	if len(queue) == 0 {
+		fmt.Println("⚠ No queued profiles, run the search phase first")
+		return false, false
+	}
+	fmt.Printf("Queued profiles: %d\n", len(queue))
+
+	allowance := a.connectionAllowance("")
+	if allowance == nil {
+		return false, false
+	}
+	if a.weeklyLimitBlocked() {
+		return false, false
+	}
+
+	remaining := allowance.Remaining
+	fmt.Printf("Remaining connections today: %d (%s)\n", remaining, allowance.Binding)
+	if remaining < len(queue) {
+		a.rhythm.Plan(remaining)
+	} else {
+		a.rhythm.Plan(len(queue))
+	}
+
+	taken := 0
+	for i, profile := range queue {
+		select {
+		case <-a.stopChan:
+			fmt.Println("\nStopping...")
+			return true, false
+		default:
+		}
+		if limit > 0 && taken == limit {
+			more = true
+			break
+		}
+		if proceed, stop := a.afterClockJump(StepConnect, result); !proceed {
+			return stop, false
+		}
+		if proceed, stop := a.afterThrottle(StepConnect, result); !proceed {
+			return stop, false
+		}
+
+		if a.retryBudget.Depleted() {
+			break
+		}
+
+		// Check if we can send more
+		if a.connectionAllowance("\n") == nil {
+			break
+		}
+		if !a.burst.connectionsLeft(result.ConnectionsSent) {
+			fmt.Println("\nThis burst's share of connection requests is sent")
+			break
+		}
+		a.checkpoint(i, queue)
+		a.work.attempted[profile.ProfileURL] = true
+		taken++
+
+		// Profiles queued before criteria were recorded say so in the audit
+		criteriaID := profile.CriteriaID
+		if criteriaID == "" {
+			criteriaID = unrecordedCriteria
+		}
+		req := &messaging.ConnectionRequest{
+			ProfileURL: profile.ProfileURL,
+			FirstName:  profile.FirstName,
+			LastName:   profile.LastName,
+			JobTitle:   profile.JobTitle,
+			Company:    profile.Company,
+			PageNumber: profile.PageNumber,
+			Position:   profile.Position,
+			CriteriaID: criteriaID,
+			Location:   profile.Location,
+		}
+
+		var conn *messaging.ConnectionResult
+		aborted := a.inFlight(func(page *rod.Page) {
+			conn, err = a.connectionManager.SendConnectionRequest(page, req, false)
+		})
+		if aborted && (err != nil || !conn.Success) {
+			a.logger.Warn("connection request aborted at shutdown", "profile", profile.ProfileURL)
+			result.ActionsAborted++
+			result.recordConnectionFailure(messaging.ReasonAborted)
+			fmt.Printf("  ⚠ Aborted at shutdown, %s %s not counted as sent\n", profile.FirstName, profile.LastName)
+			return true, false
+		}
+		if err != nil {
+			a.logger.LogError("connection request", err, map[string]interface{}{"profile": profile.ProfileURL})
+			result.recordConnectionFailure(messaging.ReasonError)
+			a.waitAfterError()
+			continue
+		}
+
+		if conn.Reason == messaging.ReasonUserQuit {
+			fmt.Println("\nStopping at operator request...")
+			return true, false
+		}
+
+		if conn.Success {
+			result.ConnectionsSent++
+			fmt.Printf("  ✓ Sent to %s %s\n", profile.FirstName, profile.LastName)
+			if conn.OpenProfile {
+				a.messageOpenProfile(req, conn, result)
+			}
+		} else {
+			result.recordConnectionFailure(conn.Reason)
+			fmt.Printf("  ⚠ Failed (%s): %s\n", conn.Reason, conn.ErrorMessage)
+			if conn.Reason == messaging.ReasonError {
+				a.waitAfterError()
+			}
+			if conn.WeeklyLimitReached {
+				a.logger.Warn("weekly invitation limit reached, stopping connection requests", "profile", profile.ProfileURL)
+				fmt.Println("\n⚠ LinkedIn reports the weekly invitation limit, stopping connection requests")
+				a.weeklyLimitBlocked()
+				break
+			}
+			if conn.Reason == messaging.ReasonDailyLimit {
+				fmt.Println("\n⚠ Connection limit reached, stopping connection requests")
+				break
+			}
+			if conn.Reason == messaging.ReasonTemplatesCapped {
+				fmt.Println("\n⚠ All note templates reached their daily cap, stopping connection requests")
+				break
+			}
+		}
+
+		// Rate limiting delay
+		a.waitBetweenActions()
+	}
+
+	if !more {
+		fmt.Printf("\n✓ Sent %d connection requests\n", result.ConnectionsSent)
+	}
+	return false, more
+}
+
+// connectionAllowance returns today's remaining invitations, or nil when
+// none can go out, printing after prefix which limit is reached
+func (a *Automation) connectionAllowance(prefix string) *messaging.ConnectionAllowance {
+	allowance, err := a.connectionManager.ConnectionAllowance()
+	if err != nil {
+		a.logger.LogError("connection allowance", err, nil)
+		fmt.Printf("%s⚠ Could not check the connection limits: %v\n", prefix, err)
+		return nil
+	}
+	if allowance.Remaining == 0 {
+		a.logger.Info("connection limit reached", "limit", allowance.Binding)
+		fmt.Printf("%s⚠ Connection limit reached (%s), stopping connection requests\n", prefix, allowance.Binding)
+		return nil
+	}
+	return allowance
+}
+
+// weeklyLimitBlocked reports whether the weekly invitation limit still
+// blocks sending, printing when capacity is expected back
+func (a *Automation) weeklyLimitBlocked() bool {
+	blocked, next, err := a.connectionManager.WeeklyLimitStatus()
+	if err != nil {
+		a.logger.LogError("weekly limit status", err, nil)
+		return false
+	}
+	if blocked {
+		a.logger.Info("weekly invitation limit in effect", "next_available", next)
+		fmt.Printf("⚠ Weekly invitation limit reached, next invitation expected after %s\n", next.Format("Mon Jan 2 15:04"))
+	}
+	return blocked
+}
+
+// runSuggestionsPhase sends note-less invitations from the "People you may
+// know" grid. It returns true if the operator quit at a preview
+func (a *Automation) runSuggestionsPhase(n int, result *RunResult) bool {
+	fmt.Printf("\n[Step %d] Connecting with people you may know...\n", n)
+
+	allowance := a.connectionAllowance("")
+	if allowance == nil {
+		return false
+	}
+	if a.weeklyLimitBlocked() {
+		return false
+	}
+	fmt.Printf("Remaining connections today: %d (%s)\n", allowance.Remaining, allowance.Binding)
+	if proceed, stop := a.afterClockJump(StepConnect, result); !proceed {
+		return stop
+	}
+	if proceed, stop := a.afterThrottle(StepConnect, result); !proceed {
+		return stop
+	}
+
+	var suggestions *messaging.SuggestionsResult
+	var err error
+	aborted := a.inFlight(func(page *rod.Page) {
+		suggestions, err = a.connectionManager.ConnectFromSuggestions(page)
+	})
+	if aborted {
+		a.logger.Warn("suggestion invitations aborted at shutdown")
+		result.ActionsAborted++
+	}
+	if err != nil {
+		a.logger.LogError("connect from suggestions", err, nil)
+		fmt.Printf("⚠ Could not connect from suggestions: %v\n", err)
+		return aborted
+	}
+
+	sent := 0
+	for _, conn := range suggestions.Results {
+		if conn.Success {
+			sent++
+			result.ConnectionsSent++
+			continue
+		}
+		result.recordConnectionFailure(conn.Reason)
+		if conn.WeeklyLimitReached {
+			a.logger.Warn("weekly invitation limit reached, stopping suggestion invitations", "profile", conn.ProfileURL)
+			fmt.Println("⚠ LinkedIn reports the weekly invitation limit, stopping connection requests")
+		}
+	}
+	fmt.Printf("✓ Sent %d connection requests from %d suggestions\n", sent, suggestions.Found)
+
+	if suggestions.Quit {
+		fmt.Println("\nStopping at operator request...")
+	}
+	return suggestions.Quit || aborted
+}
+
+// messageOpenProfile sends the open profile message in the same visit as
+// the invitation, within the daily message limit
+func (a *Automation) messageOpenProfile(req *messaging.ConnectionRequest, conn *messaging.ConnectionResult, result *RunResult) {
+	if !a.config.InActiveHours(a.config.Messaging.ActiveHours) {
+		a.logger.Info("outside messaging hours, not messaging open profile", "profile", req.ProfileURL)
+		return
+	}
+	if canSend, _, _ := a.messageManager.CanSendMoreMessagesToday(); !canSend || !a.burst.messagesLeft(result.MessagesSent) {
+		a.logger.Info("message limit reached, not messaging open profile", "profile", req.ProfileURL)
+		return
+	}
+
+	var msg *messaging.MessageResult
+	var err error
+	aborted := a.inFlight(func(page *rod.Page) {
+		msg, err = a.messageManager.SendMessage(page, &messaging.MessageRequest{
+			ConnectionID: conn.ConnectionID,
+			ProfileURL:   req.ProfileURL,
+			FirstName:    req.FirstName,
+			LastName:     req.LastName,
+			JobTitle:     req.JobTitle,
+			Company:      req.Company,
+			OpenProfile:  true,
+		}, false)
+	})
+	if aborted && (err != nil || !msg.Success) {
+		a.logger.Warn("open profile message aborted at shutdown", "profile", req.ProfileURL)
+		result.ActionsAborted++
+		return
+	}
+	if err != nil {
+		a.logger.LogError("message open profile", err, map[string]interface{}{"profile": req.ProfileURL})
+		result.MessagesFailed++
+		return
+	}
+	if !msg.Success {
+		a.logger.Warn("open profile message not sent", "profile", req.ProfileURL, "error", msg.ErrorMessage)
+		result.MessagesFailed++
+		return
+	}
+	result.MessagesSent++
+	fmt.Printf("    ✓ Open Profile, messaged %s %s\n", req.FirstName, req.LastName)
+}
+
+// runSearchPhase searches for profiles and adds the new ones to the queue
+func (a *Automation) runSearchPhase(n int, result *RunResult) {
+	fmt.Printf("\n[Step %d] Searching for profiles...\n", n)
+	searchResult, err := a.searchModule.Search(a.page)
+	if err != nil {
+		a.logger.LogError("search", err, nil)
+		fmt.Printf("⚠ Search error: %v\n", err)
+		return
+	}
+	result.ProfilesFound = len(searchResult.Profiles)
+	fmt.Printf("✓ Found %d profiles (%d unique, %d duplicates)\n",
+		searchResult.TotalFound,
+		len(searchResult.Profiles),
+		searchResult.Duplicates)
+	if searchResult.NotOpen > 0 {
+		fmt.Printf("  %d left out as not open to work\n", searchResult.NotOpen)
+	}
+	if searchResult.Enriched > 0 {
+		fmt.Printf("✓ Visited %d profiles for details\n", searchResult.Enriched)
+	}
+	if searchResult.EmptyReason != "" {
+		fmt.Printf("⚠ No new profiles (%s): %s\n", searchResult.EmptyReason, searchResult.Diagnostic)
+		fmt.Printf("  Search URL: %s\n", searchResult.FinalURL)
+	}
+
+	now := time.Now()
+	criteriaID := a.config.Search.CriteriaID()
+	for _, profile := range searchResult.Profiles {
+		err := a.db.EnqueueProfile(&database.QueuedProfile{
+			ProfileURL: profile.ProfileURL,
+			FirstName:  profile.FirstName,
+			LastName:   profile.LastName,
+			JobTitle:   profile.JobTitle,
+			Company:    profile.Company,
+			Location:   profile.Location,
+			PageNumber: profile.PageNumber,
+			Position:   profile.Position,
+			CriteriaID: criteriaID,
+			QueuedAt:   now,
+		})
+		if err != nil {
+			a.logger.LogError("queue profile", err, map[string]interface{}{"profile": profile.ProfileURL})
+		}
+		if profile.Mutual.Count > 0 {
+			err := a.db.SaveProfileDetails(&database.ProfileDetails{
+				ProfileURL:  profile.ProfileURL,
+				Headline:    profile.JobTitle,
+				MutualCount: profile.Mutual.Count,
+				MutualNames: profile.Mutual.Names,
+				UpdatedAt:   now,
+			})
+			if err != nil {
+				a.logger.LogError("save profile details", err, map[string]interface{}{"profile": profile.ProfileURL})
+			}
+		}
+	}
+}
+
+// runDetectAcceptedStep marks pending invitations that have been accepted
+func (a *Automation) runDetectAcceptedStep(n int, result *RunResult) {
+	fmt.Printf("\n[Step %d] Checking accepted connections...\n", n)
+	accepted, err := a.messageManager.DetectAcceptedConnections(a.page)
+	if err != nil {
+		a.logger.LogError("detect accepted", err, nil)
+		return
+	}
+	result.AcceptedDetected = len(accepted)
+	fmt.Printf("✓ Found %d newly accepted connections\n", len(accepted))
+
+	if a.config.Connection.AcceptIncoming.Enabled {
+		a.runAcceptInvitationsPhase(result)
+	}
+}
+
+// runAcceptInvitationsPhase accepts incoming invitations within
+// connection.accept_incoming.daily_limit
+func (a *Automation) runAcceptInvitationsPhase(result *RunResult) {
+	canAccept, remaining, _ := a.connectionManager.CanAcceptMoreToday()
+	if !canAccept {
+		fmt.Println("Daily limit for accepting invitations reached")
+		return
+	}
+
+	fmt.Println("\nAccepting incoming invitations...")
+	var invitations *messaging.InvitationsResult
+	var err error
+	aborted := a.inFlight(func(page *rod.Page) {
+		invitations, err = a.connectionManager.AcceptIncomingInvitations(page, remaining)
+	})
+	if aborted {
+		a.logger.Warn("accepting invitations aborted at shutdown")
+	}
+	if err != nil {
+		a.logger.LogError("accept incoming invitations", err, nil)
+		fmt.Printf("⚠ Could not accept invitations: %v\n", err)
+		return
+	}
+
+	result.InvitationsAccepted = len(invitations.Accepted)
+	if invitations.Found == 0 {
+		fmt.Println("No pending invitations")
+		return
+	}
+	fmt.Printf("✓ Accepted %d of %d invitations (%d filtered out)\n", len(invitations.Accepted), invitations.Found, invitations.Skipped)
+}
+
+// runFollowUpStep sends follow-up messages to accepted connections. With
+// a limit, only that many are sent and more reports whether connections
+// are left to message
+func (a *Automation) runFollowUpStep(n, limit int, result *RunResult) (stopped, more bool) {
+	if len(a.config.Messaging.Templates) == 0 && len(a.config.Messaging.LatencyTemplates) == 0 {
+		return false, false
+	}
+
+	all, _ := a.messageManager.GetConnectionsNeedingFollowUp()
+	// Earlier batches of this run already tried these
+	var needFollowUp []messaging.FollowUp
+	for _, conn := range all {
+		if !a.work.attempted[conn.ID] {
+			needFollowUp = append(needFollowUp, conn)
+		}
+	}
+	if len(needFollowUp) == 0 {
+		return false, false
+	}
+
+	fmt.Printf("\n[Step %d] Sending follow-up messages to %d connections...\n", n, len(needFollowUp))
+	a.rhythm.Plan(len(needFollowUp))
+
+	taken := 0
+	for _, conn := range needFollowUp {
+		select {
+		case <-a.stopChan:
+			return true, false
+		default:
+		}
+		if limit > 0 && taken == limit {
+			more = true
+			break
+		}
+		if proceed, stop := a.afterClockJump(StepFollowUp, result); !proceed {
+			return stop, false
+		}
+		if proceed, stop := a.afterThrottle(StepFollowUp, result); !proceed {
+			return stop, false
+		}
+
+		if a.retryBudget.Depleted() {
+			break
+		}
+
+		canSend, _, _ := a.messageManager.CanSendMoreMessagesToday()
+		if !canSend {
+			fmt.Println("\n⚠ Daily message limit reached")
+			break
+		}
+		if !a.burst.messagesLeft(result.MessagesSent) {
+			fmt.Println("\nThis burst's share of messages is sent")
+			break
+		}
+
+		a.work.attempted[conn.ID] = true
+		taken++
+
+		req := &messaging.MessageRequest{
+			ConnectionID: conn.ID,
+			ProfileURL:   conn.ProfileURL,
+			FirstName:    conn.FirstName,
+			LastName:     conn.LastName,
+			JobTitle:     conn.JobTitle,
+			Company:      conn.Company,
+			AcceptDelay:  conn.AcceptDelay,
+		}
+
+		var msg *messaging.MessageResult
+		var err error
+		aborted := a.inFlight(func(page *rod.Page) {
+			msg, err = a.messageManager.SendMessage(page, req, false)
+		})
+		if aborted && (err != nil || !msg.Success) {
+			a.logger.Warn("follow-up message aborted at shutdown", "connection", conn.ID)
+			result.ActionsAborted++
+			fmt.Printf("  ⚠ Aborted at shutdown, message to %s %s not counted as sent\n", conn.FirstName, conn.LastName)
+			return true, false
+		}
+		if err != nil {
+			a.logger.LogError("send message", err, nil)
+			result.MessagesFailed++
+			a.waitAfterError()
+			continue
+		}
+
+		if msg.Success {
+			result.MessagesSent++
+			fmt.Printf("  ✓ Message sent to %s %s\n", conn.FirstName, conn.LastName)
+		} else {
+			result.MessagesFailed++
+		}
+
+		a.waitBetweenActions()
+	}
+
+	if !more {
+		fmt.Printf("\n✓ Sent %d follow-up messages\n", result.MessagesSent)
+	}
+	return false, more
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
+	"github.com/go-rod/rod/lib/proto"
+
+	"linkedin-automation/config"
+	"linkedin-automation/database"
+	"linkedin-automation/logger"
+	"linkedin-automation/messaging"
+	"linkedin-automation/selectors"
+	"linkedin-automation/stealth"
+)
+
+// brokenBrowser is a DevTools client for a page on which every command
+// fails. Navigating to a URL containing panicOn panics instead, the way
+// rod's Must* calls do
+type brokenBrowser struct {
+	events  chan *cdp.Event
+	panicOn string
+}
+
+func (b *brokenBrowser) Event() <-chan *cdp.Event { return b.events }
+
+func (b *brokenBrowser) Call(_ context.Context, _, method string, params interface{}) ([]byte, error) {
+	switch method {
+	case "Target.setDiscoverTargets", "Page.enable":
+		return []byte("{}"), nil
+	case "Target.attachToTarget":
+		return []byte(`{"sessionId":"test"}`), nil
+	case "Page.navigate":
+		if nav, ok := params.(proto.PageNavigate); ok && strings.Contains(nav.URL, b.panicOn) {
+			panic("input error: element is stale")
+		}
+	}
+	return nil, fmt.Errorf("%s: input error", method)
+}
+
+// newTestPage returns a page of a brokenBrowser
+func newTestPage(t *testing.T, panicOn string) *rod.Page {
+	t.Helper()
+	client := &brokenBrowser{events: make(chan *cdp.Event), panicOn: panicOn}
+	t.Cleanup(func() { close(client.events) })
+	browser := rod.New().Client(client).NoDefaultDevice()
+	if err := browser.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	page, err := browser.PageFromTarget("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+// newTestConnectRun sets up a connect-only run over queued profiles on a
+// page where every action fails
+func newTestConnectRun(t *testing.T, page *rod.Page, profiles ...string) *Automation {
+	t.Helper()
+	cfg, err := config.Load("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Connection.Source = messaging.SourceSearch
+	cfg.Stealth.Timing.PreNavMinMs, cfg.Stealth.Timing.PreNavMaxMs = 0, 0
+	cfg.RateLimits.MinActionDelayMs, cfg.RateLimits.MaxActionDelayMs = 0, 0
+	cfg.RateLimits.ErrorRecoveryMinMs, cfg.RateLimits.ErrorRecoveryMaxMs = 0, 0
+
+	log, err := logger.New("error", "text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for i, url := range profiles {
+		p := &database.QueuedProfile{ProfileURL: url, FirstName: "Ana", PageNumber: 1, Position: i, QueuedAt: time.Now()}
+		if err := db.EnqueueProfile(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &Automation{
+		config:            cfg,
+		db:                db,
+		logger:            log,
+		page:              page,
+		connectionManager: messaging.NewConnectionManager(cfg.Connection, db, log, cfg.Stealth, stealth.NewNavigationPacer(0), stealth.NewCursor(), selectors.NewRegistry(), cfg.LinkedIn),
+		stopChan:          make(chan struct{}),
+		rhythm:            stealth.NewSessionRhythm(0),
+		phases:            phaseList{PhaseConnect},
+		clock:             newClockWatch(),
+		runID:             "test-run",
+		work:              newRunWork(),
+	}
+}
+
+func TestConnectStepContinuesAfterInputError(t *testing.T) {
+	profiles := []string{"https://www.linkedin.com/in/first/", "https://www.linkedin.com/in/second/"}
+	// The first profile panics mid-action, the second fails with an error
+	a := newTestConnectRun(t, newTestPage(t, "/first/"), profiles...)
+	result := newRunResult()
+
+	stopped, more := a.runConnectStep(1, 0, result)
+	if stopped || more {
+		t.Fatalf("runConnectStep = stopped %v, more %v, want the step to finish", stopped, more)
+	}
+	for _, url := range profiles {
+		if !a.work.attempted[url] {
+			t.Errorf("%s was not attempted", url)
+		}
+	}
+	if n := result.FailureReasons[messaging.ReasonError]; n != len(profiles) {
+		t.Errorf("%d profiles failed with an error, want %d", n, len(profiles))
+	}
+	if result.ConnectionsSent != 0 {
+		t.Errorf("%d connections sent, want 0", result.ConnectionsSent)
+	}
+}